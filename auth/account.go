@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// Tenants for WithTenant. A tenant can also be given by its ID or one of its
+// domain names, such as contoso.onmicrosoft.com.
+const (
+	// TenantCommon accepts personal accounts and work or school accounts.
+	TenantCommon = "common"
+
+	// TenantOrganizations accepts work or school accounts only.
+	TenantOrganizations = "organizations"
+
+	// TenantConsumers accepts personal Microsoft accounts only.
+	TenantConsumers = "consumers"
+)
+
+// ConsumerTenantID is the tenant ID of every personal Microsoft account.
+const ConsumerTenantID = "9188040d-6c67-4c5b-b112-36a304b66dad"
+
+var (
+	tenantIDPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	tenantDomainPattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?\.)+[a-zA-Z]{2,}$`)
+)
+
+// ValidateTenant checks that tenant is common, organizations, consumers, a
+// tenant ID or a domain name.
+func ValidateTenant(tenant string) error {
+	switch {
+	case tenant == TenantCommon, tenant == TenantOrganizations, tenant == TenantConsumers:
+		return nil
+	case tenantIDPattern.MatchString(tenant), tenantDomainPattern.MatchString(tenant):
+		return nil
+	}
+	return fmt.Errorf("invalid tenant %q: must be common, organizations, consumers, a tenant ID or a domain such as contoso.onmicrosoft.com", tenant)
+}
+
+// AccountType describes the kind of account, for messages to the user.
+func AccountType(account *types.Account) string {
+	switch {
+	case account == nil || account.TenantID == "":
+		return "Microsoft account"
+	case account.TenantID == ConsumerTenantID:
+		return "personal Microsoft account"
+	default:
+		return "work or school account"
+	}
+}
+
+// DescribeAccount returns the user name and account type, such as
+// "jane@contoso.com (work or school account)".
+func DescribeAccount(account *types.Account) string {
+	if account == nil || account.Username == "" {
+		return AccountType(account)
+	}
+	return fmt.Sprintf("%s (%s)", account.Username, AccountType(account))
+}
+
+// parseIDToken reads the account claims from an ID token. The token is not
+// verified: it comes straight from the token endpoint over TLS and is only
+// used to tell the user who signed in.
+func parseIDToken(token string) (*types.Account, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed ID token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("decoding ID token: %w", err)
+	}
+
+	var claims struct {
+		PreferredUsername string `json:"preferred_username"`
+		Email             string `json:"email"`
+		Name              string `json:"name"`
+		TenantID          string `json:"tid"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("parsing ID token: %w", err)
+	}
+
+	account := &types.Account{Username: claims.PreferredUsername, Name: claims.Name, TenantID: claims.TenantID}
+	if account.Username == "" {
+		account.Username = claims.Email
+	}
+	return account, nil
+}
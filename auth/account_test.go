@@ -0,0 +1,138 @@
+package auth_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/auth"
+	"github.com/michMartineau/mcp-server-microsoft-todo/graphtest"
+)
+
+func TestValidateTenant(t *testing.T) {
+	for _, tenant := range []string{"common", "organizations", "consumers", "contoso.onmicrosoft.com", "contoso.com", "72f988bf-86f1-41af-91ab-2d7cd011db47"} {
+		if err := auth.ValidateTenant(tenant); err != nil {
+			t.Errorf("ValidateTenant(%q) = %v", tenant, err)
+		}
+	}
+	for _, tenant := range []string{"", "Common ", "contoso", "72f988bf-86f1", "https://login.microsoftonline.com/common", "a/b.com"} {
+		if err := auth.ValidateTenant(tenant); err == nil {
+			t.Errorf("ValidateTenant(%q) succeeded", tenant)
+		}
+	}
+}
+
+func TestLoginToTenant(t *testing.T) {
+	work := graphtest.Account{Username: "jane@contoso.com", Name: "Jane", TenantID: "72f988bf-86f1-41af-91ab-2d7cd011db47"}
+	tests := []struct {
+		name     string
+		account  graphtest.Account
+		tenant   string
+		wantType string // empty if sign-in must fail
+	}{
+		{"personal account, consumers", graphtest.DefaultAccount, auth.TenantConsumers, "personal Microsoft account"},
+		{"personal account, common", graphtest.DefaultAccount, auth.TenantCommon, "personal Microsoft account"},
+		{"personal account, organizations", graphtest.DefaultAccount, auth.TenantOrganizations, ""},
+		{"work account, organizations", work, auth.TenantOrganizations, "work or school account"},
+		{"work account, domain", work, "contoso.com", "work or school account"},
+		{"work account, consumers", work, auth.TenantConsumers, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			srv := graphtest.NewServer()
+			defer srv.Close()
+			srv.SetAccount(tt.account)
+			tm, err := auth.NewTokenManager("test-client",
+				auth.WithAuthorityHost(srv.AuthorityHost()), auth.WithConfigDir(t.TempDir()), auth.WithTenant(tt.tenant))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+
+			code, err := tm.RequestDeviceCode(ctx)
+			if tt.wantType == "" {
+				if err == nil {
+					t.Fatalf("signing in to %s succeeded", tt.tenant)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RequestDeviceCode: %v", err)
+			}
+			srv.ApproveDeviceCode(code.UserCode)
+			resp, err := tm.PollForToken(ctx, code)
+			if err != nil {
+				t.Fatalf("PollForToken: %v", err)
+			}
+			if _, err := tm.SaveTokenResponse(resp); err != nil {
+				t.Fatalf("SaveTokenResponse: %v", err)
+			}
+
+			account, err := tm.Account()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if account == nil || account.Username != tt.account.Username || account.TenantID != tt.account.TenantID {
+				t.Fatalf("account = %+v, want %+v", account, tt.account)
+			}
+			if got := auth.AccountType(account); got != tt.wantType {
+				t.Errorf("AccountType = %q, want %q", got, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestRefreshKeepsAccount(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	tm := newTokenManager(t, srv)
+
+	tokens := srv.Tokens()
+	tokens.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := tm.SaveTokens(tokens); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tm.GetValidToken(context.Background()); err != nil {
+		t.Fatalf("GetValidToken: %v", err)
+	}
+	account, err := tm.Account()
+	if err != nil || account == nil || account.Username != graphtest.DefaultAccount.Username {
+		t.Errorf("account after refresh = %+v, %v", account, err)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+	for _, profile := range []string{auth.DefaultProfile, "work"} {
+		tm, err := auth.NewTokenManager("test-client", auth.WithAuthorityHost(srv.AuthorityHost()), auth.WithConfigDir(dir), auth.WithProfile(profile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tm.SaveTokens(srv.Tokens()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"tokens.json", "tokens-work.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	profiles, err := auth.ListProfiles(dir)
+	if err != nil || !slices.Equal(profiles, []string{"default", "work"}) {
+		t.Errorf("ListProfiles = %v, %v", profiles, err)
+	}
+
+	for _, name := range []string{"", "Work", "../work", "a b", strings.Repeat("a", 33)} {
+		if err := auth.ValidateProfile(name); err == nil {
+			t.Errorf("ValidateProfile(%q) succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// authCodeTimeout is how long the browser sign-in may take.
+const authCodeTimeout = 10 * time.Minute
+
+// AuthCodeLogin is an authorization code sign-in with PKCE in progress. The
+// user opens AuthURL in a browser; after sign-in the browser is redirected
+// to a listener on a random loopback port, which receives the code.
+//
+// Register http://127.0.0.1 as a redirect URI of the "Mobile and desktop
+// applications" platform in the app registration; any port is accepted.
+// The redirect names the IP address the listener is bound to rather than
+// localhost, which may resolve to ::1 first.
+type AuthCodeLogin struct {
+	// AuthURL is the sign-in page to open in a browser.
+	AuthURL string
+
+	// ExpiresAt is when the listener stops waiting for the browser.
+	ExpiresAt time.Time
+
+	tm          *TokenManager
+	redirectURI string
+	state       string
+	verifier    string
+	server      *http.Server
+	result      chan authCodeResult
+	closeOnce   sync.Once
+}
+
+type authCodeResult struct {
+	code string
+	err  error
+}
+
+// StartAuthCodeLogin starts the loopback listener and returns the sign-in
+// to complete with Wait. Close it if it is abandoned.
+func (tm *TokenManager) StartAuthCodeLogin() (*AuthCodeLogin, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("starting loopback listener: %w", err)
+	}
+
+	login := &AuthCodeLogin{
+		ExpiresAt:   time.Now().Add(authCodeTimeout),
+		tm:          tm,
+		redirectURI: fmt.Sprintf("http://127.0.0.1:%d/", listener.Addr().(*net.TCPAddr).Port),
+		state:       randomString(16),
+		verifier:    randomString(32),
+		result:      make(chan authCodeResult, 1),
+	}
+	challenge := sha256.Sum256([]byte(login.verifier))
+	login.AuthURL = tm.endpoint("authorize") + "?" + url.Values{
+		"client_id":             {tm.clientID},
+		"response_type":         {"code"},
+		"response_mode":         {"query"},
+		"redirect_uri":          {login.redirectURI},
+		"scope":                 {tm.scopes()},
+		"state":                 {login.state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+		"prompt":                {"select_account"},
+	}.Encode()
+
+	login.server = &http.Server{Handler: http.HandlerFunc(login.serveRedirect), ReadHeaderTimeout: 10 * time.Second}
+	go login.server.Serve(listener)
+	return login, nil
+}
+
+// randomString returns n random bytes, base64url-encoded.
+func randomString(n int) string {
+	return base64.RawURLEncoding.EncodeToString(randomBytes(n))
+}
+
+// serveRedirect receives the browser after sign-in. A request with the
+// wrong state did not come from this login; it is rejected without ending
+// the login, so that a stray request cannot preempt the real redirect.
+func (l *AuthCodeLogin) serveRedirect(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query()
+
+	if query.Get("state") != l.state {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		resultPage.Execute(w, map[string]any{"OK": false, "Message": "This sign-in response has the wrong state; it did not come from this login, so it was rejected."})
+		return
+	}
+
+	var result authCodeResult
+	switch {
+	case query.Get("error") != "":
+		result.err = fmt.Errorf("sign-in failed: %s: %s", query.Get("error"), query.Get("error_description"))
+	case query.Get("code") == "":
+		result.err = errors.New("sign-in response has no authorization code")
+	default:
+		result.code = query.Get("code")
+	}
+
+	select {
+	case l.result <- result:
+	default:
+		// A result was already delivered; this is a reload of the page.
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if result.err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resultPage.Execute(w, map[string]any{"OK": false, "Message": result.err.Error()})
+		return
+	}
+	resultPage.Execute(w, map[string]any{"OK": true})
+}
+
+var resultPage = template.Must(template.New("result").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{if .OK}}Signed in{{else}}Sign-in failed{{end}} – Microsoft To-Do MCP server</title>
+<style>
+  body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; background: #f3f6fc; color: #201f1e; display: flex; align-items: center; justify-content: center; min-height: 100vh; margin: 0; }
+  main { background: #fff; border-radius: 8px; box-shadow: 0 2px 8px rgba(0,0,0,.12); padding: 2.5rem 3rem; max-width: 28rem; text-align: center; }
+  .icon { font-size: 3rem; color: {{if .OK}}#2564cf{{else}}#c50f1f{{end}}; }
+  h1 { font-size: 1.4rem; margin: .5rem 0 1rem; }
+  p { color: #605e5c; line-height: 1.5; }
+</style>
+</head>
+<body>
+<main>
+  <div class="icon">{{if .OK}}&#10003;{{else}}&#10007;{{end}}</div>
+  {{if .OK}}
+  <h1>You're signed in to Microsoft To-Do</h1>
+  <p>You can close this tab and return to your assistant. Call <code>login_complete</code> if it has not picked up the sign-in yet.</p>
+  {{else}}
+  <h1>Sign-in failed</h1>
+  <p>{{.Message}}</p>
+  <p>Close this tab and call <code>login</code> again.</p>
+  {{end}}
+</main>
+</body>
+</html>
+`))
+
+// Wait waits for the browser to be redirected back, exchanges the code for
+// tokens and closes the listener. It gives up at ExpiresAt or when ctx is
+// done.
+func (l *AuthCodeLogin) Wait(ctx context.Context) (*types.TokenResponse, error) {
+	timer := time.NewTimer(time.Until(l.ExpiresAt))
+	defer timer.Stop()
+
+	var result authCodeResult
+	select {
+	case result = <-l.result:
+	case <-timer.C:
+		l.Close()
+		return nil, errors.New("authentication timed out")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	l.Close()
+	if result.err != nil {
+		return nil, result.err
+	}
+	return l.redeem(ctx, result.code)
+}
+
+// redeem exchanges an authorization code for tokens.
+func (l *AuthCodeLogin) redeem(ctx context.Context, code string) (*types.TokenResponse, error) {
+	data := url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {l.tm.clientID},
+		"code":          {code},
+		"redirect_uri":  {l.redirectURI},
+		"code_verifier": {l.verifier},
+		"scope":         {l.tm.scopes()},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", l.tm.endpoint("token"), strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := l.tm.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed: %s", body)
+	}
+
+	var tokens types.TokenResponse
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, err
+	}
+	return &tokens, nil
+}
+
+// Close stops the loopback listener. It is safe to call more than once.
+func (l *AuthCodeLogin) Close() {
+	l.closeOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		l.server.Shutdown(ctx)
+	})
+}
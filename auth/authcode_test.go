@@ -0,0 +1,151 @@
+package auth_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/auth"
+	"github.com/michMartineau/mcp-server-microsoft-todo/graphtest"
+)
+
+// browse opens url like a browser, following redirects, and returns the
+// final status and page.
+func browse(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestAuthCodeLogin(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	tm := newTokenManager(t, srv)
+	ctx := context.Background()
+
+	login, err := tm.StartAuthCodeLogin()
+	if err != nil {
+		t.Fatalf("StartAuthCodeLogin: %v", err)
+	}
+	defer login.Close()
+	query := mustQuery(t, login.AuthURL)
+	if query.Get("code_challenge_method") != "S256" || query.Get("state") == "" || !strings.HasPrefix(query.Get("redirect_uri"), "http://127.0.0.1:") {
+		t.Errorf("authorize URL = %s", login.AuthURL)
+	}
+
+	status, page := browse(t, login.AuthURL)
+	if status != http.StatusOK || !strings.Contains(page, "signed in to Microsoft To-Do") {
+		t.Fatalf("success page: %d\n%s", status, page)
+	}
+
+	resp, err := login.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if _, err := tm.SaveTokenResponse(resp); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tm.GetValidToken(ctx); err != nil {
+		t.Errorf("GetValidToken after sign-in: %v", err)
+	}
+	if account, _ := tm.Account(); account == nil || account.Username != graphtest.DefaultAccount.Username {
+		t.Errorf("account = %+v", account)
+	}
+
+	// The listener is closed once the code has been received.
+	if _, err := http.Get(query.Get("redirect_uri")); err == nil {
+		t.Error("the loopback listener is still running")
+	}
+}
+
+func TestAuthCodeLoginDenied(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	srv.DenySignIn(true)
+	tm := newTokenManager(t, srv)
+
+	login, err := tm.StartAuthCodeLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer login.Close()
+
+	status, page := browse(t, login.AuthURL)
+	if status != http.StatusBadRequest || !strings.Contains(page, "Sign-in failed") || !strings.Contains(page, "AADSTS65004") {
+		t.Errorf("error page: %d\n%s", status, page)
+	}
+	if _, err := login.Wait(context.Background()); err == nil || !strings.Contains(err.Error(), "access_denied") {
+		t.Errorf("Wait error = %v, want access_denied", err)
+	}
+}
+
+func TestAuthCodeLoginRejectsWrongState(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	tm := newTokenManager(t, srv)
+
+	login, err := tm.StartAuthCodeLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer login.Close()
+
+	// A redirect that was not started by this login, such as a forged one.
+	redirect := mustQuery(t, login.AuthURL).Get("redirect_uri") + "?code=stolen&state=forged"
+	if status, _ := browse(t, redirect); status != http.StatusBadRequest {
+		t.Errorf("forged redirect answered with %d", status)
+	}
+
+	// The forged redirect does not end the login; the real one completes it.
+	if status, _ := browse(t, login.AuthURL); status != http.StatusOK {
+		t.Fatalf("sign-in answered with %d", status)
+	}
+	if _, err := login.Wait(context.Background()); err != nil {
+		t.Errorf("Wait: %v", err)
+	}
+}
+
+func TestAuthCodeLoginUsesTenant(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	tm, err := auth.NewTokenManager("test-client",
+		auth.WithAuthorityHost(srv.AuthorityHost()), auth.WithConfigDir(t.TempDir()), auth.WithTenant(auth.TenantOrganizations))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login, err := tm.StartAuthCodeLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer login.Close()
+	if !strings.HasPrefix(login.AuthURL, srv.AuthorityHost()+"/organizations/oauth2/v2.0/authorize?") {
+		t.Errorf("authorize URL = %s", login.AuthURL)
+	}
+
+	// The default account is personal, so it cannot sign in to organizations.
+	browse(t, login.AuthURL)
+	if _, err := login.Wait(context.Background()); err == nil || !strings.Contains(err.Error(), "AADSTS50020") {
+		t.Errorf("Wait error = %v, want AADSTS50020", err)
+	}
+}
+
+func mustQuery(t *testing.T, rawURL string) url.Values {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query()
+}
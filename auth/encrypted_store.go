@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// Key derivation functions recorded in sealed token files.
+const (
+	// kdfPassphrase stretches a passphrase with PBKDF2-HMAC-SHA256.
+	kdfPassphrase = "pbkdf2-sha256"
+
+	// kdfKeyFile extracts a key from a random key file with HMAC-SHA256,
+	// as HKDF-Extract does.
+	kdfKeyFile = "hmac-sha256"
+)
+
+const (
+	// sealedVersion is the version of the sealed token file format.
+	sealedVersion = 1
+
+	// passphraseIterations is the PBKDF2 iteration count for new files,
+	// as recommended by OWASP for PBKDF2-HMAC-SHA256.
+	passphraseIterations = 600_000
+
+	// minKeyFileSize is the least number of bytes a key file must hold.
+	minKeyFileSize = 32
+)
+
+// sealedAD is the additional data authenticated along with the tokens.
+var sealedAD = []byte("mcp-server-microsoft-todo tokens")
+
+// TokenKey is the secret an EncryptedStore derives its encryption key from.
+type TokenKey struct {
+	secret []byte
+	kdf    string
+}
+
+// PassphraseKey returns a key derived from a passphrase.
+func PassphraseKey(passphrase string) (*TokenKey, error) {
+	if passphrase == "" {
+		return nil, errors.New("the token passphrase is empty")
+	}
+	return &TokenKey{secret: []byte(passphrase), kdf: kdfPassphrase}, nil
+}
+
+// ReadKeyFile returns a key derived from the contents of a key file, which
+// must hold at least 32 random bytes, such as one made with
+// "head -c 32 /dev/urandom > tokens.key".
+func ReadKeyFile(path string) (*TokenKey, error) {
+	secret, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+	if len(secret) < minKeyFileSize {
+		return nil, fmt.Errorf("key file %s is too short: it must hold at least %d random bytes", path, minKeyFileSize)
+	}
+	return &TokenKey{secret: secret, kdf: kdfKeyFile}, nil
+}
+
+// describe names the kind of key, for error messages.
+func (k *TokenKey) describe() string {
+	if k.kdf == kdfPassphrase {
+		return "passphrase"
+	}
+	return "key file"
+}
+
+// sealedTokens is the contents of an encrypted token file: the tokens as
+// JSON, sealed with AES-256-GCM under a key derived from the TokenKey and
+// Salt.
+type sealedTokens struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// isSealed reports whether data is an encrypted token file.
+func isSealed(data []byte) bool {
+	var sealed sealedTokens
+	return json.Unmarshal(data, &sealed) == nil && sealed.Version > 0 && sealed.Ciphertext != nil
+}
+
+// EncryptedStore keeps tokens in a file encrypted with a key derived from a
+// passphrase or a key file. A plaintext token file found at its path is
+// encrypted when it is loaded.
+type EncryptedStore struct {
+	path string
+	key  *TokenKey
+
+	// The derived key is kept for the salt it was derived with, so that
+	// the passphrase is stretched once per run, not at every refresh.
+	salt       []byte
+	iterations int
+	derived    []byte
+}
+
+// NewEncryptedStore returns a store keeping tokens in the file at path,
+// encrypted under key.
+func NewEncryptedStore(path string, key *TokenKey) *EncryptedStore {
+	return &EncryptedStore{path: path, key: key}
+}
+
+// Load implements TokenStore.
+func (s *EncryptedStore) Load() (*types.StoredTokens, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // No tokens stored yet
+		}
+		return nil, fmt.Errorf("reading tokens file: %w", err)
+	}
+
+	if !isSealed(data) {
+		var tokens types.StoredTokens
+		if err := json.Unmarshal(data, &tokens); err != nil {
+			return nil, fmt.Errorf("parsing tokens: %w", err)
+		}
+		if err := s.Save(&tokens); err != nil {
+			return nil, fmt.Errorf("encrypting plaintext tokens: %w", err)
+		}
+		return &tokens, nil
+	}
+
+	var sealed sealedTokens
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, fmt.Errorf("parsing tokens: %w", err)
+	}
+	if sealed.Version != sealedVersion {
+		return nil, fmt.Errorf("%s has unsupported version %d", s.path, sealed.Version)
+	}
+	if sealed.KDF != s.key.kdf {
+		return nil, fmt.Errorf("%s was not encrypted with a %s", s.path, s.key.describe())
+	}
+
+	aead, err := s.cipher(sealed.Salt, sealed.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, sealedAD)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %s: wrong %s, or the file was modified", s.path, s.key.describe())
+	}
+
+	var tokens types.StoredTokens
+	if err := json.Unmarshal(plaintext, &tokens); err != nil {
+		return nil, fmt.Errorf("parsing tokens: %w", err)
+	}
+	return &tokens, nil
+}
+
+// Save implements TokenStore.
+func (s *EncryptedStore) Save(tokens *types.StoredTokens) error {
+	plaintext, err := json.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("marshaling tokens: %w", err)
+	}
+
+	sealed := sealedTokens{Version: sealedVersion, KDF: s.key.kdf, Salt: s.salt, Iterations: s.iterations}
+	if sealed.Salt == nil {
+		sealed.Salt = randomBytes(16)
+		if s.key.kdf == kdfPassphrase {
+			sealed.Iterations = passphraseIterations
+		}
+	}
+	aead, err := s.cipher(sealed.Salt, sealed.Iterations)
+	if err != nil {
+		return err
+	}
+	sealed.Nonce = randomBytes(aead.NonceSize())
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, plaintext, sealedAD)
+
+	data, err := json.MarshalIndent(sealed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling tokens: %w", err)
+	}
+	return writeTokensFile(s.path, data)
+}
+
+// Clear implements TokenStore.
+func (s *EncryptedStore) Clear() error {
+	return removeTokensFile(s.path)
+}
+
+// cipher returns the AES-256-GCM cipher for the key derived with salt and
+// iterations, deriving it unless it was derived last time.
+func (s *EncryptedStore) cipher(salt []byte, iterations int) (cipher.AEAD, error) {
+	if s.derived == nil || !bytes.Equal(salt, s.salt) || iterations != s.iterations {
+		switch s.key.kdf {
+		case kdfPassphrase:
+			if iterations < 1 {
+				return nil, fmt.Errorf("%s has an invalid iteration count %d", s.path, iterations)
+			}
+			derived, err := pbkdf2.Key(sha256.New, string(s.key.secret), salt, iterations, 32)
+			if err != nil {
+				return nil, fmt.Errorf("deriving the token key: %w", err)
+			}
+			s.derived = derived
+		default:
+			mac := hmac.New(sha256.New, salt)
+			mac.Write(s.key.secret)
+			s.derived = mac.Sum(nil)
+		}
+		s.salt, s.iterations = salt, iterations
+	}
+
+	block, err := aes.NewCipher(s.derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// randomBytes returns n random bytes.
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("reading random bytes: %v", err))
+	}
+	return b
+}
@@ -12,85 +12,218 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/michMartineau/mcp-server-microsoft-todo/types"
 )
 
 const (
-	// Microsoft identity platform endpoints for consumer accounts
-	deviceCodeURL = "https://login.microsoftonline.com/consumers/oauth2/v2.0/devicecode"
-	tokenURL      = "https://login.microsoftonline.com/consumers/oauth2/v2.0/token"
+	// Required scope for Microsoft To-Do access
+	tasksScope = "Tasks.ReadWrite"
 
-	// Required scopes for Microsoft To-Do access
-	scopes = "Tasks.ReadWrite offline_access"
+	// Scopes for an ID token naming the signed-in user, and a refresh token
+	signInScopes = "offline_access openid profile"
 )
 
-// TokenManager handles OAuth token lifecycle.
+// ErrNotAuthenticated is returned when there are no usable tokens and the
+// user has to sign in again.
+var ErrNotAuthenticated = errors.New("not authenticated - run 'login' command first")
+
+// ErrSignInUnavailable is returned when the tokens could not be refreshed for
+// a reason other than the sign-in being rejected, such as throttling or an
+// outage of the identity platform. The sign-in is kept; retry later.
+var ErrSignInUnavailable = errors.New("the Microsoft sign-in service could not refresh the tokens; try again later")
+
+// TokenManager handles OAuth token lifecycle. Tokens are kept in memory
+// after the first read, so the token store is only read once and written
+// when tokens change.
 type TokenManager struct {
-	clientID         string
-	tokensPath       string
-	httpClient       *http.Client
-	PendingDeviceCode *types.DeviceCodeResponse
+	clientID      string
+	authorityHost string
+	tenant        string
+	profile       string
+	graphResource string
+	configDir     string
+	store         TokenStore
+	tokenKey      *TokenKey
+	httpClient    *http.Client
+
+	mu     sync.Mutex
+	tokens *types.StoredTokens // nil until loaded
+
+	// pendingDeviceCode or pendingAuthCode is the sign-in started by
+	// SetPendingLogin and not yet taken with TakePendingLogin.
+	pendingDeviceCode *types.DeviceCodeResponse
+	pendingAuthCode   *AuthCodeLogin
 }
 
 // NewTokenManager creates a new token manager.
-func NewTokenManager(clientID string) (*TokenManager, error) {
-	configDir, err := os.UserConfigDir()
-	if err != nil {
-		return nil, fmt.Errorf("getting config dir: %w", err)
+func NewTokenManager(clientID string, opts ...Option) (*TokenManager, error) {
+	tm := &TokenManager{
+		clientID:      clientID,
+		authorityHost: GlobalAuthority,
+		tenant:        TenantConsumers,
+		profile:       DefaultProfile,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(tm)
+	}
+
+	if tm.configDir == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("getting config dir: %w", err)
+		}
+		tm.configDir = filepath.Join(configDir, "mcp-server-microsoft-todo")
 	}
-
-	tokensDir := filepath.Join(configDir, "mcp-server-microsoft-todo")
-	if err := os.MkdirAll(tokensDir, 0700); err != nil {
+	if err := os.MkdirAll(tm.configDir, 0700); err != nil {
 		return nil, fmt.Errorf("creating tokens dir: %w", err)
 	}
+	if tm.store == nil {
+		path := tm.StatePath("tokens.json")
+		if tm.tokenKey != nil {
+			tm.store = NewEncryptedStore(path, tm.tokenKey)
+		} else {
+			tm.store = NewFileStore(path)
+		}
+	}
+	return tm, nil
+}
 
-	return &TokenManager{
-		clientID:   clientID,
-		tokensPath: filepath.Join(tokensDir, "tokens.json"),
-		httpClient: &http.Client{Timeout: 30 * time.Second},
-	}, nil
+// endpoint returns the URL of an OAuth endpoint such as "token".
+func (tm *TokenManager) endpoint(name string) string {
+	return fmt.Sprintf("%s/%s/oauth2/v2.0/%s", tm.authorityHost, tm.tenant, name)
 }
 
-// LoadTokens reads tokens from disk.
-func (tm *TokenManager) LoadTokens() (*types.StoredTokens, error) {
-	data, err := os.ReadFile(tm.tokensPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil // No tokens stored yet
-		}
-		return nil, fmt.Errorf("reading tokens file: %w", err)
+// scopes returns the scopes requested at sign-in.
+func (tm *TokenManager) scopes() string {
+	if tm.graphResource == "" {
+		return tasksScope + " " + signInScopes
 	}
+	return tm.graphResource + "/" + tasksScope + " " + signInScopes
+}
+
+// Profile returns the name of the token manager's profile.
+func (tm *TokenManager) Profile() string {
+	return tm.profile
+}
+
+// Tenant returns the tenant users sign in to, such as "consumers".
+func (tm *TokenManager) Tenant() string {
+	return tm.tenant
+}
 
-	var tokens types.StoredTokens
-	if err := json.Unmarshal(data, &tokens); err != nil {
-		return nil, fmt.Errorf("parsing tokens: %w", err)
+// ConfigDir returns the directory holding tokens.json, where other
+// persistent state is kept as well.
+func (tm *TokenManager) ConfigDir() string {
+	return tm.configDir
+}
+
+// StatePath returns the path of a state file, such as "cache.json", for
+// the token manager's profile: the file itself in ConfigDir for the default
+// profile, or one with the profile name appended, such as
+// "cache-work.json", for others.
+func (tm *TokenManager) StatePath(name string) string {
+	if tm.profile != DefaultProfile {
+		ext := filepath.Ext(name)
+		name = strings.TrimSuffix(name, ext) + "-" + tm.profile + ext
 	}
-	return &tokens, nil
+	return filepath.Join(tm.configDir, name)
 }
 
-// SaveTokens persists tokens to disk.
-func (tm *TokenManager) SaveTokens(tokens *types.StoredTokens) error {
-	data, err := json.MarshalIndent(tokens, "", "  ")
+// LoadTokens returns the stored tokens, reading them from disk on first use.
+func (tm *TokenManager) LoadTokens() (*types.StoredTokens, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.loadTokens()
+}
+
+// loadTokens does the work of LoadTokens. Callers must hold tm.mu.
+func (tm *TokenManager) loadTokens() (*types.StoredTokens, error) {
+	if tm.tokens != nil {
+		return tm.tokens, nil
+	}
+
+	tokens, err := tm.store.Load()
 	if err != nil {
-		return fmt.Errorf("marshaling tokens: %w", err)
+		return nil, err
 	}
+	tm.tokens = tokens
+	return tm.tokens, nil
+}
+
+// SaveTokens persists tokens in the token store.
+func (tm *TokenManager) SaveTokens(tokens *types.StoredTokens) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.saveTokens(tokens)
+}
 
-	if err := os.WriteFile(tm.tokensPath, data, 0600); err != nil {
-		return fmt.Errorf("writing tokens file: %w", err)
+// saveTokens does the work of SaveTokens. Callers must hold tm.mu.
+func (tm *TokenManager) saveTokens(tokens *types.StoredTokens) error {
+	if err := tm.store.Save(tokens); err != nil {
+		return err
 	}
+	tm.tokens = tokens
 	return nil
 }
 
+// SaveTokenResponse stores the tokens from a successful sign-in, along with
+// the account named by its ID token, and returns them.
+func (tm *TokenManager) SaveTokenResponse(resp *types.TokenResponse) (*types.StoredTokens, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.saveTokenResponse(resp, nil)
+}
+
+// saveTokenResponse does the work of SaveTokenResponse. If the response has
+// no readable ID token, the account is taken from previous, if any. Callers
+// must hold tm.mu.
+func (tm *TokenManager) saveTokenResponse(resp *types.TokenResponse, previous *types.StoredTokens) (*types.StoredTokens, error) {
+	tokens := &types.StoredTokens{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second),
+	}
+	if account, err := parseIDToken(resp.IDToken); err == nil {
+		tokens.Account = account
+	} else if previous != nil {
+		tokens.Account = previous.Account
+	}
+
+	if err := tm.saveTokens(tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Account returns the signed-in account, or nil if nobody is signed in or
+// the tokens predate account tracking.
+func (tm *TokenManager) Account() (*types.Account, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tokens, err := tm.loadTokens()
+	if err != nil || tokens == nil {
+		return nil, err
+	}
+	return tokens.Account, nil
+}
+
 // GetValidToken returns a valid access token, refreshing if necessary.
+// Concurrent callers wait for a single refresh.
 func (tm *TokenManager) GetValidToken(ctx context.Context) (string, error) {
-	tokens, err := tm.LoadTokens()
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tokens, err := tm.loadTokens()
 	if err != nil {
 		return "", err
 	}
 	if tokens == nil {
-		return "", errors.New("not authenticated - run 'login' command first")
+		return "", ErrNotAuthenticated
 	}
 
 	// Check if token is still valid (with 5 minute buffer)
@@ -99,13 +232,13 @@ func (tm *TokenManager) GetValidToken(ctx context.Context) (string, error) {
 	}
 
 	data := url.Values{
-		"grant_type":    {"urn:ietf:params:oauth:grant_type=refresh_token"},
+		"grant_type":    {"refresh_token"},
 		"client_id":     {tm.clientID},
 		"refresh_token": {tokens.RefreshToken},
-		"scope":         {scopes},
+		"scope":         {tm.scopes()},
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", tm.endpoint("token"), strings.NewReader(data.Encode()))
 	if err != nil {
 		return "", err
 	}
@@ -123,7 +256,7 @@ func (tm *TokenManager) GetValidToken(ctx context.Context) (string, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("token refresh failed (user may need to re-authenticate): %s", body)
+		return "", refreshError(resp.StatusCode, body)
 	}
 
 	var tokenResp types.TokenResponse
@@ -132,19 +265,30 @@ func (tm *TokenManager) GetValidToken(ctx context.Context) (string, error) {
 	}
 
 	// Save the new tokens
-	newTokens := &types.StoredTokens{
-		AccessToken:  tokenResp.AccessToken,
-		RefreshToken: tokenResp.RefreshToken,
-		ExpiresAt:    time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
-	}
-
-	if err := tm.SaveTokens(newTokens); err != nil {
+	newTokens, err := tm.saveTokenResponse(&tokenResp, tokens)
+	if err != nil {
 		return "", fmt.Errorf("saving refreshed tokens: %w", err)
 	}
 
 	return newTokens.AccessToken, nil
 }
 
+// refreshError describes a failed token refresh. Only a rejected refresh
+// token means the user has to sign in again.
+func refreshError(status int, body []byte) error {
+	var oauthErr struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(body, &oauthErr)
+
+	switch {
+	case oauthErr.Error == "invalid_grant", oauthErr.Error == "interaction_required",
+		status == http.StatusBadRequest, status == http.StatusUnauthorized:
+		return fmt.Errorf("token refresh failed: %s: %w", body, ErrNotAuthenticated)
+	}
+	return fmt.Errorf("token refresh failed with status %d: %s: %w", status, body, ErrSignInUnavailable)
+}
+
 // DeviceCodeLogin initiates the device code authentication flow.
 // It returns immediately with instructions for the user.
 func (tm *TokenManager) DeviceCodeLogin(ctx context.Context) error {
@@ -165,17 +309,12 @@ func (tm *TokenManager) DeviceCodeLogin(ctx context.Context) error {
 	}
 
 	// Step 3: Save tokens
-	storedTokens := &types.StoredTokens{
-		AccessToken:  tokens.AccessToken,
-		RefreshToken: tokens.RefreshToken,
-		ExpiresAt:    time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second),
-	}
-
-	if err := tm.SaveTokens(storedTokens); err != nil {
+	storedTokens, err := tm.SaveTokenResponse(tokens)
+	if err != nil {
 		return fmt.Errorf("saving tokens: %w", err)
 	}
 
-	fmt.Println("\n✓ Authentication successful! Tokens saved.")
+	fmt.Printf("\n✓ Authentication successful as %s! Tokens saved.\n", DescribeAccount(storedTokens.Account))
 	return nil
 }
 
@@ -183,10 +322,10 @@ func (tm *TokenManager) DeviceCodeLogin(ctx context.Context) error {
 func (tm *TokenManager) RequestDeviceCode(ctx context.Context) (*types.DeviceCodeResponse, error) {
 	data := url.Values{
 		"client_id": {tm.clientID},
-		"scope":     {scopes},
+		"scope":     {tm.scopes()},
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", deviceCodeURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", tm.endpoint("devicecode"), strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -258,7 +397,7 @@ func (tm *TokenManager) tryGetToken(ctx context.Context, deviceCode string) (*ty
 		"device_code": {deviceCode},
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", tm.endpoint("token"), strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -286,11 +425,37 @@ func (tm *TokenManager) tryGetToken(ctx context.Context, deviceCode string) (*ty
 	return &tokens, nil
 }
 
+// SetPendingLogin records a sign-in that was started and still has to be
+// completed, given as either a device code or a browser sign-in. An earlier
+// pending sign-in is abandoned. Call it with neither to only abandon it.
+func (tm *TokenManager) SetPendingLogin(deviceCode *types.DeviceCodeResponse, authCode *AuthCodeLogin) {
+	tm.mu.Lock()
+	abandoned := tm.pendingAuthCode
+	tm.pendingDeviceCode = deviceCode
+	tm.pendingAuthCode = authCode
+	tm.mu.Unlock()
+
+	if abandoned != nil && abandoned != authCode {
+		abandoned.Close()
+	}
+}
+
+// TakePendingLogin returns the pending sign-in and forgets it, so that only
+// one caller completes it. Both results are nil if there is none.
+func (tm *TokenManager) TakePendingLogin() (*types.DeviceCodeResponse, *AuthCodeLogin) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	deviceCode, authCode := tm.pendingDeviceCode, tm.pendingAuthCode
+	tm.pendingDeviceCode, tm.pendingAuthCode = nil, nil
+	return deviceCode, authCode
+}
+
 // ClearTokens removes stored tokens (logout).
 func (tm *TokenManager) ClearTokens() error {
-	err := os.Remove(tm.tokensPath)
-	if err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("removing tokens: %w", err)
-	}
-	return nil
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tm.tokens = nil
+	return tm.store.Clear()
 }
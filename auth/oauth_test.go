@@ -0,0 +1,201 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/auth"
+	"github.com/michMartineau/mcp-server-microsoft-todo/graphtest"
+)
+
+func newTokenManager(t *testing.T, srv *graphtest.Server) *auth.TokenManager {
+	t.Helper()
+	tm, err := auth.NewTokenManager("test-client", auth.WithAuthorityHost(srv.AuthorityHost()), auth.WithConfigDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewTokenManager: %v", err)
+	}
+	return tm
+}
+
+func TestDeviceCodeLogin(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	tm := newTokenManager(t, srv)
+	ctx := context.Background()
+
+	code, err := tm.RequestDeviceCode(ctx)
+	if err != nil {
+		t.Fatalf("RequestDeviceCode: %v", err)
+	}
+	if code.UserCode == "" || code.VerificationURI == "" {
+		t.Fatalf("device code response is missing fields: %+v", code)
+	}
+	if !srv.ApproveDeviceCode(code.UserCode) {
+		t.Fatalf("user code %q was not pending", code.UserCode)
+	}
+
+	tokens, err := tm.PollForToken(ctx, code)
+	if err != nil {
+		t.Fatalf("PollForToken: %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("token response is missing tokens: %+v", tokens)
+	}
+}
+
+func TestPollForTokenCanceled(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	tm := newTokenManager(t, srv)
+
+	code, err := tm.RequestDeviceCode(context.Background())
+	if err != nil {
+		t.Fatalf("RequestDeviceCode: %v", err)
+	}
+
+	// The code is never approved, so polling only ends with the context.
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	if _, err := tm.PollForToken(ctx, code); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("PollForToken error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestGetValidToken(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	tm := newTokenManager(t, srv)
+	ctx := context.Background()
+
+	if _, err := tm.GetValidToken(ctx); !errors.Is(err, auth.ErrNotAuthenticated) {
+		t.Fatalf("GetValidToken without tokens: error = %v, want ErrNotAuthenticated", err)
+	}
+
+	tokens := srv.Tokens()
+	if err := tm.SaveTokens(tokens); err != nil {
+		t.Fatalf("SaveTokens: %v", err)
+	}
+	token, err := tm.GetValidToken(ctx)
+	if err != nil {
+		t.Fatalf("GetValidToken: %v", err)
+	}
+	if token != tokens.AccessToken {
+		t.Errorf("GetValidToken = %q, want the stored token %q", token, tokens.AccessToken)
+	}
+}
+
+func TestGetValidTokenRefreshes(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	tm := newTokenManager(t, srv)
+
+	expired := srv.Tokens()
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := tm.SaveTokens(expired); err != nil {
+		t.Fatalf("SaveTokens: %v", err)
+	}
+
+	token, err := tm.GetValidToken(context.Background())
+	if err != nil {
+		t.Fatalf("GetValidToken: %v", err)
+	}
+	if token == expired.AccessToken {
+		t.Fatal("GetValidToken returned the expired token instead of refreshing it")
+	}
+
+	// The refreshed tokens are persisted for the next process.
+	reloaded, err := auth.NewTokenManager("test-client", auth.WithAuthorityHost(srv.AuthorityHost()), auth.WithConfigDir(tm.ConfigDir()))
+	if err != nil {
+		t.Fatalf("NewTokenManager: %v", err)
+	}
+	stored, err := reloaded.LoadTokens()
+	if err != nil {
+		t.Fatalf("LoadTokens: %v", err)
+	}
+	if stored == nil || stored.AccessToken != token || !stored.ExpiresAt.After(time.Now()) {
+		t.Errorf("stored tokens = %+v, want the refreshed token %q", stored, token)
+	}
+}
+
+func TestGetValidTokenRefreshRejected(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	tm := newTokenManager(t, srv)
+
+	expired := srv.Tokens()
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := tm.SaveTokens(expired); err != nil {
+		t.Fatalf("SaveTokens: %v", err)
+	}
+	srv.RevokeTokens()
+
+	if _, err := tm.GetValidToken(context.Background()); !errors.Is(err, auth.ErrNotAuthenticated) {
+		t.Fatalf("GetValidToken error = %v, want ErrNotAuthenticated", err)
+	}
+}
+
+func TestGetValidTokenRefreshFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		failure   graphtest.Failure
+		signedOut bool
+	}{
+		{"invalid grant", graphtest.Failure{Status: 400, Code: "invalid_grant"}, true},
+		{"interaction required", graphtest.Failure{Status: 400, Code: "interaction_required"}, true},
+		{"unauthorized", graphtest.Failure{Status: 401, Code: "invalid_client"}, true},
+		{"throttled", graphtest.Failure{Status: 429, Code: "temporarily_unavailable", RetryAfter: 5}, false},
+		{"outage", graphtest.Failure{Status: 503, Code: "temporarily_unavailable"}, false},
+		{"server error", graphtest.Failure{Status: 500}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := graphtest.NewServer()
+			defer srv.Close()
+			tm := newTokenManager(t, srv)
+
+			expired := srv.Tokens()
+			expired.ExpiresAt = time.Now().Add(-time.Minute)
+			if err := tm.SaveTokens(expired); err != nil {
+				t.Fatalf("SaveTokens: %v", err)
+			}
+			tt.failure.Times = 1
+			srv.FailTokens(tt.failure)
+
+			_, err := tm.GetValidToken(context.Background())
+			if signedOut := errors.Is(err, auth.ErrNotAuthenticated); signedOut != tt.signedOut {
+				t.Fatalf("GetValidToken error = %v, want signed out %v", err, tt.signedOut)
+			}
+			if !tt.signedOut && !errors.Is(err, auth.ErrSignInUnavailable) {
+				t.Fatalf("GetValidToken error = %v, want ErrSignInUnavailable", err)
+			}
+			if !tt.signedOut {
+				if _, err := tm.GetValidToken(context.Background()); err != nil {
+					t.Errorf("GetValidToken once the sign-in service is back: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestClearTokens(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	tm := newTokenManager(t, srv)
+
+	if err := tm.SaveTokens(srv.Tokens()); err != nil {
+		t.Fatalf("SaveTokens: %v", err)
+	}
+	if err := tm.ClearTokens(); err != nil {
+		t.Fatalf("ClearTokens: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tm.ConfigDir(), "tokens.json")); !os.IsNotExist(err) {
+		t.Errorf("tokens.json still exists after ClearTokens: %v", err)
+	}
+	if _, err := tm.GetValidToken(context.Background()); !errors.Is(err, auth.ErrNotAuthenticated) {
+		t.Errorf("GetValidToken after ClearTokens: error = %v, want ErrNotAuthenticated", err)
+	}
+}
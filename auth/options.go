@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Login authority hosts for WithAuthorityHost.
+const (
+	GlobalAuthority = "https://login.microsoftonline.com"
+	USGovAuthority  = "https://login.microsoftonline.us"
+	ChinaAuthority  = "https://login.chinacloudapi.cn"
+)
+
+// Option configures a TokenManager.
+type Option func(*TokenManager)
+
+// WithAuthorityHost sets the Microsoft identity platform host, such as
+// USGovAuthority or the URL of a local stand-in. The default is
+// GlobalAuthority.
+func WithAuthorityHost(host string) Option {
+	return func(tm *TokenManager) {
+		tm.authorityHost = strings.TrimSuffix(host, "/")
+	}
+}
+
+// WithTenant sets the tenant users sign in to: TenantConsumers (the
+// default), TenantOrganizations, TenantCommon, a tenant ID or a tenant's
+// domain name. Check it with ValidateTenant first.
+func WithTenant(tenant string) Option {
+	return func(tm *TokenManager) {
+		tm.tenant = tenant
+	}
+}
+
+// WithProfile keeps tokens and other state under a named profile, so
+// several accounts can be signed in at once. Check the name with
+// ValidateProfile first. The default is DefaultProfile.
+func WithProfile(name string) Option {
+	return func(tm *TokenManager) {
+		tm.profile = name
+	}
+}
+
+// WithGraphResource sets the Graph resource tokens are requested for, such
+// as https://graph.microsoft.us in the US Government cloud. By default tokens
+// are for the global Graph service.
+func WithGraphResource(resource string) Option {
+	return func(tm *TokenManager) {
+		tm.graphResource = strings.TrimSuffix(resource, "/")
+	}
+}
+
+// WithConfigDir stores tokens and other state in dir instead of the
+// user's configuration directory.
+func WithConfigDir(dir string) Option {
+	return func(tm *TokenManager) {
+		tm.configDir = dir
+	}
+}
+
+// WithTokenStore keeps tokens in store instead of the profile's tokens.json
+// in the configuration directory.
+func WithTokenStore(store TokenStore) Option {
+	return func(tm *TokenManager) {
+		tm.store = store
+	}
+}
+
+// WithTokenKey encrypts the profile's tokens.json under key, using an
+// EncryptedStore. Plaintext tokens already stored are encrypted when they
+// are first read.
+func WithTokenKey(key *TokenKey) Option {
+	return func(tm *TokenManager) {
+		tm.tokenKey = key
+	}
+}
+
+// WithTransport sets the RoundTripper requests to the authority are sent
+// through.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(tm *TokenManager) {
+		tm.httpClient.Transport = transport
+	}
+}
+
+// WithProxy sends requests to the authority through the proxy at proxyURL
+// instead of the one named by the HTTPS_PROXY environment variable. It
+// replaces any transport set by an earlier WithTransport.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(tm *TokenManager) {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = http.ProxyURL(proxyURL)
+		tm.httpClient.Transport = transport
+	}
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultProfile is the profile used when none is chosen. Its tokens are in
+// tokens.json, as before profiles existed.
+const DefaultProfile = "default"
+
+var profilePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// ValidateProfile checks that name can be used as a profile name: up to 32
+// lowercase letters, digits, '-' and '_'.
+func ValidateProfile(name string) error {
+	if !profilePattern.MatchString(name) {
+		return fmt.Errorf("invalid account name %q: use up to 32 lowercase letters, digits, '-' and '_', such as work", name)
+	}
+	return nil
+}
+
+// ListProfiles returns the names of the profiles with tokens stored in
+// configDir, sorted.
+func ListProfiles(configDir string) ([]string, error) {
+	entries, err := os.ReadDir(configDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("listing accounts: %w", err)
+	}
+
+	var profiles []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == "tokens.json" {
+			profiles = append(profiles, DefaultProfile)
+			continue
+		}
+		profile, ok := strings.CutPrefix(name, "tokens-")
+		if !ok {
+			continue
+		}
+		profile, ok = strings.CutSuffix(profile, ".json")
+		if ok && profile != DefaultProfile && ValidateProfile(profile) == nil {
+			profiles = append(profiles, profile)
+		}
+	}
+	slices.Sort(profiles)
+	return profiles, nil
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/internal/atomicfile"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// TokenStore keeps the tokens of one profile between runs. The
+// TokenManager serializes calls, so implementations need not be safe for
+// concurrent use by several managers.
+type TokenStore interface {
+	// Load returns the stored tokens, or nil if there are none.
+	Load() (*types.StoredTokens, error)
+
+	// Save replaces the stored tokens.
+	Save(tokens *types.StoredTokens) error
+
+	// Clear removes the stored tokens. It succeeds if there are none.
+	Clear() error
+}
+
+// FileStore keeps tokens in a JSON file that only the user can read.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore returns a store keeping tokens in the file at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load implements TokenStore.
+func (s *FileStore) Load() (*types.StoredTokens, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // No tokens stored yet
+		}
+		return nil, fmt.Errorf("reading tokens file: %w", err)
+	}
+	if isSealed(data) {
+		return nil, fmt.Errorf("%s is encrypted: set the passphrase or key file it was encrypted with", s.path)
+	}
+
+	var tokens types.StoredTokens
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("parsing tokens: %w", err)
+	}
+	return &tokens, nil
+}
+
+// Save implements TokenStore.
+func (s *FileStore) Save(tokens *types.StoredTokens) error {
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling tokens: %w", err)
+	}
+	return writeTokensFile(s.path, data)
+}
+
+// Clear implements TokenStore.
+func (s *FileStore) Clear() error {
+	return removeTokensFile(s.path)
+}
+
+// writeTokensFile replaces the file at path with data, readable by the user
+// only. The old tokens are kept if the write fails part way.
+func writeTokensFile(path string, data []byte) error {
+	if err := atomicfile.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("writing tokens file: %w", err)
+	}
+	return nil
+}
+
+// removeTokensFile removes the file at path, if there is one.
+func removeTokensFile(path string) error {
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing tokens: %w", err)
+	}
+	return nil
+}
+
+// MemoryStore keeps tokens in memory only, for tests and for sessions that
+// must not leave tokens behind.
+type MemoryStore struct {
+	mu     sync.Mutex
+	tokens *types.StoredTokens
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Load implements TokenStore.
+func (s *MemoryStore) Load() (*types.StoredTokens, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tokens == nil {
+		return nil, nil
+	}
+	tokens := *s.tokens
+	return &tokens, nil
+}
+
+// Save implements TokenStore.
+func (s *MemoryStore) Save(tokens *types.StoredTokens) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := *tokens
+	s.tokens = &saved
+	return nil
+}
+
+// Clear implements TokenStore.
+func (s *MemoryStore) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = nil
+	return nil
+}
@@ -0,0 +1,200 @@
+package auth_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/auth"
+	"github.com/michMartineau/mcp-server-microsoft-todo/graphtest"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+func mustPassphraseKey(t *testing.T, passphrase string) *auth.TokenKey {
+	t.Helper()
+	key, err := auth.PassphraseKey(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestTokenStores(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "tokens.key")
+	if err := os.WriteFile(keyFile, []byte(strings.Repeat("k", 32)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := auth.ReadKeyFile(keyFile)
+	if err != nil {
+		t.Fatalf("ReadKeyFile: %v", err)
+	}
+
+	stores := map[string]func(path string) auth.TokenStore{
+		"file":   func(path string) auth.TokenStore { return auth.NewFileStore(path) },
+		"memory": func(string) auth.TokenStore { return auth.NewMemoryStore() },
+		"passphrase": func(path string) auth.TokenStore {
+			return auth.NewEncryptedStore(path, mustPassphraseKey(t, "correct horse"))
+		},
+		"key file": func(path string) auth.TokenStore { return auth.NewEncryptedStore(path, key) },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			store := newStore(filepath.Join(t.TempDir(), "tokens.json"))
+
+			if tokens, err := store.Load(); err != nil || tokens != nil {
+				t.Fatalf("Load from an empty store = %+v, %v", tokens, err)
+			}
+			saved := &types.StoredTokens{
+				AccessToken:  "access",
+				RefreshToken: "refresh",
+				ExpiresAt:    time.Now().Add(time.Hour).Round(0),
+				Account:      &types.Account{Username: "user@example.com"},
+			}
+			for i := 0; i < 2; i++ {
+				if err := store.Save(saved); err != nil {
+					t.Fatalf("Save: %v", err)
+				}
+			}
+			tokens, err := store.Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if tokens == nil || tokens.RefreshToken != "refresh" || !tokens.ExpiresAt.Equal(saved.ExpiresAt) || tokens.Account.Username != "user@example.com" {
+				t.Errorf("Load = %+v, want %+v", tokens, saved)
+			}
+
+			if err := store.Clear(); err != nil {
+				t.Fatalf("Clear: %v", err)
+			}
+			if tokens, err := store.Load(); err != nil || tokens != nil {
+				t.Errorf("Load after Clear = %+v, %v", tokens, err)
+			}
+			if err := store.Clear(); err != nil {
+				t.Errorf("Clear of an empty store: %v", err)
+			}
+		})
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	tokens := &types.StoredTokens{AccessToken: "secret-access", RefreshToken: "secret-refresh", ExpiresAt: time.Now()}
+	if err := auth.NewEncryptedStore(path, mustPassphraseKey(t, "correct horse")).Save(tokens); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("the tokens file holds plaintext tokens:\n%s", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("tokens file mode = %v, %v", info.Mode(), err)
+	}
+
+	if _, err := auth.NewEncryptedStore(path, mustPassphraseKey(t, "wrong horse")).Load(); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("Load with the wrong passphrase: %v", err)
+	}
+	if _, err := auth.NewFileStore(path).Load(); err == nil || !strings.Contains(err.Error(), "encrypted") {
+		t.Errorf("Load of an encrypted file without a key: %v", err)
+	}
+
+	tampered := strings.Replace(string(data), `"ciphertext": "`, `"ciphertext": "AAAA`, 1)
+	if err := os.WriteFile(path, []byte(tampered), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.NewEncryptedStore(path, mustPassphraseKey(t, "correct horse")).Load(); err == nil {
+		t.Error("a modified tokens file was decrypted")
+	}
+}
+
+func TestEncryptedStoreEncryptsPlaintextTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	if err := auth.NewFileStore(path).Save(&types.StoredTokens{RefreshToken: "secret-refresh"}); err != nil {
+		t.Fatal(err)
+	}
+
+	store := auth.NewEncryptedStore(path, mustPassphraseKey(t, "correct horse"))
+	tokens, err := store.Load()
+	if err != nil || tokens == nil || tokens.RefreshToken != "secret-refresh" {
+		t.Fatalf("Load = %+v, %v", tokens, err)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "secret-refresh") {
+		t.Errorf("plaintext tokens were not encrypted:\n%s", data)
+	}
+}
+
+func TestTokenKeys(t *testing.T) {
+	if _, err := auth.PassphraseKey(""); err == nil {
+		t.Error("an empty passphrase was accepted")
+	}
+	short := filepath.Join(t.TempDir(), "short.key")
+	if err := os.WriteFile(short, []byte("too short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.ReadKeyFile(short); err == nil {
+		t.Error("a short key file was accepted")
+	}
+	if _, err := auth.ReadKeyFile(filepath.Join(t.TempDir(), "missing.key")); err == nil {
+		t.Error("a missing key file was accepted")
+	}
+}
+
+func TestTokenManagerUsesTokenStore(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+	store := auth.NewMemoryStore()
+	tm, err := auth.NewTokenManager("test-client", auth.WithAuthorityHost(srv.AuthorityHost()), auth.WithConfigDir(dir), auth.WithTokenStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := srv.Tokens()
+	tokens.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := tm.SaveTokens(tokens); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tm.GetValidToken(context.Background()); err != nil {
+		t.Fatalf("GetValidToken: %v", err)
+	}
+	if stored, _ := store.Load(); stored == nil || !stored.ExpiresAt.After(time.Now()) {
+		t.Errorf("the refreshed tokens were not saved in the store: %+v", stored)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("files were written to the config dir: %v", entries)
+	}
+}
+
+func TestTokenManagerWithTokenKey(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+	newTM := func(passphrase string) *auth.TokenManager {
+		tm, err := auth.NewTokenManager("test-client", auth.WithAuthorityHost(srv.AuthorityHost()), auth.WithConfigDir(dir),
+			auth.WithProfile("work"), auth.WithTokenKey(mustPassphraseKey(t, passphrase)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	if err := newTM("correct horse").SaveTokens(srv.Tokens()); err != nil {
+		t.Fatal(err)
+	}
+	if profiles, _ := auth.ListProfiles(dir); len(profiles) != 1 || profiles[0] != "work" {
+		t.Errorf("ListProfiles = %v", profiles)
+	}
+	if _, err := newTM("correct horse").GetValidToken(context.Background()); err != nil {
+		t.Errorf("GetValidToken: %v", err)
+	}
+	if _, err := newTM("wrong horse").GetValidToken(context.Background()); err == nil {
+		t.Error("GetValidToken succeeded with the wrong passphrase")
+	}
+}
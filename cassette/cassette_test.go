@@ -0,0 +1,184 @@
+package cassette_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/cassette"
+)
+
+const (
+	tokenResponse = `{"token_type":"Bearer","scope":"Tasks.ReadWrite","expires_in":3599,` +
+		`"access_token":"secret-access","refresh_token":"secret-refresh","id_token":"secret-id"}`
+	listsResponse = `{"@odata.context":"https://graph.microsoft.com/v1.0/$metadata#users('jane.doe%40contoso.com')/todo/lists",` +
+		`"value":[{"id":"AAMkADIyAAAhrbPWAAA=","displayName":"Shared with jane.doe@contoso.com","isOwner":true,` +
+		`"createdBy":{"user":{"userId":"2f3a7c1e-7d44-4c1b-9f35-60f5c4b2a9d1"}}}]}`
+	errorResponse = `{"error":{"code":"ErrorItemNotFound","message":"The specified object was not found in the store."}}`
+)
+
+// newUpstream returns a server standing in for Graph and the authority.
+func newUpstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("x-ms-ags-diagnostic", `{"ServerInfo":{"DataCenter":"West Europe"}}`)
+		w.Header().Set("Set-Cookie", "fpc=secret-cookie")
+		switch r.URL.Path {
+		case "/consumers/oauth2/v2.0/token":
+			io.WriteString(w, tokenResponse)
+		case "/v1.0/me/todo/lists":
+			io.WriteString(w, listsResponse)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, errorResponse)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// exercise sends the requests the tests record and replay and returns the
+// response bodies.
+func exercise(t *testing.T, transport http.RoundTripper, baseURL string) []string {
+	t.Helper()
+	httpClient := &http.Client{Transport: transport}
+	var bodies []string
+
+	form := url.Values{"client_id": {"app"}, "grant_type": {"refresh_token"}, "refresh_token": {"secret-old-refresh"}}
+	resp, err := httpClient.PostForm(baseURL+"/consumers/oauth2/v2.0/token", form)
+	if err != nil {
+		t.Fatalf("token request: %v", err)
+	}
+	bodies = append(bodies, readBody(t, resp))
+
+	for _, path := range []string{"/v1.0/me/todo/lists", "/v1.0/me/todo/lists/missing"} {
+		req, _ := http.NewRequest("GET", baseURL+path, nil)
+		req.Header.Set("Authorization", "Bearer secret-access")
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		bodies = append(bodies, readBody(t, resp))
+	}
+	return bodies
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRecordScrubsSecrets(t *testing.T) {
+	upstream := newUpstream(t)
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	recorder := cassette.NewRecorder(path, nil)
+	bodies := exercise(t, recorder, upstream.URL)
+	if bodies[1] != listsResponse {
+		t.Errorf("the recorder changed the live response:\n%s", bodies[1])
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the cassette was written before Close: %v", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := string(data)
+	for _, secret := range []string{"secret-", "jane.doe", "contoso", "2f3a7c1e", "West Europe"} {
+		if strings.Contains(recorded, secret) {
+			t.Errorf("the cassette contains %q:\n%s", secret, recorded)
+		}
+	}
+	for _, kept := range []string{"AAMkADIyAAAhrbPWAAA=", "ErrorItemNotFound", `"expires_in": 3599`, "users('" + cassette.RedactedUser + "')", cassette.RedactedEmail} {
+		if !strings.Contains(recorded, kept) {
+			t.Errorf("the cassette does not contain %q:\n%s", kept, recorded)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	upstream := newUpstream(t)
+	path := filepath.Join(t.TempDir(), "session.json")
+	recorder := cassette.NewRecorder(path, nil)
+	recorded := exercise(t, recorder, upstream.URL)
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	replayer, err := cassette.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	replayed := exercise(t, replayer, upstream.URL)
+	if !strings.Contains(replayed[0], `"access_token":"REDACTED"`) {
+		t.Errorf("token response = %s", replayed[0])
+	}
+	if !strings.Contains(replayed[1], "AAMkADIyAAAhrbPWAAA=") || strings.Contains(replayed[1], "contoso") {
+		t.Errorf("lists response = %s", replayed[1])
+	}
+	if replayed[2] != recorded[2] {
+		t.Errorf("error response = %s, want %s", replayed[2], recorded[2])
+	}
+	if remaining := replayer.Remaining(); len(remaining) != 0 {
+		t.Errorf("%d interactions were not replayed", len(remaining))
+	}
+
+	_, err = (&http.Client{Transport: replayer}).Get(upstream.URL + "/v1.0/me/todo/lists")
+	if err == nil || !strings.Contains(err.Error(), "no recorded response left") {
+		t.Errorf("replaying past the end: err = %v", err)
+	}
+}
+
+func TestScrubForm(t *testing.T) {
+	i := cassette.Interaction{Request: cassette.Request{
+		Method: "POST",
+		URL:    "https://login.microsoftonline.com/consumers/oauth2/v2.0/token",
+		Header: http.Header{"Content-Type": {"application/x-www-form-urlencoded"}},
+		Body:   "client_id=app&code=secret-code&code_verifier=secret-verifier&grant_type=authorization_code&login_hint=jane%40contoso.com",
+	}}
+	cassette.Scrub(&i)
+	form, err := url.ParseQuery(string(i.Request.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"client_id":     {"app"},
+		"code":          {cassette.Redacted},
+		"code_verifier": {cassette.Redacted},
+		"grant_type":    {"authorization_code"},
+		"login_hint":    {cassette.RedactedEmail},
+	}
+	if form.Encode() != want.Encode() {
+		t.Errorf("scrubbed form = %s, want %s", form.Encode(), want.Encode())
+	}
+}
+
+func TestScrubTenant(t *testing.T) {
+	for url, want := range map[string]string{
+		"https://login.microsoftonline.com/contoso.onmicrosoft.com/oauth2/v2.0/token":                   "https://login.microsoftonline.com/" + cassette.RedactedTenant + "/oauth2/v2.0/token",
+		"https://login.microsoftonline.com/2f3a7c1e-7d44-4c1b-9f35-60f5c4b2a9d1/oauth2/v2.0/devicecode": "https://login.microsoftonline.com/" + cassette.RedactedTenant + "/oauth2/v2.0/devicecode",
+		"https://login.microsoftonline.com/consumers/oauth2/v2.0/token":                                 "https://login.microsoftonline.com/consumers/oauth2/v2.0/token",
+	} {
+		i := cassette.Interaction{Request: cassette.Request{Method: "POST", URL: url}}
+		cassette.Scrub(&i)
+		if i.Request.URL != want {
+			t.Errorf("scrubbed %s = %s, want %s", url, i.Request.URL, want)
+		}
+	}
+}
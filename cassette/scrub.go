@@ -0,0 +1,176 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Placeholders written in place of scrubbed values.
+const (
+	Redacted       = "REDACTED"
+	RedactedUser   = "00000000-0000-0000-0000-000000000000"
+	RedactedEmail  = "user@example.com"
+	RedactedTenant = "00000000-0000-0000-0000-000000000000"
+)
+
+// Only these headers are recorded; all others, including Authorization,
+// cookies and diagnostic headers naming Microsoft's servers, are dropped.
+var (
+	requestHeaders  = []string{"Content-Type", "Prefer"}
+	responseHeaders = []string{"Content-Type", "Location", "Retry-After"}
+)
+
+// secretFields are JSON fields whose values grant access.
+var secretFields = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+	"client_info":   true,
+	"device_code":   true,
+}
+
+// secretFormFields are form fields whose values grant access.
+var secretFormFields = map[string]bool{
+	"refresh_token": true,
+	"device_code":   true,
+	"code":          true,
+	"code_verifier": true,
+}
+
+// publicTenants are the authority tenants that name no organization, kept
+// in authority URLs.
+var publicTenants = map[string]bool{
+	"common":        true,
+	"consumers":     true,
+	"organizations": true,
+}
+
+// userFields are JSON fields holding a user or tenant object ID.
+var userFields = map[string]bool{
+	"userId": true,
+	"oid":    true,
+	"sub":    true,
+	"tid":    true,
+}
+
+var (
+	emailPattern    = regexp.MustCompile(`[A-Za-z0-9._%+\-]+(@|%40)[A-Za-z0-9\-]+(\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+	userPathPattern = regexp.MustCompile(`users\('[^']*'\)|users/[0-9a-fA-F\-]{36}`)
+	tempAuthPattern = regexp.MustCompile(`tempauth=[^&"\s]+`)
+	tenantPattern   = regexp.MustCompile(`/[^/?#\s"]+/oauth2/`)
+)
+
+// Scrub removes bearer tokens, refresh tokens, device codes, user IDs,
+// tenants and email addresses from an interaction, and drops headers not needed to
+// replay it. Lists, tasks and their IDs are kept.
+func Scrub(i *Interaction) {
+	i.Request.URL = scrubString(i.Request.URL)
+	i.Request.Header = keepHeaders(i.Request.Header, requestHeaders)
+	i.Request.Body = scrubBody(i.Request.Body, i.Request.Header.Get("Content-Type"))
+	i.Response.Header = keepHeaders(i.Response.Header, responseHeaders)
+	i.Response.Body = scrubBody(i.Response.Body, i.Response.Header.Get("Content-Type"))
+}
+
+// scrubString replaces user IDs, email addresses, tenants in authority URLs
+// and upload session credentials in s.
+func scrubString(s string) string {
+	s = tenantPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if publicTenants[strings.TrimSuffix(strings.TrimPrefix(m, "/"), "/oauth2/")] {
+			return m
+		}
+		return "/" + RedactedTenant + "/oauth2/"
+	})
+	s = userPathPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if strings.HasPrefix(m, "users/") {
+			return "users/" + RedactedUser
+		}
+		return "users('" + RedactedUser + "')"
+	})
+	s = emailPattern.ReplaceAllString(s, RedactedEmail)
+	return tempAuthPattern.ReplaceAllString(s, "tempauth="+Redacted)
+}
+
+func keepHeaders(header http.Header, keep []string) http.Header {
+	kept := http.Header{}
+	for _, name := range keep {
+		for _, value := range header.Values(name) {
+			kept.Add(name, scrubString(value))
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+func scrubBody(body Body, contentType string) Body {
+	if body == "" {
+		return body
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		return scrubForm(body)
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(body)))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil || decoder.More() {
+		return Body(scrubString(string(body)))
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(scrubJSON(v)); err != nil {
+		return Body(scrubString(string(body)))
+	}
+	return Body(strings.TrimSuffix(buf.String(), "\n"))
+}
+
+func scrubForm(body Body) Body {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return Body(scrubString(string(body)))
+	}
+	for key := range form {
+		if secretFormFields[key] {
+			form.Set(key, Redacted)
+		} else {
+			for i, value := range form[key] {
+				form[key][i] = scrubString(value)
+			}
+		}
+	}
+	return Body(form.Encode())
+}
+
+// scrubJSON returns v with secret and user ID fields replaced and every
+// string scrubbed.
+func scrubJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			switch _, isString := value.(string); {
+			case secretFields[key] && isString:
+				v[key] = Redacted
+			case userFields[key] && isString:
+				v[key] = RedactedUser
+			default:
+				v[key] = scrubJSON(value)
+			}
+		}
+		return v
+	case []any:
+		for i, value := range v {
+			v[i] = scrubJSON(value)
+		}
+		return v
+	case string:
+		return scrubString(v)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,207 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+const (
+	// Files larger than this must be uploaded through an upload session.
+	smallAttachmentLimit = 3 * 1024 * 1024
+
+	// MaxAttachmentSize is the largest file Microsoft To-Do accepts.
+	MaxAttachmentSize = 25 * 1024 * 1024
+
+	// Upload chunks must be a multiple of 320 KiB.
+	uploadChunkSize = 10 * 320 * 1024
+)
+
+func (c *GraphClient) attachmentsURL(listID, taskID string) string {
+	return fmt.Sprintf("%s/me/todo/lists/%s/tasks/%s/attachments", c.baseURL, listID, taskID)
+}
+
+// ListAttachments returns the attachments of a task without their content,
+// following pagination.
+func (c *GraphClient) ListAttachments(ctx context.Context, listID, taskID string) ([]types.TaskFileAttachment, error) {
+	var allAttachments []types.TaskFileAttachment
+	url := c.attachmentsURL(listID, taskID)
+
+	for url != "" {
+		body, err := c.doRequest(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var resp types.AttachmentsResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("parsing attachments: %w", err)
+		}
+		allAttachments = append(allAttachments, resp.Value...)
+		url = resp.NextLink
+	}
+	return allAttachments, nil
+}
+
+// GetAttachment returns a single attachment including its content.
+func (c *GraphClient) GetAttachment(ctx context.Context, listID, taskID, attachmentID string) (*types.TaskFileAttachment, error) {
+	respBody, err := c.doRequest(ctx, "GET", c.attachmentsURL(listID, taskID)+"/"+attachmentID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachment types.TaskFileAttachment
+	if err := json.Unmarshal(respBody, &attachment); err != nil {
+		return nil, fmt.Errorf("parsing attachment: %w", err)
+	}
+	return &attachment, nil
+}
+
+// AttachFile attaches a file to a task and returns the attachment metadata.
+// Files over 3 MB are uploaded in chunks through an upload session.
+func (c *GraphClient) AttachFile(ctx context.Context, listID, taskID, name, contentType string, data []byte) (*types.TaskFileAttachment, error) {
+	if len(data) > MaxAttachmentSize {
+		return nil, fmt.Errorf("file is %d bytes, larger than the %d byte attachment limit", len(data), MaxAttachmentSize)
+	}
+	if len(data) <= smallAttachmentLimit {
+		return c.createSmallAttachment(ctx, listID, taskID, name, contentType, data)
+	}
+	return c.uploadLargeAttachment(ctx, listID, taskID, name, contentType, data)
+}
+
+func (c *GraphClient) createSmallAttachment(ctx context.Context, listID, taskID, name, contentType string, data []byte) (*types.TaskFileAttachment, error) {
+	payload, err := json.Marshal(types.TaskFileAttachment{
+		ODataType:    "#microsoft.graph.taskFileAttachment",
+		Name:         name,
+		ContentType:  contentType,
+		ContentBytes: data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling attachment: %w", err)
+	}
+
+	respBody, err := c.doRequest(ctx, "POST", c.attachmentsURL(listID, taskID), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	var attachment types.TaskFileAttachment
+	if err := json.Unmarshal(respBody, &attachment); err != nil {
+		return nil, fmt.Errorf("parsing created attachment: %w", err)
+	}
+	attachment.ContentBytes = nil
+	return &attachment, nil
+}
+
+func (c *GraphClient) uploadLargeAttachment(ctx context.Context, listID, taskID, name, contentType string, data []byte) (*types.TaskFileAttachment, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"attachmentInfo": types.AttachmentInfo{
+			AttachmentType: "file",
+			Name:           name,
+			Size:           int64(len(data)),
+			ContentType:    contentType,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling upload session: %w", err)
+	}
+
+	respBody, err := c.doRequest(ctx, "POST", c.attachmentsURL(listID, taskID)+"/createUploadSession", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	var session types.UploadSession
+	if err := json.Unmarshal(respBody, &session); err != nil {
+		return nil, fmt.Errorf("parsing upload session: %w", err)
+	}
+
+	var location string
+	for start := 0; start < len(data); start += uploadChunkSize {
+		end := min(start+uploadChunkSize, len(data))
+		location, err = c.uploadChunk(ctx, session.UploadURL, data[start:end], start, len(data))
+		if err != nil {
+			return nil, fmt.Errorf("uploading bytes %d-%d: %w", start, end-1, err)
+		}
+	}
+
+	// The final chunk's Location header points at the new attachment.
+	attachmentID := attachmentIDFromLocation(location)
+	if attachmentID == "" {
+		return c.findUploadedAttachment(ctx, listID, taskID, name, int64(len(data)))
+	}
+	attachment, err := c.GetAttachment(ctx, listID, taskID, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	attachment.ContentBytes = nil
+	return attachment, nil
+}
+
+// findUploadedAttachment looks up an attachment whose upload finished
+// without a Location header, by its name and size. It fails unless exactly
+// one attachment of the task matches.
+func (c *GraphClient) findUploadedAttachment(ctx context.Context, listID, taskID, name string, size int64) (*types.TaskFileAttachment, error) {
+	attachments, err := c.ListAttachments(ctx, listID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("the file was uploaded, but its attachment ID is unknown: %w", err)
+	}
+	var matches []types.TaskFileAttachment
+	for _, a := range attachments {
+		if a.Name == name && a.Size == size {
+			matches = append(matches, a)
+		}
+	}
+	if len(matches) != 1 {
+		return nil, fmt.Errorf("the file was uploaded, but its attachment ID is unknown: %d attachments of the task are named \"%s\" with %d bytes", len(matches), name, size)
+	}
+	return &matches[0], nil
+}
+
+// uploadChunk PUTs one byte range to an upload session and returns the
+// Location header of the response. The upload URL is pre-authenticated, so
+// no Authorization header is sent.
+func (c *GraphClient) uploadChunk(ctx context.Context, uploadURL string, chunk []byte, offset, total int) (string, error) {
+	resp, err := c.send(ctx, "PUT", func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "PUT", uploadURL, bytes.NewReader(chunk))
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+len(chunk)-1, total))
+		return req, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if resp.status < 200 || resp.status >= 300 {
+		return "", graphError(resp.status, resp.header, resp.body)
+	}
+	return resp.header.Get("Location"), nil
+}
+
+// attachmentIDFromLocation extracts the ID from a location such as
+// ".../attachments('AAMkAD...')".
+func attachmentIDFromLocation(location string) string {
+	_, rest, ok := strings.Cut(location, "attachments('")
+	if !ok {
+		return ""
+	}
+	id, _, ok := strings.Cut(rest, "')")
+	if !ok {
+		return ""
+	}
+	return id
+}
+
+// DeleteAttachment removes an attachment from a task.
+func (c *GraphClient) DeleteAttachment(ctx context.Context, listID, taskID, attachmentID string) error {
+	_, err := c.doRequest(ctx, "DELETE", c.attachmentsURL(listID, taskID)+"/"+attachmentID, nil)
+	return err
+}
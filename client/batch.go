@@ -0,0 +1,442 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// maxBatchSize is the most requests Graph accepts in one $batch call.
+const maxBatchSize = 20
+
+// BatchRequest is one request inside a JSON batch.
+type BatchRequest struct {
+	// ID identifies the request within the batch. Defaults to its position,
+	// starting at "1".
+	ID string
+
+	Method string
+
+	// URL is either an absolute Graph URL or a path relative to the API
+	// version root, such as "/me/todo/lists".
+	URL string
+
+	// Body is marshaled as JSON if non-nil.
+	Body interface{}
+
+	// DependsOn lists the IDs of requests that must succeed before this one
+	// runs. Requests that depend on each other are always sent in the same
+	// $batch call.
+	DependsOn []string
+}
+
+// BatchResponse is the result of one BatchRequest.
+type BatchResponse struct {
+	ID      string
+	Status  int
+	Headers map[string]string
+	Body    json.RawMessage
+
+	// Err is set if the request failed, including when the batch call
+	// carrying it could not be made.
+	Err error
+}
+
+// Decode unmarshals the response body into v, or returns the request's error.
+func (r BatchResponse) Decode(v interface{}) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if len(r.Body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.Body, v)
+}
+
+type batchPayload struct {
+	Requests []batchRequestPayload `json:"requests"`
+}
+
+type batchRequestPayload struct {
+	ID        string            `json:"id"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      interface{}       `json:"body,omitempty"`
+	DependsOn []string          `json:"dependsOn,omitempty"`
+}
+
+type batchResult struct {
+	Responses []struct {
+		ID      string            `json:"id"`
+		Status  int               `json:"status"`
+		Headers map[string]string `json:"headers"`
+		Body    json.RawMessage   `json:"body"`
+	} `json:"responses"`
+}
+
+// Batch sends requests using Graph JSON batching, packing up to 20 of them
+// into each HTTP call. Requests that are throttled inside a batch are sent
+// again in a new batch, following the retry policy, together with the
+// requests that failed because they depended on them. It returns one
+// response per request, in the same order. The returned error is only set
+// for invalid input; failures of individual requests, or of a whole $batch
+// call, are reported in the responses.
+func (c *GraphClient) Batch(ctx context.Context, requests []BatchRequest) ([]BatchResponse, error) {
+	requests = append([]BatchRequest(nil), requests...)
+	index := map[string]int{}
+	for i := range requests {
+		if requests[i].ID == "" {
+			requests[i].ID = strconv.Itoa(i + 1)
+		}
+		if _, dup := index[requests[i].ID]; dup {
+			return nil, fmt.Errorf("duplicate batch request ID %q", requests[i].ID)
+		}
+		index[requests[i].ID] = i
+	}
+
+	pending := make([]int, len(requests))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	responses := make([]BatchResponse, len(requests))
+	for attempt := 0; ; attempt++ {
+		chunks, err := batchChunks(requests, index, pending)
+		if err != nil {
+			return nil, err
+		}
+		for _, chunk := range chunks {
+			c.sendBatch(ctx, requests, chunk, responses)
+		}
+
+		var wait time.Duration
+		pending, wait = c.batchRetries(requests, responses, pending, attempt)
+		if len(pending) == 0 {
+			return responses, nil
+		}
+		if attempt >= c.retryPolicy.MaxRetries {
+			c.retries.gaveUp()
+			return responses, nil
+		}
+
+		c.retries.retry(wait)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return responses, nil
+		case <-timer.C:
+		}
+	}
+}
+
+// batchRetries picks the requests from the last round that should be sent
+// again and how long to wait first: the ones throttled or unavailable, and
+// the ones that failed only because a request they depend on was.
+func (c *GraphClient) batchRetries(requests []BatchRequest, responses []BatchResponse, sent []int, attempt int) ([]int, time.Duration) {
+	var retry []int
+	var wait time.Duration
+	retrying := map[string]bool{}
+	for _, i := range sent {
+		resp := responses[i]
+		header := batchHeader(resp.Headers)
+		switch {
+		case resp.Status != 0 && c.retryPolicy.retryable(requests[i].Method, resp.Status, header, nil):
+			c.retries.batched(resp.Status, header)
+			wait = max(wait, c.retryPolicy.delay(attempt, header))
+		case resp.Status == http.StatusFailedDependency && dependsOnAny(requests[i], retrying):
+		default:
+			continue
+		}
+		retry = append(retry, i)
+		retrying[requests[i].ID] = true
+	}
+	return retry, wait
+}
+
+// batchHeader converts the headers of a batch sub-response.
+func batchHeader(headers map[string]string) http.Header {
+	header := http.Header{}
+	for key, value := range headers {
+		header.Set(key, value)
+	}
+	return header
+}
+
+func dependsOnAny(req BatchRequest, ids map[string]bool) bool {
+	for _, dep := range req.DependsOn {
+		if ids[dep] {
+			return true
+		}
+	}
+	return false
+}
+
+// batchChunks splits the requests at the included indexes into groups of
+// at most maxBatchSize, keeping requests connected by DependsOn in the same
+// group. Dependencies on requests that are not included are ignored, as
+// they have already succeeded. It returns indexes into requests, in their
+// original order within each chunk.
+func batchChunks(requests []BatchRequest, index map[string]int, include []int) ([][]int, error) {
+	parent := make([]int, len(requests))
+	included := make([]bool, len(requests))
+	for i := range parent {
+		parent[i] = i
+	}
+	for _, i := range include {
+		included[i] = true
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for _, i := range include {
+		for _, dep := range requests[i].DependsOn {
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("batch request %q depends on unknown request %q", requests[i].ID, dep)
+			}
+			if included[j] {
+				parent[find(i)] = find(j)
+			}
+		}
+	}
+
+	// Groups are ordered by their first request.
+	var order []int
+	groups := map[int][]int{}
+	for _, i := range include {
+		root := find(i)
+		if _, ok := groups[root]; !ok {
+			order = append(order, root)
+		}
+		groups[root] = append(groups[root], i)
+	}
+
+	var chunks [][]int
+	var current []int
+	for _, root := range order {
+		group := groups[root]
+		if len(group) > maxBatchSize {
+			return nil, fmt.Errorf("%d batch requests depend on each other, more than the limit of %d", len(group), maxBatchSize)
+		}
+		if len(current)+len(group) > maxBatchSize {
+			chunks = append(chunks, current)
+			current = nil
+		}
+		current = append(current, group...)
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks, nil
+}
+
+// sendBatch sends the requests at the given indexes in one $batch call and
+// stores their results in responses.
+func (c *GraphClient) sendBatch(ctx context.Context, requests []BatchRequest, chunk []int, responses []BatchResponse) {
+	fail := func(err error) {
+		for _, i := range chunk {
+			responses[i] = BatchResponse{ID: requests[i].ID, Err: err}
+		}
+	}
+
+	inChunk := map[string]bool{}
+	for _, i := range chunk {
+		inChunk[requests[i].ID] = true
+	}
+
+	payload := batchPayload{}
+	for _, i := range chunk {
+		req := requests[i]
+		sub := batchRequestPayload{
+			ID:     req.ID,
+			Method: req.Method,
+			URL:    strings.TrimPrefix(req.URL, c.baseURL),
+		}
+		for _, dep := range req.DependsOn {
+			if inChunk[dep] {
+				sub.DependsOn = append(sub.DependsOn, dep)
+			}
+		}
+		if req.Body != nil {
+			sub.Body = req.Body
+			sub.Headers = map[string]string{"Content-Type": "application/json"}
+		}
+		payload.Requests = append(payload.Requests, sub)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		fail(fmt.Errorf("marshaling batch: %w", err))
+		return
+	}
+	respBody, err := c.doRequest(ctx, "POST", c.baseURL+"/$batch", bytes.NewReader(data))
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	var result batchResult
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		fail(fmt.Errorf("parsing batch response: %w", err))
+		return
+	}
+
+	byID := map[string]int{}
+	for _, i := range chunk {
+		byID[requests[i].ID] = i
+	}
+	for _, resp := range result.Responses {
+		i, ok := byID[resp.ID]
+		if !ok {
+			continue
+		}
+		delete(byID, resp.ID)
+
+		responses[i] = BatchResponse{ID: resp.ID, Status: resp.Status, Headers: resp.Headers, Body: resp.Body}
+		if resp.Status < 200 || resp.Status >= 300 {
+			responses[i].Err = graphError(resp.Status, batchHeader(resp.Headers), resp.Body)
+			continue
+		}
+		c.notifyWrite(requests[i].Method, requests[i].URL)
+	}
+	for id, i := range byID {
+		responses[i] = BatchResponse{ID: id, Err: fmt.Errorf("no response for batch request %q", id)}
+	}
+}
+
+// TaskResult is the outcome of one task in a bulk operation.
+type TaskResult struct {
+	TaskID string
+
+	// Task is the task as returned by Graph; nil for deletes and failures.
+	Task *types.TodoTask
+
+	Err error
+}
+
+// CompleteTasks marks several tasks in a list as completed, batching the
+// requests. It returns one result per task ID, in the same order.
+func (c *GraphClient) CompleteTasks(ctx context.Context, listID string, taskIDs []string) []TaskResult {
+	requests := make([]BatchRequest, len(taskIDs))
+	for i, taskID := range taskIDs {
+		requests[i] = BatchRequest{
+			Method: "PATCH",
+			URL:    fmt.Sprintf("/me/todo/lists/%s/tasks/%s", listID, taskID),
+			Body:   map[string]string{"status": "completed"},
+		}
+	}
+	return c.taskBatch(ctx, taskIDs, requests)
+}
+
+// DeleteTasks deletes several tasks in a list, batching the requests. It
+// returns one result per task ID, in the same order.
+func (c *GraphClient) DeleteTasks(ctx context.Context, listID string, taskIDs []string) []TaskResult {
+	requests := make([]BatchRequest, len(taskIDs))
+	for i, taskID := range taskIDs {
+		requests[i] = BatchRequest{
+			Method: "DELETE",
+			URL:    fmt.Sprintf("/me/todo/lists/%s/tasks/%s", listID, taskID),
+		}
+	}
+	return c.taskBatch(ctx, taskIDs, requests)
+}
+
+// UpdateTasks applies the same update to several tasks in a list, batching
+// the requests. It returns one result per task ID, in the same order.
+func (c *GraphClient) UpdateTasks(ctx context.Context, listID string, taskIDs []string, update TaskUpdate) []TaskResult {
+	patch, err := update.patch()
+	if err != nil {
+		return failedTasks(taskIDs, err)
+	}
+	requests := make([]BatchRequest, len(taskIDs))
+	for i, taskID := range taskIDs {
+		requests[i] = BatchRequest{
+			Method: "PATCH",
+			URL:    fmt.Sprintf("/me/todo/lists/%s/tasks/%s", listID, taskID),
+			Body:   patch,
+		}
+	}
+	return c.taskBatch(ctx, taskIDs, requests)
+}
+
+// CreateTasks creates several tasks in a list, batching the requests. It
+// returns one result per task, in the same order, with TaskID set to the
+// ID of each created task.
+func (c *GraphClient) CreateTasks(ctx context.Context, listID string, tasks []NewTask) []TaskResult {
+	results := make([]TaskResult, len(tasks))
+	var requests []BatchRequest
+	var positions []int
+	for i, task := range tasks {
+		payload, err := task.payload()
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		requests = append(requests, BatchRequest{
+			Method: "POST",
+			URL:    fmt.Sprintf("/me/todo/lists/%s/tasks", listID),
+			Body:   payload,
+		})
+		positions = append(positions, i)
+	}
+
+	created := c.taskBatch(ctx, make([]string, len(requests)), requests)
+	for j, result := range created {
+		if result.Task != nil {
+			result.TaskID = result.Task.ID
+		}
+		results[positions[j]] = result
+	}
+	return results
+}
+
+func failedTasks(taskIDs []string, err error) []TaskResult {
+	results := make([]TaskResult, len(taskIDs))
+	for i, taskID := range taskIDs {
+		results[i] = TaskResult{TaskID: taskID, Err: err}
+	}
+	return results
+}
+
+// taskBatch sends requests that each return a task, or nothing, and pairs
+// the results with taskIDs.
+func (c *GraphClient) taskBatch(ctx context.Context, taskIDs []string, requests []BatchRequest) []TaskResult {
+	results := make([]TaskResult, len(requests))
+	responses, err := c.Batch(ctx, requests)
+	for i := range requests {
+		results[i].TaskID = taskIDs[i]
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		resp := responses[i]
+		if resp.Err != nil {
+			results[i].Err = resp.Err
+			continue
+		}
+		if len(resp.Body) == 0 {
+			continue
+		}
+		var task types.TodoTask
+		if err := json.Unmarshal(resp.Body, &task); err != nil {
+			results[i].Err = fmt.Errorf("parsing task: %w", err)
+			continue
+		}
+		results[i].Task = &task
+	}
+	return results
+}
@@ -0,0 +1,88 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/graphtest"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+func TestCompleteTasksBatches(t *testing.T) {
+	srv, c := newTestClient(t)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	var ids []string
+	for i := range 25 {
+		ids = append(ids, srv.AddTask(list.ID, types.TodoTask{Title: fmt.Sprintf("Task %d", i+1)}).ID)
+	}
+	ids = append(ids, "missing")
+	srv.Fail(graphtest.Failure{Path: "/me/todo/lists/" + list.ID + "/tasks/" + ids[3], Status: http.StatusTooManyRequests, Times: 1})
+
+	results := c.CompleteTasks(context.Background(), list.ID, ids)
+	if len(results) != len(ids) {
+		t.Fatalf("got %d results, want %d", len(results), len(ids))
+	}
+	for i, result := range results[:25] {
+		if result.Err != nil || result.TaskID != ids[i] || result.Task == nil || result.Task.Status != "completed" {
+			t.Errorf("result %d = %+v, want task %s completed", i, result, ids[i])
+		}
+	}
+	if last := results[25]; !client.IsNotFound(last.Err) {
+		t.Errorf("result for a missing task = %+v, want not found", last)
+	}
+
+	// 26 requests need two batches, and the throttled one a third.
+	if got := len(srv.Requests()); got != 3 {
+		t.Errorf("made %d requests %v, want 3 batches", got, srv.Requests())
+	}
+	if stats := c.RetryStats(); stats.Throttled != 1 || stats.Retries != 1 {
+		t.Errorf("retry stats = %+v, want one throttled retry", stats)
+	}
+}
+
+func TestCreateTasks(t *testing.T) {
+	srv, c := newTestClient(t)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+
+	results := c.CreateTasks(context.Background(), list.ID, []client.NewTask{
+		{Title: "One"},
+		{Title: "Two", DueDate: "not a date"},
+		{Title: "Three", Importance: "high"},
+	})
+	if results[0].Err != nil || results[0].TaskID == "" || results[2].Err != nil || results[2].Task.Importance != "high" {
+		t.Errorf("results = %+v, want the first and third task created", results)
+	}
+	if results[1].Err == nil {
+		t.Error("a task with an invalid due date was created")
+	}
+	if tasks := srv.Tasks(list.ID); len(tasks) != 2 {
+		t.Errorf("server has %d tasks, want 2", len(tasks))
+	}
+}
+
+func TestBatchFailedDependency(t *testing.T) {
+	srv, c := newTestClient(t)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+
+	responses, err := c.Batch(context.Background(), []client.BatchRequest{
+		{ID: "get", Method: "GET", URL: "/me/todo/lists/" + list.ID + "/tasks/missing"},
+		{ID: "create", Method: "POST", URL: "/me/todo/lists/" + list.ID + "/tasks", Body: map[string]string{"title": "After"}, DependsOn: []string{"get"}},
+		{ID: "list", Method: "GET", URL: srv.GraphURL() + "/me/todo/lists/" + list.ID},
+	})
+	if err != nil {
+		t.Fatalf("Batch: %v", err)
+	}
+	if responses[0].Status != http.StatusNotFound || responses[1].Status != http.StatusFailedDependency {
+		t.Errorf("statuses = %d, %d, want 404 and 424", responses[0].Status, responses[1].Status)
+	}
+	var got types.TodoTaskList
+	if err := responses[2].Decode(&got); err != nil || got.ID != list.ID {
+		t.Errorf("independent request = %+v, %v, want the list", got, err)
+	}
+	if len(srv.Tasks(list.ID)) != 0 {
+		t.Error("the dependent request ran")
+	}
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+func (c *GraphClient) checklistURL(listID, taskID string) string {
+	return fmt.Sprintf("%s/me/todo/lists/%s/tasks/%s/checklistItems", c.baseURL, listID, taskID)
+}
+
+// ListChecklistItems returns the checklist items of a task, following pagination.
+func (c *GraphClient) ListChecklistItems(ctx context.Context, listID, taskID string) ([]types.ChecklistItem, error) {
+	var allItems []types.ChecklistItem
+	url := c.checklistURL(listID, taskID)
+
+	for url != "" {
+		body, err := c.doRequest(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var resp types.ChecklistItemsResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("parsing checklist items: %w", err)
+		}
+		allItems = append(allItems, resp.Value...)
+		url = resp.NextLink
+	}
+	return allItems, nil
+}
+
+// CreateChecklistItem adds a checklist item to a task and returns it.
+func (c *GraphClient) CreateChecklistItem(ctx context.Context, listID, taskID, displayName string) (*types.ChecklistItem, error) {
+	return c.createChecklistItem(ctx, listID, taskID, types.ChecklistItem{DisplayName: displayName})
+}
+
+func (c *GraphClient) createChecklistItem(ctx context.Context, listID, taskID string, item types.ChecklistItem) (*types.ChecklistItem, error) {
+	payload, err := json.Marshal(map[string]interface{}{"displayName": item.DisplayName, "isChecked": item.IsChecked})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling checklist item: %w", err)
+	}
+
+	respBody, err := c.doRequest(ctx, "POST", c.checklistURL(listID, taskID), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	var created types.ChecklistItem
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return nil, fmt.Errorf("parsing created checklist item: %w", err)
+	}
+	return &created, nil
+}
+
+// UpdateChecklistItem renames a checklist item.
+func (c *GraphClient) UpdateChecklistItem(ctx context.Context, listID, taskID, itemID, displayName string) (*types.ChecklistItem, error) {
+	return c.patchChecklistItem(ctx, listID, taskID, itemID, map[string]interface{}{"displayName": displayName})
+}
+
+// CheckChecklistItem marks a checklist item as checked or unchecked.
+func (c *GraphClient) CheckChecklistItem(ctx context.Context, listID, taskID, itemID string, checked bool) (*types.ChecklistItem, error) {
+	return c.patchChecklistItem(ctx, listID, taskID, itemID, map[string]interface{}{"isChecked": checked})
+}
+
+func (c *GraphClient) patchChecklistItem(ctx context.Context, listID, taskID, itemID string, update map[string]interface{}) (*types.ChecklistItem, error) {
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling update: %w", err)
+	}
+
+	url := c.checklistURL(listID, taskID) + "/" + itemID
+	respBody, err := c.doRequest(ctx, "PATCH", url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	var item types.ChecklistItem
+	if err := json.Unmarshal(respBody, &item); err != nil {
+		return nil, fmt.Errorf("parsing updated checklist item: %w", err)
+	}
+	return &item, nil
+}
+
+// DeleteChecklistItem removes a checklist item from a task.
+func (c *GraphClient) DeleteChecklistItem(ctx context.Context, listID, taskID, itemID string) error {
+	_, err := c.doRequest(ctx, "DELETE", c.checklistURL(listID, taskID)+"/"+itemID, nil)
+	return err
+}
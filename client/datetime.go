@@ -0,0 +1,43 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// graphDateTimeLayout is the local date/time format used in dateTimeTimeZone values.
+const graphDateTimeLayout = "2006-01-02T15:04:05"
+
+// loadTimeZone resolves an IANA time zone name, defaulting to UTC.
+func loadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q (use an IANA name such as Europe/Paris)", name)
+	}
+	return loc, nil
+}
+
+// newDateTimeZone converts a user-supplied date/time into a Graph
+// dateTimeTimeZone in the named IANA time zone. Values without an offset are
+// taken as local time in that zone; values with an offset are converted to it.
+func newDateTimeZone(value, timeZone string) (*types.DateTimeZone, error) {
+	loc, err := loadTimeZone(timeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &types.DateTimeZone{DateTime: t.In(loc).Format(graphDateTimeLayout), TimeZone: loc.String()}, nil
+	}
+	for _, layout := range []string{graphDateTimeLayout, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return &types.DateTimeZone{DateTime: t.Format(graphDateTimeLayout), TimeZone: loc.String()}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid date %q (expected ISO 8601, e.g. 2025-12-31T17:00:00)", value)
+}
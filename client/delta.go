@@ -0,0 +1,434 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/internal/atomicfile"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// DeltaState is a saved position in a delta query.
+type DeltaState struct {
+	DeltaLink string    `json:"delta_link"`
+	SyncedAt  time.Time `json:"synced_at"`
+
+	// Lists is every task list as of the sync, saved with the "lists"
+	// state only.
+	Lists []types.TodoTaskList `json:"lists,omitempty"`
+}
+
+// DeltaTokens persists delta links between runs in a JSON file keyed by
+// resource, e.g. "lists" or "tasks:<listID>".
+type DeltaTokens struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewDeltaTokens returns a store backed by the file at path.
+func NewDeltaTokens(path string) *DeltaTokens {
+	return &DeltaTokens{path: path}
+}
+
+func (d *DeltaTokens) load() (map[string]DeltaState, error) {
+	data, err := os.ReadFile(d.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]DeltaState{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading delta tokens: %w", err)
+	}
+
+	states := map[string]DeltaState{}
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("parsing delta tokens: %w", err)
+	}
+	return states, nil
+}
+
+// Get returns the saved state for key, or a zero state if there is none.
+func (d *DeltaTokens) Get(key string) (DeltaState, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	states, err := d.load()
+	if err != nil {
+		return DeltaState{}, err
+	}
+	return states[key], nil
+}
+
+// Set saves the state for key.
+func (d *DeltaTokens) Set(key string, state DeltaState) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	states, err := d.load()
+	if err != nil {
+		return err
+	}
+	states[key] = state
+	return d.save(states)
+}
+
+// Delete removes the saved states for keys.
+func (d *DeltaTokens) Delete(keys ...string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	states, err := d.load()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		delete(states, key)
+	}
+	return d.save(states)
+}
+
+func (d *DeltaTokens) save(states map[string]DeltaState) error {
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling delta tokens: %w", err)
+	}
+	if err := atomicfile.WriteFile(d.path, data, 0600); err != nil {
+		return fmt.Errorf("writing delta tokens: %w", err)
+	}
+	return nil
+}
+
+// ListsDelta fetches the task lists changed since deltaLink was issued, or
+// every list if deltaLink is empty. It returns the changes and the delta
+// link to use next time. Deleted lists have Removed set.
+func (c *GraphClient) ListsDelta(ctx context.Context, deltaLink string) ([]types.TodoTaskList, string, error) {
+	url := deltaLink
+	if url == "" {
+		url = c.baseURL + "/me/todo/lists/delta"
+	}
+
+	var changes []types.TodoTaskList
+	for {
+		body, err := c.doRequest(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, "", err
+		}
+
+		var resp types.TodoTaskListsResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, "", fmt.Errorf("parsing task list changes: %w", err)
+		}
+		changes = append(changes, resp.Value...)
+
+		if resp.NextLink == "" {
+			return changes, resp.DeltaLink, nil
+		}
+		url = resp.NextLink
+	}
+}
+
+// TasksDelta fetches the tasks in a list changed since deltaLink was issued,
+// or every task if deltaLink is empty. It returns the changes and the delta
+// link to use next time. Deleted tasks have Removed set.
+func (c *GraphClient) TasksDelta(ctx context.Context, listID, deltaLink string) ([]types.TodoTask, string, error) {
+	url := deltaLink
+	if url == "" {
+		url = fmt.Sprintf("%s/me/todo/lists/%s/tasks/delta", c.baseURL, listID)
+	}
+
+	var changes []types.TodoTask
+	for {
+		body, err := c.doRequest(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, "", err
+		}
+
+		var resp types.TodoTasksResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, "", fmt.Errorf("parsing task changes: %w", err)
+		}
+		changes = append(changes, resp.Value...)
+
+		if resp.NextLink == "" {
+			return changes, resp.DeltaLink, nil
+		}
+		url = resp.NextLink
+	}
+}
+
+// ExpandTasks fills in the checklist items and linked resources of tasks in
+// a list, which delta queries leave out. A few tasks are expanded with a
+// single JSON batch; more, such as on a first sync, by listing the tasks of
+// the list with $expand, so that large lists do not cost two requests per
+// task. Removed tasks, and tasks deleted in the meantime, are skipped.
+func (c *GraphClient) ExpandTasks(ctx context.Context, listID string, tasks []types.TodoTask) error {
+	var expanded []int
+	for i, task := range tasks {
+		if task.Removed == nil {
+			expanded = append(expanded, i)
+		}
+	}
+	if 2*len(expanded) > maxBatchSize {
+		return c.expandFromList(ctx, listID, tasks, expanded)
+	}
+
+	var requests []BatchRequest
+	for _, i := range expanded {
+		task := tasks[i]
+		taskURL := fmt.Sprintf("/me/todo/lists/%s/tasks/%s", listID, task.ID)
+		requests = append(requests,
+			BatchRequest{Method: "GET", URL: taskURL + "/checklistItems"},
+			BatchRequest{Method: "GET", URL: taskURL + "/linkedResources"},
+		)
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+
+	responses, err := c.Batch(ctx, requests)
+	if err != nil {
+		return err
+	}
+	for n, i := range expanded {
+		task := &tasks[i]
+
+		var items types.ChecklistItemsResponse
+		if err := responses[2*n].Decode(&items); err != nil {
+			if IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("reading checklist items: %w", err)
+		}
+		task.ChecklistItems = items.Value
+		if items.NextLink != "" {
+			if task.ChecklistItems, err = c.ListChecklistItems(ctx, listID, task.ID); err != nil {
+				return fmt.Errorf("reading checklist items: %w", err)
+			}
+		}
+
+		var links types.LinkedResourcesResponse
+		if err := responses[2*n+1].Decode(&links); err != nil {
+			if IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("reading linked resources: %w", err)
+		}
+		task.LinkedResources = links.Value
+		if links.NextLink != "" {
+			if task.LinkedResources, err = c.ListLinkedResources(ctx, listID, task.ID); err != nil {
+				return fmt.Errorf("reading linked resources: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// expandFromList is ExpandTasks for the tasks at the expanded indexes,
+// copying their children from a listing of the whole list.
+func (c *GraphClient) expandFromList(ctx context.Context, listID string, tasks []types.TodoTask, expanded []int) error {
+	listed, err := c.ListTasks(ctx, listID, TaskQuery{})
+	if err != nil {
+		return fmt.Errorf("reading checklist items and linked resources: %w", err)
+	}
+	byID := make(map[string]types.TodoTask, len(listed))
+	for _, task := range listed {
+		byID[task.ID] = task
+	}
+	for _, i := range expanded {
+		if task, ok := byID[tasks[i].ID]; ok {
+			tasks[i].ChecklistItems = task.ChecklistItems
+			tasks[i].LinkedResources = task.LinkedResources
+		}
+	}
+	return nil
+}
+
+// TaskDelta is the result of a delta query on one list's tasks.
+type TaskDelta struct {
+	Changes   []types.TodoTask
+	DeltaLink string
+	Err       error
+}
+
+// TasksDeltaBatch runs TasksDelta on several lists at once. deltaLinks maps
+// list IDs to the delta link from their previous query, or "" to fetch every
+// task. The first page of every list is fetched in JSON batches; further
+// pages, if any, are fetched one list at a time.
+func (c *GraphClient) TasksDeltaBatch(ctx context.Context, deltaLinks map[string]string) map[string]*TaskDelta {
+	listIDs := make([]string, 0, len(deltaLinks))
+	requests := make([]BatchRequest, 0, len(deltaLinks))
+	for listID, deltaLink := range deltaLinks {
+		url := deltaLink
+		if url == "" {
+			url = fmt.Sprintf("/me/todo/lists/%s/tasks/delta", listID)
+		}
+		listIDs = append(listIDs, listID)
+		requests = append(requests, BatchRequest{Method: "GET", URL: url})
+	}
+
+	results := make(map[string]*TaskDelta, len(listIDs))
+	responses, err := c.Batch(ctx, requests)
+	for i, listID := range listIDs {
+		if err != nil {
+			results[listID] = &TaskDelta{Err: err}
+			continue
+		}
+
+		var resp types.TodoTasksResponse
+		if err := responses[i].Decode(&resp); err != nil {
+			results[listID] = &TaskDelta{Err: err}
+			continue
+		}
+		result := &TaskDelta{Changes: resp.Value, DeltaLink: resp.DeltaLink}
+		if resp.NextLink != "" {
+			more, next, err := c.TasksDelta(ctx, listID, resp.NextLink)
+			result.Changes = append(result.Changes, more...)
+			result.DeltaLink, result.Err = next, err
+		}
+		results[listID] = result
+	}
+	return results
+}
+
+// ListChanges is the result of SyncLists.
+type ListChanges struct {
+	Lists []types.TodoTaskList
+
+	// All is every task list after the changes.
+	All []types.TodoTaskList
+
+	// Since is when the previous sync happened; zero on the first sync, in
+	// which case Lists holds every list rather than changes.
+	Since time.Time
+}
+
+// TaskChanges is the result of SyncTasks.
+type TaskChanges struct {
+	Tasks []types.TodoTask
+
+	// Since is when the previous sync happened; zero on the first sync, in
+	// which case Tasks holds every task rather than changes.
+	Since time.Time
+}
+
+// SyncLists returns the lists changed since the last call, using the delta
+// link saved in the config directory, and saves the new one. It also
+// forgets the task delta links of lists that were deleted.
+func (c *GraphClient) SyncLists(ctx context.Context) (*ListChanges, error) {
+	const key = "lists"
+	state, err := c.deltaTokens.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	lists, next, err := c.ListsDelta(ctx, state.DeltaLink)
+	if err != nil && state.DeltaLink != "" && IsDeltaExpired(err) {
+		// Start over with a new baseline.
+		state = DeltaState{}
+		lists, next, err = c.ListsDelta(ctx, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	all, removed := mergeLists(state.Lists, lists)
+	if err := c.deltaTokens.Set(key, DeltaState{DeltaLink: next, SyncedAt: now, Lists: all}); err != nil {
+		return nil, err
+	}
+	if len(removed) > 0 {
+		if err := c.deltaTokens.Delete(removed...); err != nil {
+			return nil, err
+		}
+	}
+	return &ListChanges{Lists: lists, All: all, Since: state.SyncedAt}, nil
+}
+
+// mergeLists applies list changes to the lists known before them. It
+// returns the resulting lists and the task delta keys of deleted lists.
+func mergeLists(known, changes []types.TodoTaskList) ([]types.TodoTaskList, []string) {
+	all := slices.Clone(known)
+	var removed []string
+	for _, change := range changes {
+		i := slices.IndexFunc(all, func(list types.TodoTaskList) bool { return list.ID == change.ID })
+		switch {
+		case change.Removed != nil:
+			removed = append(removed, "tasks:"+change.ID)
+			if i >= 0 {
+				all = slices.Delete(all, i, i+1)
+			}
+		case i >= 0:
+			all[i] = change
+		default:
+			all = append(all, change)
+		}
+	}
+	return all, removed
+}
+
+// SyncTasks returns the tasks in a list changed since the last call, using
+// the delta link saved in the config directory, and saves the new one.
+func (c *GraphClient) SyncTasks(ctx context.Context, listID string) (*TaskChanges, error) {
+	key := "tasks:" + listID
+	state, err := c.deltaTokens.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tasks, next, err := c.TasksDelta(ctx, listID, state.DeltaLink)
+	if err != nil && state.DeltaLink != "" && IsDeltaExpired(err) {
+		// Start over with a new baseline.
+		state = DeltaState{}
+		tasks, next, err = c.TasksDelta(ctx, listID, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.deltaTokens.Set(key, DeltaState{DeltaLink: next, SyncedAt: now}); err != nil {
+		return nil, err
+	}
+	return &TaskChanges{Tasks: tasks, Since: state.SyncedAt}, nil
+}
+
+// SyncAllTasks is SyncTasks for several lists, with the delta queries sent
+// in JSON batches. It returns the changes keyed by list ID; a list that
+// could not be synced is returned in the error map instead.
+func (c *GraphClient) SyncAllTasks(ctx context.Context, listIDs []string) (map[string]*TaskChanges, map[string]error, error) {
+	states := make(map[string]DeltaState, len(listIDs))
+	links := make(map[string]string, len(listIDs))
+	for _, listID := range listIDs {
+		state, err := c.deltaTokens.Get("tasks:" + listID)
+		if err != nil {
+			return nil, nil, err
+		}
+		states[listID] = state
+		links[listID] = state.DeltaLink
+	}
+
+	now := time.Now()
+	changes := map[string]*TaskChanges{}
+	failed := map[string]error{}
+	for listID, delta := range c.TasksDeltaBatch(ctx, links) {
+		if delta.Err != nil && links[listID] != "" && IsDeltaExpired(delta.Err) {
+			// Start over with a new baseline.
+			states[listID] = DeltaState{}
+			delta = &TaskDelta{}
+			delta.Changes, delta.DeltaLink, delta.Err = c.TasksDelta(ctx, listID, "")
+		}
+		if delta.Err != nil {
+			failed[listID] = delta.Err
+			continue
+		}
+		if err := c.deltaTokens.Set("tasks:"+listID, DeltaState{DeltaLink: delta.DeltaLink, SyncedAt: now}); err != nil {
+			return nil, nil, err
+		}
+		changes[listID] = &TaskChanges{Tasks: delta.Changes, Since: states[listID].SyncedAt}
+	}
+	return changes, failed, nil
+}
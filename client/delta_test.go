@@ -0,0 +1,179 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/auth"
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/graphtest"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+func TestSyncTasks(t *testing.T) {
+	srv, c := newTestClient(t)
+	srv.PageSize = 2
+	ctx := context.Background()
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	keep := srv.AddTask(list.ID, types.TodoTask{Title: "Keep"})
+	edit := srv.AddTask(list.ID, types.TodoTask{Title: "Edit"})
+	drop := srv.AddTask(list.ID, types.TodoTask{Title: "Drop"})
+
+	first, err := c.SyncTasks(ctx, list.ID)
+	if err != nil {
+		t.Fatalf("SyncTasks: %v", err)
+	}
+	if len(first.Tasks) != 3 || !first.Since.IsZero() {
+		t.Fatalf("first sync = %d tasks since %v, want all 3 tasks", len(first.Tasks), first.Since)
+	}
+
+	srv.ModifyTask(list.ID, edit.ID, func(task *types.TodoTask) { task.Title = "Edited" })
+	if err := c.DeleteTask(ctx, list.ID, drop.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := c.SyncTasks(ctx, list.ID)
+	if err != nil {
+		t.Fatalf("SyncTasks: %v", err)
+	}
+	if second.Since.IsZero() {
+		t.Error("second sync has no Since")
+	}
+	changed := map[string]types.TodoTask{}
+	for _, task := range second.Tasks {
+		changed[task.ID] = task
+	}
+	if _, ok := changed[keep.ID]; ok || len(changed) != 2 {
+		t.Errorf("changes = %+v, want only the edited and deleted tasks", second.Tasks)
+	}
+	if changed[edit.ID].Title != "Edited" {
+		t.Errorf("edited task = %+v", changed[edit.ID])
+	}
+	if changed[drop.ID].Removed == nil {
+		t.Errorf("deleted task = %+v, want @removed", changed[drop.ID])
+	}
+}
+
+func TestSyncRestartsWhenDeltaExpires(t *testing.T) {
+	srv, c := newTestClient(t)
+	ctx := context.Background()
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	srv.AddTask(list.ID, types.TodoTask{Title: "One"})
+
+	if _, err := c.SyncLists(ctx); err != nil {
+		t.Fatalf("SyncLists: %v", err)
+	}
+	if _, err := c.SyncTasks(ctx, list.ID); err != nil {
+		t.Fatalf("SyncTasks: %v", err)
+	}
+	srv.ExpireDeltaLinks()
+
+	lists, err := c.SyncLists(ctx)
+	if err != nil {
+		t.Fatalf("SyncLists after expiry: %v", err)
+	}
+	if len(lists.Lists) != 1 || !lists.Since.IsZero() {
+		t.Errorf("lists after expiry = %+v, want a new baseline", lists)
+	}
+	tasks, err := c.SyncTasks(ctx, list.ID)
+	if err != nil {
+		t.Fatalf("SyncTasks after expiry: %v", err)
+	}
+	if len(tasks.Tasks) != 1 || !tasks.Since.IsZero() {
+		t.Errorf("tasks after expiry = %+v, want a new baseline", tasks)
+	}
+}
+
+func TestSyncAllTasks(t *testing.T) {
+	srv, c := newTestClient(t)
+	ctx := context.Background()
+	home := srv.AddList(types.TodoTaskList{DisplayName: "Home"})
+	work := srv.AddList(types.TodoTaskList{DisplayName: "Work"})
+	srv.AddTask(home.ID, types.TodoTask{Title: "Dishes"})
+	srv.AddTask(work.ID, types.TodoTask{Title: "Report"})
+	srv.AddTask(work.ID, types.TodoTask{Title: "Slides"})
+
+	changes, failed, err := c.SyncAllTasks(ctx, []string{home.ID, work.ID, "missing"})
+	if err != nil {
+		t.Fatalf("SyncAllTasks: %v", err)
+	}
+	if len(changes[home.ID].Tasks) != 1 || len(changes[work.ID].Tasks) != 2 {
+		t.Errorf("changes = %+v", changes)
+	}
+	if _, ok := failed["missing"]; !ok || len(failed) != 1 {
+		t.Errorf("failed = %v, want only the missing list", failed)
+	}
+	if got := srv.Requests(); len(got) != 1 || got[0] != "POST /$batch" {
+		t.Errorf("requests = %v, want a single batch", got)
+	}
+
+	srv.AddTask(home.ID, types.TodoTask{Title: "Laundry"})
+	changes, _, err = c.SyncAllTasks(ctx, []string{home.ID, work.ID})
+	if err != nil {
+		t.Fatalf("SyncAllTasks: %v", err)
+	}
+	if len(changes[home.ID].Tasks) != 1 || changes[home.ID].Tasks[0].Title != "Laundry" || len(changes[work.ID].Tasks) != 0 {
+		t.Errorf("second sync = home %+v, work %+v, want only Laundry", changes[home.ID].Tasks, changes[work.ID].Tasks)
+	}
+}
+
+func TestSyncListsKeepsEveryList(t *testing.T) {
+	srv := graphtest.NewServer()
+	t.Cleanup(srv.Close)
+	dir := t.TempDir()
+	tm, err := auth.NewTokenManager("test-client", auth.WithAuthorityHost(srv.AuthorityHost()), auth.WithConfigDir(dir))
+	if err != nil {
+		t.Fatalf("NewTokenManager: %v", err)
+	}
+	if err := tm.SaveTokens(srv.Tokens()); err != nil {
+		t.Fatalf("SaveTokens: %v", err)
+	}
+	c := client.NewGraphClient(tm, client.WithBaseURL(srv.GraphURL()), client.WithRetryPolicy(testRetryPolicy))
+	ctx := context.Background()
+	home := srv.AddList(types.TodoTaskList{DisplayName: "Home"})
+	work := srv.AddList(types.TodoTaskList{DisplayName: "Work"})
+
+	if _, err := c.SyncLists(ctx); err != nil {
+		t.Fatalf("SyncLists: %v", err)
+	}
+	if _, _, err := c.SyncAllTasks(ctx, []string{home.ID, work.ID}); err != nil {
+		t.Fatalf("SyncAllTasks: %v", err)
+	}
+	if err := c.DeleteList(ctx, work.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RenameList(ctx, home.ID, "House"); err != nil {
+		t.Fatal(err)
+	}
+	garden := srv.AddList(types.TodoTaskList{DisplayName: "Garden"})
+
+	changes, err := c.SyncLists(ctx)
+	if err != nil {
+		t.Fatalf("SyncLists: %v", err)
+	}
+	var names []string
+	for _, list := range changes.All {
+		names = append(names, list.DisplayName)
+	}
+	if !slices.Equal(names, []string{"House", "Garden"}) || changes.All[1].ID != garden.ID {
+		t.Errorf("all lists = %+v, want House and Garden", changes.All)
+	}
+
+	data, err := os.ReadFile(tm.StatePath("delta.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var states map[string]client.DeltaState
+	if err := json.Unmarshal(data, &states); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := states["tasks:"+work.ID]; ok {
+		t.Error("the deleted list's task delta link was kept")
+	}
+	if _, ok := states["tasks:"+home.ID]; !ok {
+		t.Error("the remaining list's task delta link was dropped")
+	}
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// APIError is a request that Microsoft Graph answered with an error status.
+// Use errors.As to get at it from an error returned by GraphClient.
+type APIError struct {
+	StatusCode int
+
+	// Code is the Graph error code, e.g. "ErrorItemNotFound". It may be
+	// empty if the response had no Graph error body.
+	Code    string
+	Message string
+
+	// RequestID identifies the request to Microsoft support.
+	RequestID string
+
+	// RetryAfter is the wait Graph asked for on a throttled response.
+	RetryAfter time.Duration
+
+	InnerError *types.GraphInnerError
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("Graph API error (%s): %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("Graph API returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// graphError builds an APIError from a failed response, using the Graph
+// error body when it has one.
+func graphError(status int, header http.Header, body []byte) error {
+	apiErr := &APIError{StatusCode: status, Message: string(body), RequestID: header.Get("request-id")}
+	apiErr.RetryAfter, _ = parseRetryAfter(header.Get("Retry-After"), time.Now())
+
+	var graphErr types.GraphError
+	if json.Unmarshal(body, &graphErr) == nil && graphErr.Error.Message != "" {
+		apiErr.Code = graphErr.Error.Code
+		apiErr.Message = graphErr.Error.Message
+		apiErr.InnerError = graphErr.Error.InnerError
+		if inner := graphErr.Error.InnerError; inner != nil && inner.RequestID != "" {
+			apiErr.RequestID = inner.RequestID
+		}
+	}
+	return apiErr
+}
+
+func hasStatus(err error, statuses ...int) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, status := range statuses {
+		if apiErr.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotFound reports whether err means the item does not exist, for
+// example because it was deleted.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsDeltaExpired reports whether err means a delta link can no longer be
+// used and the data must be fetched from scratch.
+func IsDeltaExpired(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusGone || apiErr.Code == "syncStateNotFound" || apiErr.Code == "resyncRequired"
+}
@@ -5,78 +5,166 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/michMartineau/mcp-server-microsoft-todo/auth"
+	"github.com/michMartineau/mcp-server-microsoft-todo/odata"
 	"github.com/michMartineau/mcp-server-microsoft-todo/types"
 )
 
-const baseURL = "https://graph.microsoft.com/v1.0"
+// Microsoft Graph endpoints for WithBaseURL.
+const (
+	GlobalEndpoint   = "https://graph.microsoft.com/v1.0"
+	BetaEndpoint     = "https://graph.microsoft.com/beta"
+	USGovEndpoint    = "https://graph.microsoft.us/v1.0"
+	USGovDoDEndpoint = "https://dod-graph.microsoft.us/v1.0"
+	ChinaEndpoint    = "https://microsoftgraph.chinacloudapi.cn/v1.0"
+)
 
 // GraphClient makes authenticated requests to the Microsoft Graph API.
 type GraphClient struct {
 	tokenManager *auth.TokenManager
+	baseURL      string
 	httpClient   *http.Client
+	deltaTokens  *DeltaTokens
+	writeHooks   []WriteHook
+	retryPolicy  RetryPolicy
+	retries      retryCounter
+}
+
+// WriteHook is called after every successful write request. listID is the
+// list whose tasks were written, or empty if only the collection of lists
+// changed; listChanged reports whether the list itself was created, renamed
+// or deleted.
+type WriteHook func(listID string, listChanged bool)
+
+// OnWrite registers a hook called after every successful write, so local
+// copies of the data can be invalidated.
+func (c *GraphClient) OnWrite(hook WriteHook) {
+	c.writeHooks = append(c.writeHooks, hook)
+}
+
+// notifyWrite calls the write hooks for a successful request to url.
+func (c *GraphClient) notifyWrite(method, url string) {
+	if method == "GET" || len(c.writeHooks) == 0 {
+		return
+	}
+	_, path, ok := strings.Cut(url, "/me/todo/lists")
+	if !ok {
+		return
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	listID := segments[0]
+	listChanged := len(segments) == 1
+	for _, hook := range c.writeHooks {
+		hook(listID, listChanged)
+	}
 }
 
-// NewGraphClient creates a new Graph API client.
-func NewGraphClient(tm *auth.TokenManager) *GraphClient {
-	return &GraphClient{
+// NewGraphClient creates a new Graph API client. Delta links are saved in
+// delta.json, or the token manager's profile's equivalent, next to its
+// tokens.
+func NewGraphClient(tm *auth.TokenManager, opts ...Option) *GraphClient {
+	c := &GraphClient{
 		tokenManager: tm,
+		baseURL:      GlobalEndpoint,
 		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		deltaTokens:  NewDeltaTokens(tm.StatePath("delta.json")),
+		retryPolicy:  DefaultRetryPolicy(),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
-// doRequest performs an authenticated HTTP request and returns the response body.
-func (c *GraphClient) doRequest(ctx context.Context, method, url string, body io.Reader) ([]byte, error) {
-	token, err := c.tokenManager.GetValidToken(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("getting auth token: %w", err)
-	}
+// BaseURL returns the Graph endpoint the client sends requests to.
+func (c *GraphClient) BaseURL() string {
+	return c.baseURL
+}
 
-	req, err := http.NewRequestWithContext(ctx, method, url, body)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+// IsUnreachable reports whether err means Microsoft Graph (or the login
+// service) could not be reached at all, as opposed to rejecting the request.
+func IsUnreachable(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && !errors.Is(err, context.Canceled)
+}
+
+// IsNotSent reports whether err means a request never reached Microsoft
+// Graph because no connection could be opened, for example when the host
+// name did not resolve. Such a request certainly had no effect, whereas one
+// cut off after it was sent may have been carried out.
+func IsNotSent(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
 	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
 
-	req.Header.Set("Authorization", "Bearer "+token)
+// doRequest performs an authenticated HTTP request and returns the response
+// body. Transient failures are retried according to the retry policy.
+func (c *GraphClient) doRequest(ctx context.Context, method, url string, body io.Reader) ([]byte, error) {
+	var payload []byte
 	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
+		var err error
+		if payload, err = io.ReadAll(body); err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("executing request: %w", err)
-	}
-	defer resp.Body.Close()
+	resp, err := c.send(ctx, method, func() (*http.Request, error) {
+		token, err := c.tokenManager.GetValidToken(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("getting auth token: %w", err)
+		}
+
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
 
-	respBody, err := io.ReadAll(resp.Body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		return req, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		return nil, err
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		var graphErr types.GraphError
-		if json.Unmarshal(respBody, &graphErr) == nil && graphErr.Error.Message != "" {
-			return nil, fmt.Errorf("Graph API error (%s): %s", graphErr.Error.Code, graphErr.Error.Message)
-		}
-		return nil, fmt.Errorf("Graph API returned status %d: %s", resp.StatusCode, respBody)
+	if resp.status < 200 || resp.status >= 300 {
+		return nil, graphError(resp.status, resp.header, resp.body)
 	}
 
-	return respBody, nil
+	c.notifyWrite(method, url)
+	return resp.body, nil
 }
 
 // ListTodoLists returns the user's To-Do task lists, following pagination.
-// If filter is non-empty, it is passed as an OData $filter query parameter.
-func (c *GraphClient) ListTodoLists(ctx context.Context, filter string) ([]types.TodoTaskList, error) {
+// If filter is non-empty, it is passed as an OData $filter query parameter;
+// build it from ListFields.
+func (c *GraphClient) ListTodoLists(ctx context.Context, filter odata.Expr) ([]types.TodoTaskList, error) {
 	var allLists []types.TodoTaskList
-	u := baseURL + "/me/todo/lists"
-	if filter != "" {
-		u += "?$filter=" + url.QueryEscape(filter)
+	u := c.baseURL + "/me/todo/lists"
+	if !filter.IsZero() {
+		u += "?$filter=" + url.QueryEscape(filter.String())
 	}
 
 	for u != "" {
@@ -95,10 +183,18 @@ func (c *GraphClient) ListTodoLists(ctx context.Context, filter string) ([]types
 	return allLists, nil
 }
 
-// ListTasks returns all tasks in a specific task list, following pagination.
-func (c *GraphClient) ListTasks(ctx context.Context, listID string) ([]types.TodoTask, error) {
+// ListTasks returns the tasks in a specific task list that match query,
+// following pagination until query.Top tasks are collected. Checklist items
+// and linked resources are expanded inline, unless query.Select leaves them
+// out.
+func (c *GraphClient) ListTasks(ctx context.Context, listID string, query TaskQuery) ([]types.TodoTask, error) {
+	params, err := query.encode()
+	if err != nil {
+		return nil, err
+	}
+
 	var allTasks []types.TodoTask
-	url := fmt.Sprintf("%s/me/todo/lists/%s/tasks", baseURL, listID)
+	url := fmt.Sprintf("%s/me/todo/lists/%s/tasks?%s", c.baseURL, listID, params)
 
 	for url != "" {
 		body, err := c.doRequest(ctx, "GET", url, nil)
@@ -112,26 +208,93 @@ func (c *GraphClient) ListTasks(ctx context.Context, listID string) ([]types.Tod
 		}
 		allTasks = append(allTasks, resp.Value...)
 		url = resp.NextLink
+
+		if query.Top > 0 && len(allTasks) >= query.Top {
+			allTasks = allTasks[:query.Top]
+			break
+		}
 	}
 	return allTasks, nil
 }
 
+// NewTask describes a task to create. Only Title is required.
+type NewTask struct {
+	Title        string
+	Body         string
+	Importance   string
+	DueDate      string
+	StartDate    string
+	ReminderDate string
+
+	// TimeZone is the IANA time zone the dates are expressed in. Defaults to UTC.
+	TimeZone string
+
+	// Recurrence makes the task repeat. It is validated before sending.
+	Recurrence *types.PatternedRecurrence
+
+	Categories []string
+
+	// LinkedResources are created together with the task.
+	LinkedResources []types.LinkedResource
+}
+
 // CreateTask creates a new task in the specified list and returns the created task.
-func (c *GraphClient) CreateTask(ctx context.Context, listID string, title string, body string, importance string, dueDate string) (*types.TodoTask, error) {
-	url := fmt.Sprintf("%s/me/todo/lists/%s/tasks", baseURL, listID)
+func (c *GraphClient) CreateTask(ctx context.Context, listID string, newTask NewTask) (*types.TodoTask, error) {
+	task, err := newTask.payload()
+	if err != nil {
+		return nil, err
+	}
+	return c.postTask(ctx, listID, task)
+}
 
+// payload builds the JSON body that creates the task.
+func (newTask NewTask) payload() (map[string]interface{}, error) {
 	task := map[string]interface{}{
-		"title": title, // always required
+		"title": newTask.Title, // always required
+	}
+	if newTask.Importance != "" {
+		task["importance"] = newTask.Importance
 	}
-	if importance != "" {
-		task["importance"] = importance
+	if newTask.Body != "" {
+		task["body"] = types.ItemBody{Content: newTask.Body, ContentType: "text"}
 	}
-	if body != "" {
-		task["body"] = types.ItemBody{Content: body, ContentType: "text"}
+	dates := []struct{ field, value string }{
+		{"dueDateTime", newTask.DueDate},
+		{"startDateTime", newTask.StartDate},
+		{"reminderDateTime", newTask.ReminderDate},
 	}
-	if dueDate != "" {
-		task["dueDateTime"] = types.DateTimeZone{DateTime: dueDate, TimeZone: "UTC"}
+	for _, date := range dates {
+		if date.value == "" {
+			continue
+		}
+		dt, err := newDateTimeZone(date.value, newTask.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", date.field, err)
+		}
+		task[date.field] = dt
+	}
+	if newTask.ReminderDate != "" {
+		task["isReminderOn"] = true
+	}
+	if newTask.Recurrence != nil {
+		recurrence := normalizeRecurrence(newTask.Recurrence)
+		if err := validateRecurrence(recurrence); err != nil {
+			return nil, err
+		}
+		task["recurrence"] = recurrence
 	}
+	if len(newTask.Categories) > 0 {
+		task["categories"] = newTask.Categories
+	}
+	if len(newTask.LinkedResources) > 0 {
+		task["linkedResources"] = newTask.LinkedResources
+	}
+	return task, nil
+}
+
+// postTask creates a task from a raw JSON payload.
+func (c *GraphClient) postTask(ctx context.Context, listID string, task interface{}) (*types.TodoTask, error) {
+	url := fmt.Sprintf("%s/me/todo/lists/%s/tasks", c.baseURL, listID)
 
 	payload, err := json.Marshal(task)
 	if err != nil {
@@ -150,7 +313,7 @@ func (c *GraphClient) CreateTask(ctx context.Context, listID string, title strin
 
 // CompleteTask marks a task as completed.
 func (c *GraphClient) CompleteTask(ctx context.Context, listID, taskID string) (*types.TodoTask, error) {
-	url := fmt.Sprintf("%s/me/todo/lists/%s/tasks/%s", baseURL, listID, taskID)
+	url := fmt.Sprintf("%s/me/todo/lists/%s/tasks/%s", c.baseURL, listID, taskID)
 
 	update := map[string]string{"status": "completed"}
 	payload, err := json.Marshal(update)
@@ -177,7 +340,7 @@ func (c *GraphClient) CreateList(ctx context.Context, displayName string) (*type
 		return nil, fmt.Errorf("marshaling list: %w", err)
 	}
 
-	respBody, err := c.doRequest(ctx, "POST", baseURL+"/me/todo/lists", bytes.NewReader(payload))
+	respBody, err := c.doRequest(ctx, "POST", c.baseURL+"/me/todo/lists", bytes.NewReader(payload))
 	if err != nil {
 		return nil, err
 	}
@@ -191,7 +354,188 @@ func (c *GraphClient) CreateList(ctx context.Context, displayName string) (*type
 
 // DeleteTask removes a task from a list.
 func (c *GraphClient) DeleteTask(ctx context.Context, listID, taskID string) error {
-	url := fmt.Sprintf("%s/me/todo/lists/%s/tasks/%s", baseURL, listID, taskID)
+	url := fmt.Sprintf("%s/me/todo/lists/%s/tasks/%s", c.baseURL, listID, taskID)
 	_, err := c.doRequest(ctx, "DELETE", url, nil)
 	return err
 }
+
+// GetTask returns a single task from a list.
+func (c *GraphClient) GetTask(ctx context.Context, listID, taskID string) (*types.TodoTask, error) {
+	url := fmt.Sprintf("%s/me/todo/lists/%s/tasks/%s", c.baseURL, listID, taskID)
+
+	respBody, err := c.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var task types.TodoTask
+	if err := json.Unmarshal(respBody, &task); err != nil {
+		return nil, fmt.Errorf("parsing task: %w", err)
+	}
+	return &task, nil
+}
+
+// TaskUpdate describes a partial update to a task. Nil fields are left
+// untouched on the server; an empty date string clears that date.
+type TaskUpdate struct {
+	Title        *string
+	Body         *string
+	Importance   *string
+	Status       *string
+	DueDate      *string
+	StartDate    *string
+	ReminderDate *string
+
+	// Recurrence replaces the task's recurrence when set. A recurrence
+	// with neither pattern nor range removes it.
+	Recurrence *types.PatternedRecurrence
+
+	// Categories replaces the task's categories when non-nil. An empty,
+	// non-nil slice removes them all.
+	Categories []string
+
+	// TimeZone is the IANA time zone the dates are expressed in. Defaults to UTC.
+	TimeZone string
+}
+
+// IsEmpty reports whether the update would not change anything.
+func (u TaskUpdate) IsEmpty() bool {
+	return u.Title == nil && u.Body == nil && u.Importance == nil && u.Status == nil &&
+		u.DueDate == nil && u.StartDate == nil && u.ReminderDate == nil && u.Recurrence == nil &&
+		u.Categories == nil
+}
+
+// patch builds the PATCH payload, containing only the fields that were set.
+func (u TaskUpdate) patch() (map[string]interface{}, error) {
+	patch := map[string]interface{}{}
+	if u.Title != nil {
+		patch["title"] = *u.Title
+	}
+	if u.Body != nil {
+		patch["body"] = types.ItemBody{Content: *u.Body, ContentType: "text"}
+	}
+	if u.Importance != nil {
+		patch["importance"] = *u.Importance
+	}
+	if u.Status != nil {
+		patch["status"] = *u.Status
+	}
+	dates := []struct {
+		field string
+		value *string
+	}{
+		{"dueDateTime", u.DueDate},
+		{"startDateTime", u.StartDate},
+		{"reminderDateTime", u.ReminderDate},
+	}
+	for _, date := range dates {
+		if date.value == nil {
+			continue
+		}
+		if *date.value == "" {
+			patch[date.field] = nil
+			continue
+		}
+		dt, err := newDateTimeZone(*date.value, u.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", date.field, err)
+		}
+		patch[date.field] = dt
+	}
+	if u.ReminderDate != nil {
+		patch["isReminderOn"] = *u.ReminderDate != ""
+	}
+	if u.Recurrence != nil {
+		if u.Recurrence.Pattern == nil && u.Recurrence.Range == nil {
+			patch["recurrence"] = nil
+		} else {
+			recurrence := normalizeRecurrence(u.Recurrence)
+			if err := validateRecurrence(recurrence); err != nil {
+				return nil, err
+			}
+			patch["recurrence"] = recurrence
+		}
+	}
+	if u.Categories != nil {
+		patch["categories"] = u.Categories
+	}
+	return patch, nil
+}
+
+// UpdateTask applies a partial update to a task and returns the updated task.
+func (c *GraphClient) UpdateTask(ctx context.Context, listID, taskID string, update TaskUpdate) (*types.TodoTask, error) {
+	url := fmt.Sprintf("%s/me/todo/lists/%s/tasks/%s", c.baseURL, listID, taskID)
+
+	patch, err := update.patch()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(patch)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling update: %w", err)
+	}
+
+	respBody, err := c.doRequest(ctx, "PATCH", url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	var task types.TodoTask
+	if err := json.Unmarshal(respBody, &task); err != nil {
+		return nil, fmt.Errorf("parsing updated task: %w", err)
+	}
+	return &task, nil
+}
+
+// GetList returns a single task list.
+func (c *GraphClient) GetList(ctx context.Context, listID string) (*types.TodoTaskList, error) {
+	respBody, err := c.doRequest(ctx, "GET", c.baseURL+"/me/todo/lists/"+listID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var list types.TodoTaskList
+	if err := json.Unmarshal(respBody, &list); err != nil {
+		return nil, fmt.Errorf("parsing task list: %w", err)
+	}
+	return &list, nil
+}
+
+// RenameList changes the display name of a task list and returns it.
+func (c *GraphClient) RenameList(ctx context.Context, listID, displayName string) (*types.TodoTaskList, error) {
+	payload, err := json.Marshal(map[string]string{"displayName": displayName})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling list: %w", err)
+	}
+
+	respBody, err := c.doRequest(ctx, "PATCH", c.baseURL+"/me/todo/lists/"+listID, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	var list types.TodoTaskList
+	if err := json.Unmarshal(respBody, &list); err != nil {
+		return nil, fmt.Errorf("parsing updated list: %w", err)
+	}
+	return &list, nil
+}
+
+// IsWellknownList reports whether a list is one of the built-in lists, such
+// as the default "Tasks" list or "Flagged email", which cannot be deleted.
+func IsWellknownList(list types.TodoTaskList) bool {
+	return list.WellknownName != "" && list.WellknownName != "none"
+}
+
+// DeleteList removes a task list and all its tasks. Built-in lists are refused.
+func (c *GraphClient) DeleteList(ctx context.Context, listID string) error {
+	list, err := c.GetList(ctx, listID)
+	if err != nil {
+		return err
+	}
+	if IsWellknownList(*list) {
+		return fmt.Errorf("list \"%s\" is the built-in %s list and cannot be deleted", list.DisplayName, list.WellknownName)
+	}
+
+	_, err = c.doRequest(ctx, "DELETE", c.baseURL+"/me/todo/lists/"+listID, nil)
+	return err
+}
@@ -0,0 +1,478 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/auth"
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/graphtest"
+	"github.com/michMartineau/mcp-server-microsoft-todo/odata"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// testRetryPolicy retries like the default policy, without the long waits.
+var testRetryPolicy = client.RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+// newTestClient returns a client signed in to a fresh fake server.
+func newTestClient(t *testing.T) (*graphtest.Server, *client.GraphClient) {
+	t.Helper()
+	srv := graphtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	tm, err := auth.NewTokenManager("test-client", auth.WithAuthorityHost(srv.AuthorityHost()), auth.WithConfigDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewTokenManager: %v", err)
+	}
+	if err := tm.SaveTokens(srv.Tokens()); err != nil {
+		t.Fatalf("SaveTokens: %v", err)
+	}
+	return srv, client.NewGraphClient(tm, client.WithBaseURL(srv.GraphURL()), client.WithRetryPolicy(testRetryPolicy))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestListTodoListsPaginates(t *testing.T) {
+	srv, c := newTestClient(t)
+	srv.PageSize = 2
+	for _, name := range []string{"Tasks", "Groceries", "Work", "Travel", "Books"} {
+		srv.AddList(types.TodoTaskList{DisplayName: name})
+	}
+
+	lists, err := c.ListTodoLists(context.Background(), odata.Expr{})
+	if err != nil {
+		t.Fatalf("ListTodoLists: %v", err)
+	}
+	if len(lists) != 5 {
+		t.Fatalf("got %d lists, want 5", len(lists))
+	}
+	if got := len(srv.Requests()); got != 3 {
+		t.Errorf("made %d requests, want 3 pages", got)
+	}
+
+	filter, err := client.ListFields.Compare("displayName", odata.Eq, "Work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lists, err = c.ListTodoLists(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("ListTodoLists with filter: %v", err)
+	}
+	if len(lists) != 1 || lists[0].DisplayName != "Work" {
+		t.Errorf("filtered lists = %+v, want only Work", lists)
+	}
+}
+
+func TestTaskLifecycle(t *testing.T) {
+	srv, c := newTestClient(t)
+	ctx := context.Background()
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks", WellknownName: "defaultList"})
+
+	created, err := c.CreateTask(ctx, list.ID, client.NewTask{
+		Title:      "Buy milk",
+		Body:       "Semi-skimmed",
+		Importance: "high",
+		DueDate:    "2025-03-01",
+		TimeZone:   "Europe/Paris",
+		Categories: []string{"Errands"},
+	})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if created.ID == "" || created.Title != "Buy milk" || created.Importance != "high" {
+		t.Fatalf("created task = %+v", created)
+	}
+	if created.DueDateTime == nil || created.DueDateTime.DateTime != "2025-03-01T00:00:00" || created.DueDateTime.TimeZone != "Europe/Paris" {
+		t.Errorf("due date = %+v, want midnight in Europe/Paris", created.DueDateTime)
+	}
+
+	updated, err := c.UpdateTask(ctx, list.ID, created.ID, client.TaskUpdate{Title: ptr("Buy oat milk"), DueDate: ptr("")})
+	if err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if updated.Title != "Buy oat milk" || updated.DueDateTime != nil {
+		t.Errorf("updated task = %+v, want new title and no due date", updated)
+	}
+	if updated.Importance != "high" {
+		t.Errorf("importance = %q, want it left unchanged", updated.Importance)
+	}
+
+	completed, err := c.CompleteTask(ctx, list.ID, created.ID)
+	if err != nil {
+		t.Fatalf("CompleteTask: %v", err)
+	}
+	if completed.Status != "completed" || completed.CompletedDateTime == nil {
+		t.Errorf("completed task = %+v", completed)
+	}
+
+	if err := c.DeleteTask(ctx, list.ID, created.ID); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if _, err := c.GetTask(ctx, list.ID, created.ID); !client.IsNotFound(err) {
+		t.Errorf("GetTask after delete: error = %v, want not found", err)
+	}
+}
+
+func TestCreateTaskWithRecurrence(t *testing.T) {
+	srv, c := newTestClient(t)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	recurrence := &types.PatternedRecurrence{Pattern: &types.RecurrencePattern{Type: "weekly", DaysOfWeek: []string{"monday"}}}
+
+	if err := client.ValidateRecurrence(recurrence); err != nil {
+		t.Fatalf("ValidateRecurrence: %v", err)
+	}
+	created, err := c.CreateTask(context.Background(), list.ID, client.NewTask{Title: "Standup", Recurrence: recurrence})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	today := time.Now().Format("2006-01-02")
+	if r := created.Recurrence; r == nil || r.Pattern.Interval != 1 || r.Range == nil || r.Range.Type != "noEnd" || r.Range.StartDate != today {
+		t.Errorf("recurrence = %+v, want the defaults filled in", created.Recurrence)
+	}
+	if recurrence.Pattern.Interval != 0 || recurrence.Range != nil {
+		t.Errorf("recurrence given = %+v %+v, want it unchanged", recurrence.Pattern, recurrence.Range)
+	}
+}
+
+func TestCreateTaskReportsFirstInvalidDate(t *testing.T) {
+	srv, c := newTestClient(t)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+
+	_, err := c.CreateTask(context.Background(), list.ID, client.NewTask{Title: "Dentist", DueDate: "tomorrow", ReminderDate: "soon"})
+	if err == nil || !strings.HasPrefix(err.Error(), `dueDateTime: invalid date "tomorrow"`) {
+		t.Errorf("CreateTask error = %v, want the due date named", err)
+	}
+	_, err = c.UpdateTask(context.Background(), list.ID, "task-1", client.TaskUpdate{StartDate: ptr("later"), ReminderDate: ptr("soon")})
+	if err == nil || !strings.HasPrefix(err.Error(), `startDateTime: invalid date "later"`) {
+		t.Errorf("UpdateTask error = %v, want the start date named", err)
+	}
+}
+
+func TestListTasksQuery(t *testing.T) {
+	srv, c := newTestClient(t)
+	srv.PageSize = 2
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Work"})
+	srv.AddTask(list.ID, types.TodoTask{Title: "Report", Importance: "high", DueDateTime: &types.DateTimeZone{DateTime: "2025-01-10T00:00:00", TimeZone: "UTC"}})
+	srv.AddTask(list.ID, types.TodoTask{Title: "Slides", Importance: "high", DueDateTime: &types.DateTimeZone{DateTime: "2025-01-05T00:00:00", TimeZone: "UTC"}})
+	srv.AddTask(list.ID, types.TodoTask{Title: "Expenses", Status: "completed", Categories: []string{"Finance"}})
+	srv.AddTask(list.ID, types.TodoTask{Title: "Email", ChecklistItems: []types.ChecklistItem{{DisplayName: "Draft"}}})
+
+	dueBefore := time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		query client.TaskQuery
+		want  []string
+	}{
+		{name: "all", query: client.TaskQuery{}, want: []string{"Report", "Slides", "Expenses", "Email"}},
+		{name: "open", query: client.TaskQuery{NotStatus: "completed"}, want: []string{"Report", "Slides", "Email"}},
+		{name: "importance ordered by due", query: client.TaskQuery{Importance: "high", OrderBy: "due"}, want: []string{"Slides", "Report"}},
+		{name: "due before", query: client.TaskQuery{DueBefore: &dueBefore}, want: []string{"Slides"}},
+		{name: "category before top", query: client.TaskQuery{Category: "Finance", Top: 1}, want: []string{"Expenses"}},
+		{name: "category is case-sensitive", query: client.TaskQuery{Category: "finance"}},
+		{name: "top across pages", query: client.TaskQuery{OrderBy: "title", Descending: true, Top: 3}, want: []string{"Slides", "Report", "Expenses"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, err := c.ListTasks(context.Background(), list.ID, tt.query)
+			if err != nil {
+				t.Fatalf("ListTasks: %v", err)
+			}
+			var got []string
+			for _, task := range tasks {
+				got = append(got, task.Title)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	tasks, err := c.ListTasks(context.Background(), list.ID, client.TaskQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items := tasks[3].ChecklistItems; len(items) != 1 || items[0].DisplayName != "Draft" {
+		t.Errorf("checklist items were not expanded: %+v", items)
+	}
+}
+
+// queryRecorder records the query strings of the requests it passes on.
+type queryRecorder struct {
+	queries []string
+}
+
+func (r *queryRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.queries = append(r.queries, req.URL.RawQuery)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestListTasksSelectQuery(t *testing.T) {
+	srv, _ := newTestClient(t)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Work"})
+	srv.AddTask(list.ID, types.TodoTask{Title: "Email", ChecklistItems: []types.ChecklistItem{{DisplayName: "Draft"}}})
+	tm, err := auth.NewTokenManager("test-client", auth.WithAuthorityHost(srv.AuthorityHost()), auth.WithConfigDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.SaveTokens(srv.Tokens()); err != nil {
+		t.Fatal(err)
+	}
+	recorder := &queryRecorder{}
+	c := client.NewGraphClient(tm, client.WithBaseURL(srv.GraphURL()), client.WithTransport(recorder))
+
+	tests := []struct {
+		fields []string
+		want   string
+	}{
+		{nil, "$expand=checklistItems,linkedResources"},
+		{[]string{"title", "status"}, "$select=id,title,status"},
+		{[]string{"title", "checklistItems"}, "$select=id,title&$expand=checklistItems"},
+	}
+	for _, tt := range tests {
+		recorder.queries = nil
+		tasks, err := c.ListTasks(context.Background(), list.ID, client.TaskQuery{Select: tt.fields})
+		if err != nil {
+			t.Fatalf("ListTasks %v: %v", tt.fields, err)
+		}
+		if len(recorder.queries) != 1 || recorder.queries[0] != tt.want {
+			t.Errorf("query for %v = %q, want %q", tt.fields, recorder.queries, tt.want)
+		}
+		if expanded := len(tasks[0].ChecklistItems) > 0; expanded != strings.Contains(tt.want, "checklistItems") {
+			t.Errorf("checklist items for %v = %+v", tt.fields, tasks[0].ChecklistItems)
+		}
+	}
+}
+
+func TestListManagement(t *testing.T) {
+	srv, c := newTestClient(t)
+	ctx := context.Background()
+	defaultList := srv.AddList(types.TodoTaskList{DisplayName: "Tasks", WellknownName: "defaultList"})
+
+	list, err := c.CreateList(ctx, "Groceries")
+	if err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+	renamed, err := c.RenameList(ctx, list.ID, "Shopping")
+	if err != nil {
+		t.Fatalf("RenameList: %v", err)
+	}
+	if renamed.DisplayName != "Shopping" {
+		t.Errorf("renamed list = %+v", renamed)
+	}
+
+	if err := c.DeleteList(ctx, defaultList.ID); err == nil {
+		t.Error("DeleteList of the built-in list succeeded")
+	}
+	if err := c.DeleteList(ctx, list.ID); err != nil {
+		t.Fatalf("DeleteList: %v", err)
+	}
+	if lists := srv.Lists(); len(lists) != 1 || lists[0].ID != defaultList.ID {
+		t.Errorf("lists after delete = %+v, want only the built-in list", lists)
+	}
+}
+
+func TestChecklistItems(t *testing.T) {
+	srv, c := newTestClient(t)
+	ctx := context.Background()
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	task := srv.AddTask(list.ID, types.TodoTask{Title: "Pack"})
+
+	item, err := c.CreateChecklistItem(ctx, list.ID, task.ID, "Passport")
+	if err != nil {
+		t.Fatalf("CreateChecklistItem: %v", err)
+	}
+	if _, err := c.CreateChecklistItem(ctx, list.ID, task.ID, "Charger"); err != nil {
+		t.Fatalf("CreateChecklistItem: %v", err)
+	}
+	if _, err := c.CheckChecklistItem(ctx, list.ID, task.ID, item.ID, true); err != nil {
+		t.Fatalf("CheckChecklistItem: %v", err)
+	}
+	if _, err := c.UpdateChecklistItem(ctx, list.ID, task.ID, item.ID, "Passport and visa"); err != nil {
+		t.Fatalf("UpdateChecklistItem: %v", err)
+	}
+
+	items, err := c.ListChecklistItems(ctx, list.ID, task.ID)
+	if err != nil {
+		t.Fatalf("ListChecklistItems: %v", err)
+	}
+	if len(items) != 2 || items[0].DisplayName != "Passport and visa" || !items[0].IsChecked || items[1].IsChecked {
+		t.Fatalf("checklist items = %+v", items)
+	}
+
+	if err := c.DeleteChecklistItem(ctx, list.ID, task.ID, item.ID); err != nil {
+		t.Fatalf("DeleteChecklistItem: %v", err)
+	}
+	if err := c.DeleteChecklistItem(ctx, list.ID, task.ID, item.ID); !client.IsNotFound(err) {
+		t.Errorf("deleting a deleted item: error = %v, want not found", err)
+	}
+}
+
+func TestMoveTask(t *testing.T) {
+	srv, c := newTestClient(t)
+	ctx := context.Background()
+	from := srv.AddList(types.TodoTaskList{DisplayName: "Inbox"})
+	to := srv.AddList(types.TodoTaskList{DisplayName: "Work"})
+	task := srv.AddTask(from.ID, types.TodoTask{
+		Title:           "Review PR",
+		Importance:      "high",
+		ChecklistItems:  []types.ChecklistItem{{DisplayName: "Read diff", IsChecked: true}, {DisplayName: "Run tests"}},
+		LinkedResources: []types.LinkedResource{{WebURL: "https://example.com/pr/1", ApplicationName: "GitHub", DisplayName: "PR 1"}},
+	})
+
+	moved, err := c.MoveTask(ctx, from.ID, task.ID, to.ID)
+	if err != nil {
+		t.Fatalf("MoveTask: %v", err)
+	}
+	if _, ok := srv.Task(from.ID, task.ID); ok {
+		t.Error("the original task still exists")
+	}
+	copied, ok := srv.Task(to.ID, moved.ID)
+	if !ok {
+		t.Fatal("the moved task is not in the target list")
+	}
+	if copied.Title != "Review PR" || copied.Importance != "high" {
+		t.Errorf("moved task = %+v", copied)
+	}
+	if len(copied.ChecklistItems) != 2 || !copied.ChecklistItems[0].IsChecked {
+		t.Errorf("checklist items = %+v", copied.ChecklistItems)
+	}
+	if len(copied.LinkedResources) != 1 || copied.LinkedResources[0].WebURL != "https://example.com/pr/1" {
+		t.Errorf("linked resources = %+v", copied.LinkedResources)
+	}
+}
+
+func TestMoveCompletedTask(t *testing.T) {
+	srv, c := newTestClient(t)
+	from := srv.AddList(types.TodoTaskList{DisplayName: "Inbox"})
+	to := srv.AddList(types.TodoTaskList{DisplayName: "Done"})
+	completedAt := &types.DateTimeZone{DateTime: "2025-01-15T09:30:00.0000000", TimeZone: "UTC"}
+	task := srv.AddTask(from.ID, types.TodoTask{Title: "File taxes", Status: "completed", CompletedDateTime: completedAt})
+
+	moved, err := c.MoveTask(context.Background(), from.ID, task.ID, to.ID)
+	if err != nil {
+		t.Fatalf("MoveTask: %v", err)
+	}
+	copied, ok := srv.Task(to.ID, moved.ID)
+	if !ok {
+		t.Fatal("the moved task is not in the target list")
+	}
+	if copied.Status != "completed" || copied.CompletedDateTime == nil || *copied.CompletedDateTime != *completedAt {
+		t.Errorf("moved task = %s, completed %+v; want it completed at %+v", copied.Status, copied.CompletedDateTime, completedAt)
+	}
+}
+
+func TestMoveTaskRollsBack(t *testing.T) {
+	srv, c := newTestClient(t)
+	from := srv.AddList(types.TodoTaskList{DisplayName: "Inbox"})
+	to := srv.AddList(types.TodoTaskList{DisplayName: "Work"})
+	task := srv.AddTask(from.ID, types.TodoTask{Title: "Review PR"})
+	srv.Fail(graphtest.Failure{Method: "DELETE", Path: "/me/todo/lists/" + from.ID, Status: 403, Times: 1})
+
+	if _, err := c.MoveTask(context.Background(), from.ID, task.ID, to.ID); err == nil {
+		t.Fatal("MoveTask succeeded although the original could not be deleted")
+	}
+	if _, ok := srv.Task(from.ID, task.ID); !ok {
+		t.Error("the original task is gone")
+	}
+	if tasks := srv.Tasks(to.ID); len(tasks) != 0 {
+		t.Errorf("the copy was not rolled back: %+v", tasks)
+	}
+}
+
+func TestMoveTaskWhenDeleteResponseIsLost(t *testing.T) {
+	srv, c := newTestClient(t)
+	from := srv.AddList(types.TodoTaskList{DisplayName: "Inbox"})
+	to := srv.AddList(types.TodoTaskList{DisplayName: "Work"})
+	task := srv.AddTask(from.ID, types.TodoTask{Title: "Review PR"})
+	// The DELETE succeeds, but its response is lost; the retry gets a 404.
+	srv.Fail(graphtest.Failure{Method: "DELETE", Path: "/me/todo/lists/" + from.ID, Status: 503, Times: 1, AfterProcessing: true})
+
+	moved, err := c.MoveTask(context.Background(), from.ID, task.ID, to.ID)
+	if err != nil {
+		t.Fatalf("MoveTask: %v", err)
+	}
+	if _, ok := srv.Task(from.ID, task.ID); ok {
+		t.Error("the original task still exists")
+	}
+	if _, ok := srv.Task(to.ID, moved.ID); !ok {
+		t.Error("the moved task was rolled back, so the task is lost")
+	}
+}
+
+func TestMoveTaskWhenDeleteKeepsFailing(t *testing.T) {
+	srv, c := newTestClient(t)
+	from := srv.AddList(types.TodoTaskList{DisplayName: "Inbox"})
+	to := srv.AddList(types.TodoTaskList{DisplayName: "Work"})
+	task := srv.AddTask(from.ID, types.TodoTask{Title: "Review PR"})
+	// The DELETE succeeds, but it and every retry lose their response.
+	srv.Fail(graphtest.Failure{Method: "DELETE", Path: "/me/todo/lists/" + from.ID, Disconnect: true, AfterProcessing: true})
+
+	moved, err := c.MoveTask(context.Background(), from.ID, task.ID, to.ID)
+	if err != nil {
+		t.Fatalf("MoveTask: %v", err)
+	}
+	if _, ok := srv.Task(to.ID, moved.ID); !ok {
+		t.Error("the moved task was rolled back, so the task is lost")
+	}
+}
+
+func TestMoveTaskKeepsBothWhenOriginalCannotBeChecked(t *testing.T) {
+	srv, c := newTestClient(t)
+	from := srv.AddList(types.TodoTaskList{DisplayName: "Inbox"})
+	to := srv.AddList(types.TodoTaskList{DisplayName: "Work"})
+	task := srv.AddTask(from.ID, types.TodoTask{Title: "Review PR"})
+	taskPath := "/me/todo/lists/" + from.ID + "/tasks/" + task.ID
+	srv.Fail(graphtest.Failure{Method: "DELETE", Path: taskPath, Disconnect: true, AfterProcessing: true})
+	// Let the four reads of the copy through, then drop the check.
+	srv.Fail(graphtest.Failure{Method: "GET", Path: taskPath, Disconnect: true, After: 4})
+
+	_, err := c.MoveTask(context.Background(), from.ID, task.ID, to.ID)
+	if err == nil {
+		t.Fatal("MoveTask succeeded although the original could not be checked")
+	}
+	copies := srv.Tasks(to.ID)
+	if len(copies) != 1 {
+		t.Fatalf("the copy was rolled back although the original may be gone: %+v", copies)
+	}
+	if msg := err.Error(); !strings.Contains(msg, task.ID) || !strings.Contains(msg, copies[0].ID) {
+		t.Errorf("error %q does not name the original and the copy", msg)
+	}
+}
+
+func TestCopyTaskToAnotherAccount(t *testing.T) {
+	srv, c := newTestClient(t)
+	otherSrv, other := newTestClient(t)
+	ctx := context.Background()
+	from := srv.AddList(types.TodoTaskList{DisplayName: "Inbox"})
+	to := otherSrv.AddList(types.TodoTaskList{DisplayName: "Work"})
+	task := srv.AddTask(from.ID, types.TodoTask{
+		Title:           "Review PR",
+		ChecklistItems:  []types.ChecklistItem{{DisplayName: "Read diff", IsChecked: true}},
+		LinkedResources: []types.LinkedResource{{WebURL: "https://example.com/pr/1", ApplicationName: "GitHub", DisplayName: "PR 1"}},
+	})
+
+	copied, err := c.CopyTask(ctx, from.ID, task.ID, other, to.ID)
+	if err != nil {
+		t.Fatalf("CopyTask: %v", err)
+	}
+	if _, ok := srv.Task(from.ID, task.ID); !ok {
+		t.Error("the original task is gone")
+	}
+	got, ok := otherSrv.Task(to.ID, copied.ID)
+	if !ok {
+		t.Fatal("the copy is not in the other account")
+	}
+	if got.Title != "Review PR" || len(got.ChecklistItems) != 1 || len(got.LinkedResources) != 1 {
+		t.Errorf("copied task = %+v", got)
+	}
+}
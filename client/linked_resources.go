@@ -0,0 +1,61 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+func (c *GraphClient) linkedResourcesURL(listID, taskID string) string {
+	return fmt.Sprintf("%s/me/todo/lists/%s/tasks/%s/linkedResources", c.baseURL, listID, taskID)
+}
+
+// ListLinkedResources returns the resources linked to a task, following pagination.
+func (c *GraphClient) ListLinkedResources(ctx context.Context, listID, taskID string) ([]types.LinkedResource, error) {
+	var allLinks []types.LinkedResource
+	url := c.linkedResourcesURL(listID, taskID)
+
+	for url != "" {
+		body, err := c.doRequest(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var resp types.LinkedResourcesResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("parsing linked resources: %w", err)
+		}
+		allLinks = append(allLinks, resp.Value...)
+		url = resp.NextLink
+	}
+	return allLinks, nil
+}
+
+// CreateLinkedResource links a task to an external resource and returns the link.
+func (c *GraphClient) CreateLinkedResource(ctx context.Context, listID, taskID string, link types.LinkedResource) (*types.LinkedResource, error) {
+	link.ID = ""
+	payload, err := json.Marshal(link)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling linked resource: %w", err)
+	}
+
+	respBody, err := c.doRequest(ctx, "POST", c.linkedResourcesURL(listID, taskID), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	var created types.LinkedResource
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return nil, fmt.Errorf("parsing created linked resource: %w", err)
+	}
+	return &created, nil
+}
+
+// DeleteLinkedResource removes a link from a task.
+func (c *GraphClient) DeleteLinkedResource(ctx context.Context, listID, taskID, linkID string) error {
+	_, err := c.doRequest(ctx, "DELETE", c.linkedResourcesURL(listID, taskID)+"/"+linkID, nil)
+	return err
+}
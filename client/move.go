@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// MoveTask moves a task to another list and returns the task in its new list.
+//
+// Graph has no move operation, so the task is copied into the target list
+// with CopyTask and the original is deleted afterwards. If any step fails the
+// copy is deleted again, so the task is never lost or duplicated; an
+// original that turns out to be deleted already counts as moved. If the
+// original cannot be checked, both are kept and the error names them. The
+// moved task gets a new ID.
+func (c *GraphClient) MoveTask(ctx context.Context, listID, taskID, targetListID string) (*types.TodoTask, error) {
+	if listID == targetListID {
+		return nil, fmt.Errorf("task is already in the target list")
+	}
+
+	moved, err := c.CopyTask(ctx, listID, taskID, c, targetListID)
+	if err != nil {
+		return nil, err
+	}
+	// A 404 means the original is already gone, for example because a
+	// retried DELETE had succeeded before its response was lost. Rolling
+	// back then would lose the task.
+	if err := c.DeleteTask(ctx, listID, taskID); err != nil && !IsNotFound(err) {
+		// The DELETE may still have gone through, so the copy is only
+		// rolled back once the original is known to exist.
+		if _, getErr := c.GetTask(ctx, listID, taskID); getErr != nil {
+			if IsNotFound(getErr) {
+				return moved, nil
+			}
+			return nil, fmt.Errorf("deleting original task: %w (it could not be checked afterwards: %v; the original %s and the copy %s in the target list are both kept)",
+				err, getErr, taskID, moved.ID)
+		}
+		return nil, rollbackCopy(ctx, c, targetListID, moved.ID, fmt.Errorf("deleting original task: %w", err))
+	}
+	return moved, nil
+}
+
+// CopyTask copies a task, together with its checklist items, linked
+// resources and attachments, into targetListID of target, which may be a
+// client signed in to another account. It returns the copy. If any step
+// fails the partial copy is deleted again.
+func (c *GraphClient) CopyTask(ctx context.Context, listID, taskID string, target *GraphClient, targetListID string) (*types.TodoTask, error) {
+	original, err := c.GetTask(ctx, listID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("reading task: %w", err)
+	}
+	items, err := c.ListChecklistItems(ctx, listID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("reading checklist items: %w", err)
+	}
+	links, err := c.ListLinkedResources(ctx, listID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("reading linked resources: %w", err)
+	}
+	attachments, err := c.ListAttachments(ctx, listID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("reading attachments: %w", err)
+	}
+
+	copied, err := target.postTask(ctx, targetListID, copyableTask(*original))
+	if err != nil {
+		return nil, fmt.Errorf("creating task in target list: %w", err)
+	}
+
+	if err := c.copyTaskChildren(ctx, listID, taskID, target, targetListID, copied.ID, items, links, attachments); err != nil {
+		return nil, rollbackCopy(ctx, target, targetListID, copied.ID, err)
+	}
+
+	copied.ChecklistItems = nil
+	copied.LinkedResources = nil
+	return copied, nil
+}
+
+// copyableTask strips the server-assigned fields from a task so it can be
+// posted to another list. A completed task keeps its completion time, which
+// Graph would otherwise set to the time of the copy.
+func copyableTask(task types.TodoTask) types.TodoTask {
+	copied := types.TodoTask{
+		Title:            task.Title,
+		Body:             task.Body,
+		Importance:       task.Importance,
+		Status:           task.Status,
+		DueDateTime:      task.DueDateTime,
+		StartDateTime:    task.StartDateTime,
+		ReminderDateTime: task.ReminderDateTime,
+		IsReminderOn:     task.IsReminderOn,
+		Recurrence:       task.Recurrence,
+		Categories:       task.Categories,
+	}
+	if task.Status == "completed" {
+		copied.CompletedDateTime = task.CompletedDateTime
+	}
+	return copied
+}
+
+func (c *GraphClient) copyTaskChildren(ctx context.Context, listID, taskID string, target *GraphClient, targetListID, targetTaskID string,
+	items []types.ChecklistItem, links []types.LinkedResource, attachments []types.TaskFileAttachment) error {
+	// Checklist items and links are small, so they are copied in batches.
+	var requests []BatchRequest
+	var names []string
+	for _, item := range items {
+		requests = append(requests, BatchRequest{
+			Method: "POST",
+			URL:    target.checklistURL(targetListID, targetTaskID),
+			Body:   map[string]interface{}{"displayName": item.DisplayName, "isChecked": item.IsChecked},
+		})
+		names = append(names, fmt.Sprintf("checklist item \"%s\"", item.DisplayName))
+	}
+	for _, link := range links {
+		link.ID = ""
+		requests = append(requests, BatchRequest{
+			Method: "POST",
+			URL:    target.linkedResourcesURL(targetListID, targetTaskID),
+			Body:   link,
+		})
+		names = append(names, fmt.Sprintf("linked resource \"%s\"", link.DisplayName))
+	}
+	if len(requests) > 0 {
+		responses, err := target.Batch(ctx, requests)
+		if err != nil {
+			return err
+		}
+		for i, resp := range responses {
+			if resp.Err != nil {
+				return fmt.Errorf("copying %s: %w", names[i], resp.Err)
+			}
+		}
+	}
+
+	for _, a := range attachments {
+		full, err := c.GetAttachment(ctx, listID, taskID, a.ID)
+		if err != nil {
+			return fmt.Errorf("reading attachment \"%s\": %w", a.Name, err)
+		}
+		if _, err := target.AttachFile(ctx, targetListID, targetTaskID, full.Name, full.ContentType, full.ContentBytes); err != nil {
+			return fmt.Errorf("copying attachment \"%s\": %w", a.Name, err)
+		}
+	}
+	return nil
+}
+
+// rollbackCopy deletes the partial copy from target after a failed copy or
+// move and returns the original failure, noting if the copy could not be
+// removed.
+func rollbackCopy(ctx context.Context, target *GraphClient, targetListID, copyID string, cause error) error {
+	if err := target.DeleteTask(ctx, targetListID, copyID); err != nil {
+		return fmt.Errorf("%w (rollback failed, a partial copy with ID %s remains in the target list: %v)", cause, copyID, err)
+	}
+	return fmt.Errorf("%w (rolled back, the original task is unchanged)", cause)
+}
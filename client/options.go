@@ -0,0 +1,50 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Option configures a GraphClient.
+type Option func(*GraphClient)
+
+// WithRetryPolicy sets how transient failures are retried.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *GraphClient) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithBaseURL sets the Graph endpoint, including the API version, such as
+// BetaEndpoint, USGovEndpoint or the URL of a local stand-in. The default is
+// GlobalEndpoint.
+func WithBaseURL(baseURL string) Option {
+	return func(c *GraphClient) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithTransport sets the RoundTripper requests are sent through.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *GraphClient) {
+		c.httpClient.Transport = transport
+	}
+}
+
+// WithProxy sends requests through the proxy at proxyURL instead of the one
+// named by the HTTPS_PROXY environment variable. It replaces any transport
+// set by an earlier WithTransport.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(c *GraphClient) {
+		c.httpClient.Transport = proxyTransport(proxyURL)
+	}
+}
+
+// proxyTransport returns a copy of http.DefaultTransport that connects
+// through proxyURL.
+func proxyTransport(proxyURL *url.URL) http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	return transport
+}
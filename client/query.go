@@ -0,0 +1,178 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/odata"
+)
+
+// TaskSortFields maps the sort keys accepted by TaskQuery.OrderBy to Graph properties.
+var TaskSortFields = map[string]string{
+	"due":        "dueDateTime/dateTime",
+	"start":      "startDateTime/dateTime",
+	"importance": "importance",
+	"title":      "title",
+	"status":     "status",
+	"created":    "createdDateTime",
+	"modified":   "lastModifiedDateTime",
+}
+
+// TaskSelectFields lists the task properties that TaskQuery.Select accepts.
+var TaskSelectFields = []string{
+	"title", "body", "importance", "status", "categories",
+	"createdDateTime", "lastModifiedDateTime", "completedDateTime",
+	"dueDateTime", "startDateTime", "reminderDateTime", "isReminderOn", "recurrence",
+	"checklistItems", "linkedResources",
+}
+
+// taskExpandFields are the fields of TaskSelectFields that Graph returns
+// through $expand rather than $select.
+var taskExpandFields = []string{"checklistItems", "linkedResources"}
+
+var taskStatuses = []string{"notStarted", "inProgress", "completed", "waitingOnOthers", "deferred"}
+
+// TaskFields is the whitelist of task properties that can be filtered on.
+var TaskFields = odata.Fields{
+	"status":               {Kind: odata.String, Values: taskStatuses},
+	"importance":           {Kind: odata.String, Values: []string{"low", "normal", "high"}},
+	"title":                {Kind: odata.String},
+	"dueDateTime/dateTime": {Kind: odata.DateTime},
+	"isReminderOn":         {Kind: odata.Bool},
+	"categories":           {Kind: odata.StringCollection},
+}
+
+// ListFields is the whitelist of task list properties that can be filtered on.
+var ListFields = odata.Fields{
+	"displayName": {Kind: odata.String},
+}
+
+// TaskQuery narrows, orders and trims the tasks returned by ListTasks. It is
+// translated into OData query options so the filtering happens on the
+// server. The zero value returns every task.
+type TaskQuery struct {
+	Status     string // only tasks with this status
+	NotStatus  string // only tasks without this status, e.g. "completed" for open tasks
+	Importance string
+	Category   string     // only tasks tagged with this category
+	DueBefore  *time.Time // due strictly before this instant
+	DueAfter   *time.Time // due strictly after this instant
+
+	OrderBy    string // a key of TaskSortFields
+	Descending bool
+
+	Top    int      // maximum number of tasks to return; 0 means all
+	Select []string // task properties to return; the ID is always included
+}
+
+// IsZero reports whether the query returns every task unchanged.
+func (q TaskQuery) IsZero() bool {
+	return q.Status == "" && q.NotStatus == "" && q.Importance == "" && q.Category == "" && q.DueBefore == nil && q.DueAfter == nil &&
+		q.OrderBy == "" && q.Top == 0 && len(q.Select) == 0
+}
+
+// filter builds the $filter expression.
+func (q TaskQuery) filter() (odata.Expr, error) {
+	var clauses []odata.Expr
+	add := func(field string, op odata.Op, value any) error {
+		expr, err := TaskFields.Compare(field, op, value)
+		if err != nil {
+			return err
+		}
+		clauses = append(clauses, expr)
+		return nil
+	}
+
+	if q.Status != "" {
+		if err := add("status", odata.Eq, q.Status); err != nil {
+			return odata.Expr{}, err
+		}
+	}
+	if q.NotStatus != "" {
+		if err := add("status", odata.Ne, q.NotStatus); err != nil {
+			return odata.Expr{}, err
+		}
+	}
+	if q.Importance != "" {
+		if err := add("importance", odata.Eq, q.Importance); err != nil {
+			return odata.Expr{}, err
+		}
+	}
+	if q.Category != "" {
+		expr, err := TaskFields.Any("categories", q.Category)
+		if err != nil {
+			return odata.Expr{}, err
+		}
+		clauses = append(clauses, expr)
+	}
+	// Graph stores due dates as local date/time strings, in UTC for To-Do.
+	if q.DueBefore != nil {
+		if err := add("dueDateTime/dateTime", odata.Lt, *q.DueBefore); err != nil {
+			return odata.Expr{}, err
+		}
+	}
+	if q.DueAfter != nil {
+		if err := add("dueDateTime/dateTime", odata.Gt, *q.DueAfter); err != nil {
+			return odata.Expr{}, err
+		}
+	}
+	return odata.And(clauses...), nil
+}
+
+// encode returns the OData query string, without the leading '?'.
+func (q TaskQuery) encode() (string, error) {
+	var params []string
+
+	filter, err := q.filter()
+	if err != nil {
+		return "", err
+	}
+	if !filter.IsZero() {
+		params = append(params, "$filter="+url.QueryEscape(filter.String()))
+	}
+
+	if q.OrderBy != "" {
+		field, ok := TaskSortFields[q.OrderBy]
+		if !ok {
+			return "", fmt.Errorf("unknown sort field %q", q.OrderBy)
+		}
+		if q.Descending {
+			field += " desc"
+		}
+		params = append(params, "$orderby="+url.QueryEscape(field))
+	}
+
+	if q.Top < 0 {
+		return "", fmt.Errorf("top must not be negative")
+	}
+	if q.Top > 0 {
+		params = append(params, fmt.Sprintf("$top=%d", q.Top))
+	}
+
+	// Without a selection, checklist items and linked resources are
+	// expanded; with one, only if they are selected.
+	expand := taskExpandFields
+	if len(q.Select) > 0 {
+		fields := []string{"id"}
+		expand = nil
+		for _, field := range q.Select {
+			switch {
+			case !slices.Contains(TaskSelectFields, field):
+				return "", fmt.Errorf("unknown field %q, expected one of %v", field, TaskSelectFields)
+			case slices.Contains(taskExpandFields, field):
+				expand = append(expand, field)
+			default:
+				fields = append(fields, field)
+			}
+		}
+		params = append(params, "$select="+strings.Join(fields, ","))
+	}
+
+	if len(expand) > 0 {
+		params = append(params, "$expand="+strings.Join(expand, ","))
+	}
+	return strings.Join(params, "&"), nil
+}
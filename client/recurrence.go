@@ -0,0 +1,117 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+var (
+	recurrencePatternTypes = []string{"daily", "weekly", "absoluteMonthly", "relativeMonthly", "absoluteYearly", "relativeYearly"}
+	recurrenceRangeTypes   = []string{"endDate", "noEnd", "numbered"}
+	recurrenceIndexes      = []string{"first", "second", "third", "fourth", "last"}
+	daysOfWeek             = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+)
+
+const recurrenceDateLayout = "2006-01-02"
+
+// ValidateRecurrence checks a recurrence against the rules Microsoft Graph
+// enforces, so mistakes are reported before any request is sent. A missing
+// interval, range or start date is accepted, since normalizeRecurrence
+// fills it in. r is not modified.
+func ValidateRecurrence(r *types.PatternedRecurrence) error {
+	return validateRecurrence(normalizeRecurrence(r))
+}
+
+// normalizeRecurrence returns a copy of r with the defaults filled in: an
+// interval of 1 and a "noEnd" range, starting today if no start date is
+// given.
+func normalizeRecurrence(r *types.PatternedRecurrence) *types.PatternedRecurrence {
+	n := &types.PatternedRecurrence{}
+	if r.Pattern != nil {
+		p := *r.Pattern
+		p.DaysOfWeek = slices.Clone(p.DaysOfWeek)
+		if p.Interval == 0 {
+			p.Interval = 1
+		}
+		n.Pattern = &p
+	}
+	rr := types.RecurrenceRange{Type: "noEnd"}
+	if r.Range != nil {
+		rr = *r.Range
+	}
+	if rr.StartDate == "" {
+		rr.StartDate = time.Now().Format(recurrenceDateLayout)
+	}
+	n.Range = &rr
+	return n
+}
+
+// validateRecurrence does the work of ValidateRecurrence on a normalized
+// recurrence.
+func validateRecurrence(r *types.PatternedRecurrence) error {
+	p := r.Pattern
+	if p == nil {
+		return fmt.Errorf("recurrence pattern is required")
+	}
+	if !slices.Contains(recurrencePatternTypes, p.Type) {
+		return fmt.Errorf("recurrence type %q must be one of %v", p.Type, recurrencePatternTypes)
+	}
+	if p.Interval < 1 || p.Interval > 99 {
+		return fmt.Errorf("recurrence interval must be between 1 and 99, got %d", p.Interval)
+	}
+	for _, day := range p.DaysOfWeek {
+		if !slices.Contains(daysOfWeek, day) {
+			return fmt.Errorf("day of week %q must be one of %v", day, daysOfWeek)
+		}
+	}
+	if p.FirstDayOfWeek != "" && !slices.Contains(daysOfWeek, p.FirstDayOfWeek) {
+		return fmt.Errorf("first day of week %q must be one of %v", p.FirstDayOfWeek, daysOfWeek)
+	}
+	if p.Index != "" && !slices.Contains(recurrenceIndexes, p.Index) {
+		return fmt.Errorf("recurrence index %q must be one of %v", p.Index, recurrenceIndexes)
+	}
+
+	needsDays := p.Type == "weekly" || p.Type == "relativeMonthly" || p.Type == "relativeYearly"
+	if needsDays && len(p.DaysOfWeek) == 0 {
+		return fmt.Errorf("%s recurrence requires daysOfWeek", p.Type)
+	}
+	needsDayOfMonth := p.Type == "absoluteMonthly" || p.Type == "absoluteYearly"
+	if needsDayOfMonth && (p.DayOfMonth < 1 || p.DayOfMonth > 31) {
+		return fmt.Errorf("%s recurrence requires dayOfMonth between 1 and 31", p.Type)
+	}
+	needsMonth := p.Type == "absoluteYearly" || p.Type == "relativeYearly"
+	if needsMonth && (p.Month < 1 || p.Month > 12) {
+		return fmt.Errorf("%s recurrence requires month between 1 and 12", p.Type)
+	}
+
+	return validateRecurrenceRange(r.Range)
+}
+
+func validateRecurrenceRange(rr *types.RecurrenceRange) error {
+	if !slices.Contains(recurrenceRangeTypes, rr.Type) {
+		return fmt.Errorf("recurrence range type %q must be one of %v", rr.Type, recurrenceRangeTypes)
+	}
+	start, err := time.Parse(recurrenceDateLayout, rr.StartDate)
+	if err != nil {
+		return fmt.Errorf("recurrence startDate %q must be a date like 2025-01-31", rr.StartDate)
+	}
+
+	switch rr.Type {
+	case "endDate":
+		end, err := time.Parse(recurrenceDateLayout, rr.EndDate)
+		if err != nil {
+			return fmt.Errorf("recurrence endDate %q must be a date like 2025-01-31", rr.EndDate)
+		}
+		if end.Before(start) {
+			return fmt.Errorf("recurrence endDate %s is before startDate %s", rr.EndDate, rr.StartDate)
+		}
+	case "numbered":
+		if rr.NumberOfOccurrences < 1 {
+			return fmt.Errorf("numbered recurrence requires numberOfOccurrences of at least 1")
+		}
+	}
+	return nil
+}
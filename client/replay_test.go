@@ -0,0 +1,171 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/auth"
+	"github.com/michMartineau/mcp-server-microsoft-todo/cassette"
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/odata"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+const (
+	replayListID = "AQMkADAwATNiZmYAZC1hZjE2LTk2ZjYtMDACLTAwCgAuAAADiQ8RE6fqaEOyolDhfzYDkgEA"
+	replayTaskID = "AQMkADAwATNiZmYAZC1hZjE2LTk2ZjYtMDACLTAwCgBGAAADiQ8RE6fqaEOyolDhfzYDkgcAZQAAAA=="
+)
+
+// newReplayClient returns a client that answers from a cassette in
+// testdata/cassettes. The stored access token has expired, so the first
+// request refreshes it through the cassette too.
+func newReplayClient(t *testing.T, name string) (*client.GraphClient, *cassette.Replayer) {
+	t.Helper()
+	replayer, err := cassette.Load(filepath.Join("testdata", "cassettes", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm, err := auth.NewTokenManager("test-client", auth.WithConfigDir(t.TempDir()), auth.WithTransport(replayer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := &types.StoredTokens{AccessToken: cassette.Redacted, RefreshToken: cassette.Redacted, ExpiresAt: time.Now().Add(-time.Hour)}
+	if err := tm.SaveTokens(expired); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if remaining := replayer.Remaining(); len(remaining) > 0 {
+			t.Errorf("%d recorded interactions were not replayed, first %s %s",
+				len(remaining), remaining[0].Request.Method, remaining[0].Request.URL)
+		}
+	})
+	return client.NewGraphClient(tm, client.WithTransport(replayer), client.WithRetryPolicy(testRetryPolicy)), replayer
+}
+
+func TestReplayDecodesTasks(t *testing.T) {
+	c, _ := newReplayClient(t, "tasks.json")
+	ctx := context.Background()
+
+	lists, err := c.ListTodoLists(ctx, odata.Expr{})
+	if err != nil {
+		t.Fatalf("ListTodoLists: %v", err)
+	}
+	if len(lists) != 2 || lists[0].WellknownName != "defaultList" || lists[1].WellknownName != "none" || !lists[1].IsShared {
+		t.Errorf("lists = %+v", lists)
+	}
+
+	tasks, err := c.ListTasks(ctx, replayListID, client.TaskQuery{})
+	if err != nil {
+		t.Fatalf("ListTasks: %v", err)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("got %d tasks across two pages, want 3", len(tasks))
+	}
+
+	// Graph sends seven fractional digits in timestamps and none in
+	// dateTimeTimeZone values.
+	full := tasks[0]
+	if want := time.Date(2025, 2, 10, 8, 15, 22, 123456700, time.UTC); full.CreatedDateTime == nil || !full.CreatedDateTime.Equal(want) {
+		t.Errorf("createdDateTime = %v, want %v", full.CreatedDateTime, want)
+	}
+	if full.DueDateTime == nil || full.DueDateTime.DateTime != "2025-03-01T00:00:00.0000000" || full.DueDateTime.TimeZone != "UTC" {
+		t.Errorf("dueDateTime = %+v", full.DueDateTime)
+	}
+	if full.Body == nil || full.Body.ContentType != "text" || full.Body.Content != "Semi-skimmed" {
+		t.Errorf("body = %+v", full.Body)
+	}
+	if r := full.Recurrence; r == nil || r.Pattern.Type != "weekly" || len(r.Pattern.DaysOfWeek) != 1 || r.Range.Type != "noEnd" || r.Range.StartDate != "2025-03-01" {
+		t.Errorf("recurrence = %+v", full.Recurrence)
+	}
+	if len(full.ChecklistItems) != 1 || full.ChecklistItems[0].CheckedDateTime == nil || !full.ChecklistItems[0].IsChecked {
+		t.Errorf("checklist items = %+v", full.ChecklistItems)
+	}
+
+	// A task created on a phone has an empty body, no dates and no
+	// categories.
+	sparse := tasks[1]
+	if sparse.DueDateTime != nil || sparse.Recurrence != nil || len(sparse.Categories) != 0 || sparse.Body == nil || sparse.Body.Content != "" {
+		t.Errorf("sparse task = %+v", sparse)
+	}
+
+	done := tasks[2]
+	if done.Status != "completed" || done.CompletedDateTime == nil || done.CompletedDateTime.DateTime != "2025-02-12T00:00:00.0000000" {
+		t.Errorf("completed task = %+v", done)
+	}
+
+	task, err := c.GetTask(ctx, replayListID, replayTaskID)
+	if err != nil {
+		t.Fatalf("GetTask: %v", err)
+	}
+	if task.Title != full.Title || !task.LastModifiedDateTime.After(*task.CreatedDateTime) {
+		t.Errorf("task = %+v", task)
+	}
+}
+
+func TestReplayDecodesTaskChildren(t *testing.T) {
+	c, _ := newReplayClient(t, "children.json")
+	ctx := context.Background()
+
+	links, err := c.ListLinkedResources(ctx, replayListID, replayTaskID)
+	if err != nil {
+		t.Fatalf("ListLinkedResources: %v", err)
+	}
+	if len(links) != 1 || links[0].ApplicationName != "Outlook" || links[0].WebURL == "" || links[0].ExternalID == "" {
+		t.Errorf("links = %+v", links)
+	}
+
+	attachments, err := c.ListAttachments(ctx, replayListID, replayTaskID)
+	if err != nil {
+		t.Fatalf("ListAttachments: %v", err)
+	}
+	if len(attachments) != 1 || attachments[0].Size != 41 || attachments[0].LastModifiedDateTime == nil || attachments[0].ContentBytes != nil {
+		t.Fatalf("attachments = %+v", attachments)
+	}
+
+	attachment, err := c.GetAttachment(ctx, replayListID, replayTaskID, attachments[0].ID)
+	if err != nil {
+		t.Fatalf("GetAttachment: %v", err)
+	}
+	if string(attachment.ContentBytes) != "Receipt #1042\nTotal: 12.40 EUR\nPaid: card" {
+		t.Errorf("content = %q", attachment.ContentBytes)
+	}
+
+	_, err = c.GetTask(ctx, replayListID, "deleted")
+	if !client.IsNotFound(err) {
+		t.Fatalf("GetTask of a deleted task: err = %v, want not found", err)
+	}
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.RequestID == "" {
+		t.Errorf("error has no request ID: %#v", err)
+	}
+}
+
+func TestReplayDecodesDelta(t *testing.T) {
+	c, _ := newReplayClient(t, "delta.json")
+	ctx := context.Background()
+
+	changes, deltaLink, err := c.TasksDelta(ctx, replayListID, "")
+	if err != nil {
+		t.Fatalf("TasksDelta: %v", err)
+	}
+	if len(changes) != 2 || deltaLink == "" {
+		t.Fatalf("changes = %+v, deltaLink = %q", changes, deltaLink)
+	}
+
+	changes, _, err = c.TasksDelta(ctx, replayListID, deltaLink)
+	if err != nil {
+		t.Fatalf("TasksDelta with delta link: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("changes = %+v", changes)
+	}
+	if changes[0].Removed == nil || changes[0].Removed.Reason != "deleted" || changes[0].Title != "" {
+		t.Errorf("removed task = %+v", changes[0])
+	}
+	if changes[1].Removed != nil || changes[1].Status != "completed" {
+		t.Errorf("updated task = %+v", changes[1])
+	}
+}
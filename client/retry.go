@@ -0,0 +1,237 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how requests that fail transiently are retried.
+//
+// Throttled (429) responses are retried for every method, since Graph has
+// not processed them, and so are unavailable (503) responses that carry
+// Retry-After. Other unavailable responses, gateway errors and network
+// failures are only retried for methods that are safe to repeat, since a
+// front end may fail after the request was processed, so a POST or PATCH
+// that may have been applied is never sent twice.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt; zero
+	// disables retrying.
+	MaxRetries int
+
+	// BaseDelay is the wait before the first retry when Graph does not send
+	// Retry-After. It doubles with each retry, with random jitter.
+	BaseDelay time.Duration
+
+	// MaxDelay caps a single wait, including one asked for by Retry-After.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy returns the policy used unless WithRetryPolicy is given.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxRetries: 4, BaseDelay: 500 * time.Millisecond, MaxDelay: 30 * time.Second}
+}
+
+// idempotent reports whether a request can be repeated without changing
+// the outcome. PATCH is not: repeating one after another write to the same
+// task has been applied would undo that write.
+func idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// retryable reports whether a failed attempt should be retried.
+func (p RetryPolicy) retryable(method string, status int, header http.Header, err error) bool {
+	if err != nil {
+		return idempotent(method) && IsUnreachable(err)
+	}
+	switch status {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusServiceUnavailable:
+		return idempotent(method) || header.Get("Retry-After") != ""
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent(method)
+	}
+	return false
+}
+
+// delay returns how long to wait before retry number attempt (from 0),
+// honoring Retry-After when the response has one.
+func (p RetryPolicy) delay(attempt int, header http.Header) time.Duration {
+	if d, ok := parseRetryAfter(header.Get("Retry-After"), time.Now()); ok {
+		return min(d, p.MaxDelay)
+	}
+	d := p.BaseDelay << attempt
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	// Jitter between half and the full delay spreads out clients that were
+	// throttled together.
+	return d/2 + rand.N(d/2+1)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an
+// HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// RetryStats summarizes the retries made by a client since it was created.
+type RetryStats struct {
+	Requests      int // HTTP requests sent, including retries
+	Retries       int
+	Throttled     int // 429 responses, including inside batches
+	Unavailable   int // 502, 503 and 504 responses, including inside batches
+	NetworkErrors int
+	GaveUp        int // requests that still failed after the last retry
+
+	// TotalWait is the time spent waiting between retries.
+	TotalWait time.Duration
+
+	// LastThrottled is when Graph last answered 429, and LastRetryAfter the
+	// wait it asked for, if any.
+	LastThrottled  time.Time
+	LastRetryAfter time.Duration
+}
+
+type retryCounter struct {
+	mu    sync.Mutex
+	stats RetryStats
+}
+
+// attempt records an HTTP request and its outcome.
+func (r *retryCounter) attempt(status int, err error, header http.Header) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.Requests++
+	r.observe(status, err, header)
+}
+
+// batched records the outcome of a request sent inside a $batch call.
+func (r *retryCounter) batched(status int, header http.Header) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observe(status, nil, header)
+}
+
+// observe counts failures. Callers must hold r.mu.
+func (r *retryCounter) observe(status int, err error, header http.Header) {
+	switch {
+	case err != nil:
+		if IsUnreachable(err) {
+			r.stats.NetworkErrors++
+		}
+	case status == http.StatusTooManyRequests:
+		r.stats.Throttled++
+		r.stats.LastThrottled = time.Now()
+		r.stats.LastRetryAfter, _ = parseRetryAfter(header.Get("Retry-After"), time.Now())
+	case status == http.StatusBadGateway, status == http.StatusServiceUnavailable, status == http.StatusGatewayTimeout:
+		r.stats.Unavailable++
+	}
+}
+
+func (r *retryCounter) retry(wait time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Retries++
+	r.stats.TotalWait += wait
+}
+
+func (r *retryCounter) gaveUp() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.GaveUp++
+}
+
+// RetryStats returns a summary of the retries made so far.
+func (c *GraphClient) RetryStats() RetryStats {
+	c.retries.mu.Lock()
+	defer c.retries.mu.Unlock()
+	return c.retries.stats
+}
+
+// RetryPolicy returns the policy the client retries with.
+func (c *GraphClient) RetryPolicy() RetryPolicy {
+	return c.retryPolicy
+}
+
+// response is a fully read HTTP response.
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// send performs a request built by newRequest, retrying it according to
+// the client's retry policy. newRequest is called for every attempt, so the
+// body and credentials are fresh each time. Responses with any status are
+// returned; only transport failures are errors.
+func (c *GraphClient) send(ctx context.Context, method string, newRequest func() (*http.Request, error)) (*response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		var resp *response
+		httpResp, err := c.httpClient.Do(req)
+		if err == nil {
+			resp = &response{status: httpResp.StatusCode, header: httpResp.Header}
+			resp.body, err = io.ReadAll(httpResp.Body)
+			httpResp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("reading response: %w", err)
+			}
+		} else {
+			err = fmt.Errorf("executing request: %w", err)
+		}
+
+		var status int
+		var header http.Header
+		if resp != nil {
+			status, header = resp.status, resp.header
+		}
+		c.retries.attempt(status, err, header)
+
+		if !c.retryPolicy.retryable(method, status, header, err) {
+			return resp, err
+		}
+		if attempt >= c.retryPolicy.MaxRetries {
+			c.retries.gaveUp()
+			return resp, err
+		}
+
+		wait := c.retryPolicy.delay(attempt, header)
+		c.retries.retry(wait)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			// Report the failure that was being retried.
+			timer.Stop()
+			return resp, err
+		case <-timer.C:
+		}
+	}
+}
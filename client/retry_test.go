@@ -0,0 +1,205 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/auth"
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/graphtest"
+	"github.com/michMartineau/mcp-server-microsoft-todo/odata"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+func TestRetriesThrottledRequests(t *testing.T) {
+	srv, c := newTestClient(t)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	srv.Fail(graphtest.Failure{Status: http.StatusTooManyRequests, RetryAfter: 1, Times: 2})
+
+	// Retry-After asks for a second, which the test policy caps.
+	_, err := c.CreateTask(context.Background(), list.ID, client.NewTask{Title: "Throttled"})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if len(srv.Tasks(list.ID)) != 1 {
+		t.Errorf("tasks = %+v, want the one created", srv.Tasks(list.ID))
+	}
+
+	stats := c.RetryStats()
+	if stats.Requests != 3 || stats.Retries != 2 || stats.Throttled != 2 || stats.GaveUp != 0 {
+		t.Errorf("retry stats = %+v, want 3 requests, 2 throttled retries", stats)
+	}
+	if stats.LastRetryAfter.Seconds() != 1 {
+		t.Errorf("LastRetryAfter = %s, want 1s", stats.LastRetryAfter)
+	}
+}
+
+func TestGivesUpAfterMaxRetries(t *testing.T) {
+	srv, c := newTestClient(t)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	srv.Fail(graphtest.Failure{Status: http.StatusServiceUnavailable})
+
+	_, err := c.GetList(context.Background(), list.ID)
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("GetList error = %v, want a 503 APIError", err)
+	}
+	if stats := c.RetryStats(); stats.Requests != 4 || stats.GaveUp != 1 {
+		t.Errorf("retry stats = %+v, want 4 requests and one give-up", stats)
+	}
+}
+
+func TestDoesNotRepeatFailedCreates(t *testing.T) {
+	srv, c := newTestClient(t)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	srv.Fail(graphtest.Failure{Method: "POST", Status: http.StatusBadGateway, Times: 1})
+
+	if _, err := c.CreateTask(context.Background(), list.ID, client.NewTask{Title: "Once"}); err == nil {
+		t.Fatal("CreateTask succeeded despite the 502")
+	}
+	if got := len(srv.Requests()); got != 1 {
+		t.Errorf("made %d requests, want the POST sent once", got)
+	}
+}
+
+func TestRetriesUnavailableCreatesOnlyWithRetryAfter(t *testing.T) {
+	srv, c := newTestClient(t)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+
+	// A 503 may come from a front end after the task was created.
+	srv.Fail(graphtest.Failure{Method: "POST", Status: http.StatusServiceUnavailable, Times: 1, AfterProcessing: true})
+	if _, err := c.CreateTask(context.Background(), list.ID, client.NewTask{Title: "Once"}); err == nil {
+		t.Fatal("CreateTask succeeded despite the 503")
+	}
+	if tasks := srv.Tasks(list.ID); len(tasks) != 1 {
+		t.Errorf("tasks = %+v, want the task created once", tasks)
+	}
+
+	// With Retry-After, Graph itself asks for the request to be sent again.
+	srv.Fail(graphtest.Failure{Method: "POST", Status: http.StatusServiceUnavailable, RetryAfter: 1, Times: 1})
+	if _, err := c.CreateTask(context.Background(), list.ID, client.NewTask{Title: "Retried"}); err != nil {
+		t.Errorf("CreateTask after a 503 with Retry-After: %v", err)
+	}
+}
+
+func TestDoesNotRepeatFailedUpdates(t *testing.T) {
+	srv, c := newTestClient(t)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	task := srv.AddTask(list.ID, types.TodoTask{Title: "Call bank"})
+	srv.Fail(graphtest.Failure{Method: "PATCH", Status: http.StatusBadGateway, Times: 1})
+
+	if _, err := c.UpdateTask(context.Background(), list.ID, task.ID, client.TaskUpdate{Title: ptr("Call the bank")}); err == nil {
+		t.Fatal("UpdateTask succeeded despite the 502")
+	}
+	if got := len(srv.Requests()); got != 1 {
+		t.Errorf("made %d requests, want the PATCH sent once", got)
+	}
+}
+
+func TestRetriesNetworkErrors(t *testing.T) {
+	srv, c := newTestClient(t)
+	srv.SetOffline(true)
+
+	_, err := c.ListTodoLists(context.Background(), odata.Expr{})
+	if !client.IsUnreachable(err) || !client.IsNotSent(err) {
+		t.Fatalf("ListTodoLists error = %v, want unreachable and not sent", err)
+	}
+	if stats := c.RetryStats(); stats.NetworkErrors != 4 || stats.GaveUp != 1 {
+		t.Errorf("retry stats = %+v, want 4 network errors and one give-up", stats)
+	}
+}
+
+func TestDroppedConnectionsMayHaveBeenSent(t *testing.T) {
+	srv, c := newTestClient(t)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	srv.Fail(graphtest.Failure{Method: "POST", Disconnect: true, AfterProcessing: true, Times: 1})
+
+	_, err := c.CreateTask(context.Background(), list.ID, client.NewTask{Title: "Lost answer"})
+	if !client.IsUnreachable(err) || client.IsNotSent(err) {
+		t.Fatalf("CreateTask error = %v, want unreachable but maybe sent", err)
+	}
+	if tasks := srv.Tasks(list.ID); len(tasks) != 1 {
+		t.Errorf("tasks = %+v, want the task created once", tasks)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	srv, c := newTestClient(t)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	ctx := context.Background()
+
+	_, err := c.GetTask(ctx, list.ID, "missing")
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("GetTask error = %v, want an APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Code != "ErrorItemNotFound" || apiErr.RequestID == "" {
+		t.Errorf("APIError = %+v, want 404 ErrorItemNotFound with a request-id", apiErr)
+	}
+	if !client.IsNotFound(err) {
+		t.Error("IsNotFound = false for a 404")
+	}
+
+	srv.Fail(graphtest.Failure{Status: http.StatusUnauthorized, Times: 1})
+	_, err = c.GetList(ctx, list.ID)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Code != "InvalidAuthenticationToken" {
+		t.Errorf("GetList error = %v, want 401 InvalidAuthenticationToken", err)
+	}
+	if client.IsNotFound(err) {
+		t.Error("IsNotFound = true for a 401")
+	}
+}
+
+func TestUploadErrors(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	tm, err := auth.NewTokenManager("test-client", auth.WithAuthorityHost(srv.AuthorityHost()), auth.WithConfigDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.SaveTokens(srv.Tokens()); err != nil {
+		t.Fatal(err)
+	}
+
+	// graphtest has no upload sessions, so this server opens one whose
+	// chunks are rejected.
+	var upload *httptest.Server
+	upload = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			json.NewEncoder(w).Encode(types.UploadSession{UploadURL: upload.URL + "/session"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"code":"ErrorItemNotFound","message":"The upload session has expired."}}`))
+	}))
+	defer upload.Close()
+	c := client.NewGraphClient(tm, client.WithBaseURL(upload.URL), client.WithRetryPolicy(testRetryPolicy))
+
+	_, err = c.AttachFile(context.Background(), "list", "task", "big.bin", "application/octet-stream", make([]byte, 4*1024*1024))
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "ErrorItemNotFound" || !client.IsNotFound(err) {
+		t.Errorf("AttachFile error = %v, want a 404 APIError", err)
+	}
+}
+
+func TestNotSignedIn(t *testing.T) {
+	srv := graphtest.NewServer()
+	defer srv.Close()
+	tm, err := auth.NewTokenManager("test-client", auth.WithAuthorityHost(srv.AuthorityHost()), auth.WithConfigDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := client.NewGraphClient(tm, client.WithBaseURL(srv.GraphURL()))
+
+	if _, err := c.ListTodoLists(context.Background(), odata.Expr{}); !errors.Is(err, auth.ErrNotAuthenticated) {
+		t.Errorf("ListTodoLists error = %v, want ErrNotAuthenticated", err)
+	}
+	if len(srv.Requests()) != 0 {
+		t.Errorf("requests = %v, want none without a token", srv.Requests())
+	}
+}
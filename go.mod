@@ -1,6 +1,6 @@
 module github.com/michMartineau/mcp-server-microsoft-todo
 
-go 1.23.0
+go 1.24.0
 
 require github.com/mark3labs/mcp-go v0.43.2
 
@@ -0,0 +1,330 @@
+package graphtest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+type deviceCode struct {
+	userCode string
+	scope    string
+	approved bool
+}
+
+type authCode struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	scope       string
+}
+
+// Account is the user who signs in to the fake identity platform.
+type Account struct {
+	Username string
+	Name     string
+
+	// TenantID is the user's tenant; PersonalTenantID for a personal
+	// Microsoft account.
+	TenantID string
+}
+
+// PersonalTenantID is the tenant ID of personal Microsoft accounts.
+const PersonalTenantID = "9188040d-6c67-4c5b-b112-36a304b66dad"
+
+// DefaultAccount is the account a new Server signs in.
+var DefaultAccount = Account{Username: "user@example.com", Name: "Test User", TenantID: PersonalTenantID}
+
+// SetAccount changes the account that signs in. Sign-in to a tenant the
+// account does not belong to fails as it does with Microsoft: a personal
+// account cannot use "organizations", and a work account cannot use
+// "consumers".
+func (s *Server) SetAccount(account Account) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.account = account
+}
+
+// acceptsTenant reports whether the account can sign in to tenant, given
+// in the URL as a keyword, tenant ID or domain. Callers must hold s.mu.
+func (s *Server) acceptsTenant(tenant string) bool {
+	personal := s.account.TenantID == PersonalTenantID
+	_, domain, _ := strings.Cut(s.account.Username, "@")
+	switch tenant {
+	case "common":
+		return true
+	case "consumers":
+		return personal
+	case "organizations":
+		return !personal
+	}
+	return !personal && (tenant == s.account.TenantID || strings.EqualFold(tenant, domain))
+}
+
+// Tokens signs in without the device code flow and returns tokens valid
+// for an hour, ready for TokenManager.SaveTokens.
+func (s *Server) Tokens() *types.StoredTokens {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := s.issueTokens("")
+	return &types.StoredTokens{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second),
+		Account:      &types.Account{Username: s.account.Username, Name: s.account.Name, TenantID: s.account.TenantID},
+	}
+}
+
+// RevokeTokens invalidates every access and refresh token issued so far, as
+// when the user signs out everywhere or the session expires.
+func (s *Server) RevokeTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessTokens = map[string]bool{}
+	s.refreshTokens = map[string]bool{}
+}
+
+// ApproveDeviceCode completes the sign-in the user would do in the browser
+// for the given user code. It reports whether the code was pending.
+func (s *Server) ApproveDeviceCode(userCode string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, code := range s.deviceCodes {
+		if code.userCode == userCode && !code.approved {
+			code.approved = true
+			return true
+		}
+	}
+	return false
+}
+
+// DenySignIn makes browser sign-ins fail as if the user declined to grant
+// the app access, until it is called again with false.
+func (s *Server) DenySignIn(deny bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.denySignIn = deny
+}
+
+// issueTokens creates a token pair, with an ID token if scope asks for
+// openid. Callers must hold s.mu.
+func (s *Server) issueTokens(scope string) types.TokenResponse {
+	resp := types.TokenResponse{
+		AccessToken:  s.newID("access"),
+		RefreshToken: s.newID("refresh"),
+		ExpiresIn:    3600,
+		TokenType:    "Bearer",
+		Scope:        scope,
+	}
+	if slices.Contains(strings.Fields(scope), "openid") {
+		resp.IDToken = s.idToken()
+	}
+	s.accessTokens[resp.AccessToken] = true
+	s.refreshTokens[resp.RefreshToken] = true
+	return resp
+}
+
+// idToken returns an unsigned ID token for the account. Callers must hold
+// s.mu.
+func (s *Server) idToken() string {
+	encode := func(v any) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	header := encode(map[string]string{"alg": "none", "typ": "JWT"})
+	claims := encode(map[string]any{
+		"iss":                fmt.Sprintf("%s/%s/v2.0", s.URL, s.account.TenantID),
+		"tid":                s.account.TenantID,
+		"preferred_username": s.account.Username,
+		"name":               s.account.Name,
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(time.Hour).Unix(),
+	})
+	return header + "." + claims + ".fake-signature"
+}
+
+// serveIdentity answers the OAuth endpoints below
+// /{tenant}/oauth2/v2.0/.
+func (s *Server) serveIdentity(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) != 4 || segments[1] != "oauth2" || segments[2] != "v2.0" {
+		http.NotFound(w, r)
+		return
+	}
+	if segments[3] == "authorize" && r.Method == "GET" {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.serveAuthorize(w, r, segments[0])
+		return
+	}
+	if r.Method != "POST" {
+		http.NotFound(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, "invalid_request", err.Error())
+		return
+	}
+	if r.PostForm.Get("client_id") == "" {
+		oauthError(w, "invalid_request", "AADSTS900144: The request body must contain the following parameter: 'client_id'.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.acceptsTenant(segments[0]) {
+		oauthError(w, "invalid_request", fmt.Sprintf("AADSTS50020: User account '%s' does not exist in tenant '%s'.", s.account.Username, segments[0]))
+		return
+	}
+
+	switch segments[3] {
+	case "devicecode":
+		s.serveDeviceCode(w, r)
+	case "token":
+		if f := s.tokenFailure(); f != nil {
+			if f.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(f.RetryAfter))
+			}
+			writeJSON(w, f.Status, map[string]string{"error": f.Code, "error_description": fmt.Sprintf("Injected %d failure.", f.Status)})
+			return
+		}
+		s.serveToken(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveAuthorize stands in for the browser sign-in: it signs the account in
+// at once and redirects to the app's loopback redirect URI with a code.
+// Callers must hold s.mu.
+func (s *Server) serveAuthorize(w http.ResponseWriter, r *http.Request, tenant string) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme != "http" || (redirectURI.Hostname() != "localhost" && redirectURI.Hostname() != "127.0.0.1") {
+		http.Error(w, "AADSTS50011: The redirect URI specified in the request does not match the redirect URIs configured for the application.", http.StatusBadRequest)
+		return
+	}
+	if query.Get("client_id") == "" {
+		http.Error(w, "AADSTS900144: The request body must contain the following parameter: 'client_id'.", http.StatusBadRequest)
+		return
+	}
+
+	redirect := func(params url.Values) {
+		params.Set("state", query.Get("state"))
+		target := *redirectURI
+		target.RawQuery = params.Encode()
+		http.Redirect(w, r, target.String(), http.StatusFound)
+	}
+	switch {
+	case query.Get("response_type") != "code":
+		redirect(url.Values{"error": {"unsupported_response_type"}, "error_description": {"AADSTS70005: response_type 'token' is not enabled for the application."}})
+	case query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256":
+		redirect(url.Values{"error": {"invalid_request"}, "error_description": {"AADSTS9002325: Proof Key for Code Exchange is required for cross-origin authorization code redemption."}})
+	case !s.acceptsTenant(tenant):
+		redirect(url.Values{"error": {"access_denied"}, "error_description": {fmt.Sprintf("AADSTS50020: User account '%s' does not exist in tenant '%s'.", s.account.Username, tenant)}})
+	case s.denySignIn:
+		redirect(url.Values{"error": {"access_denied"}, "error_description": {"AADSTS65004: User declined to consent to access the app."}})
+	default:
+		code := s.newID("code")
+		s.authCodes[code] = &authCode{
+			clientID:    query.Get("client_id"),
+			redirectURI: query.Get("redirect_uri"),
+			challenge:   query.Get("code_challenge"),
+			scope:       query.Get("scope"),
+		}
+		redirect(url.Values{"code": {code}})
+	}
+}
+
+func (s *Server) serveDeviceCode(w http.ResponseWriter, r *http.Request) {
+	code := s.newID("device")
+	userCode := fmt.Sprintf("CODE%d", s.nextID)
+	s.deviceCodes[code] = &deviceCode{userCode: userCode, scope: r.PostForm.Get("scope")}
+
+	verificationURI := s.URL + "/devicelogin"
+	writeJSON(w, http.StatusOK, types.DeviceCodeResponse{
+		DeviceCode:      code,
+		UserCode:        userCode,
+		VerificationURI: verificationURI,
+		ExpiresIn:       900,
+		Interval:        1,
+		Message:         fmt.Sprintf("To sign in, use a web browser to open the page %s and enter the code %s to authenticate.", verificationURI, userCode),
+	})
+}
+
+// tokenFailure returns the next token endpoint failure, using it up.
+// Callers must hold s.mu.
+func (s *Server) tokenFailure() *Failure {
+	if len(s.tokenFailures) == 0 {
+		return nil
+	}
+	f := s.tokenFailures[0]
+	if f.Times > 0 {
+		f.Times--
+		if f.Times == 0 {
+			s.tokenFailures = s.tokenFailures[1:]
+		}
+	}
+	return f
+}
+
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	form := r.PostForm
+	switch grantType := form.Get("grant_type"); grantType {
+	case "urn:ietf:params:oauth:grant-type:device_code":
+		code, ok := s.deviceCodes[form.Get("device_code")]
+		switch {
+		case !ok:
+			oauthError(w, "expired_token", "AADSTS70020: The provided value for the input parameter 'device_code' is not valid.")
+		case !code.approved:
+			oauthError(w, "authorization_pending", "AADSTS70016: OAuth 2.0 device flow error. Authorization is pending.")
+		default:
+			delete(s.deviceCodes, form.Get("device_code"))
+			writeJSON(w, http.StatusOK, s.issueTokens(code.scope))
+		}
+	case "authorization_code":
+		code, ok := s.authCodes[form.Get("code")]
+		delete(s.authCodes, form.Get("code"))
+		verifier := sha256.Sum256([]byte(form.Get("code_verifier")))
+		switch {
+		case !ok || code.clientID != form.Get("client_id"):
+			oauthError(w, "invalid_grant", "AADSTS70000: The provided authorization code is invalid or has expired.")
+		case code.redirectURI != form.Get("redirect_uri"):
+			oauthError(w, "invalid_grant", "AADSTS70001: The redirect URI does not match the one used to request the authorization code.")
+		case base64.RawURLEncoding.EncodeToString(verifier[:]) != code.challenge:
+			oauthError(w, "invalid_grant", "AADSTS501481: The Code_Verifier does not match the code_challenge supplied in the authorization request.")
+		default:
+			writeJSON(w, http.StatusOK, s.issueTokens(code.scope))
+		}
+	case "refresh_token":
+		if !s.refreshTokens[form.Get("refresh_token")] {
+			oauthError(w, "invalid_grant", "AADSTS70000: The provided refresh token is invalid or has expired.")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.issueTokens(form.Get("scope")))
+	default:
+		oauthError(w, "unsupported_grant_type", fmt.Sprintf("AADSTS70003: The app requested an unsupported grant type '%s'.", grantType))
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func oauthError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": description})
+}
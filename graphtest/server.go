@@ -0,0 +1,428 @@
+// Package graphtest provides an in-process fake of the Microsoft Graph To-Do
+// API and of the Microsoft identity platform endpoints used to sign in, so
+// the client, auth and tools packages can be tested without network access.
+//
+// A Server keeps lists, tasks, checklist items, linked resources and small
+// attachments in memory. It pages collections with @odata.nextLink, answers
+// delta queries and JSON batches, and can be told to fail requests:
+//
+//	srv := graphtest.NewServer()
+//	defer srv.Close()
+//	srv.Fail(graphtest.Failure{Status: http.StatusTooManyRequests, Times: 2})
+//
+// Point a TokenManager at srv.AuthorityHost() and a GraphClient at
+// srv.GraphURL(), and give the TokenManager srv.Tokens() or sign in through
+// the device code flow or the browser flow; the fake authorize endpoint
+// redirects straight back to the app's loopback listener.
+package graphtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// apiVersion is the path prefix of the Graph endpoints.
+const apiVersion = "/v1.0"
+
+// Server is a fake Microsoft Graph and identity platform.
+type Server struct {
+	*httptest.Server
+
+	// PageSize is how many items a collection returns per page when the
+	// request has no $top. Set it before making requests.
+	PageSize int
+
+	mu            sync.Mutex
+	nextID        int
+	seq           int // incremented by every change, for delta queries
+	deltaGen      int // delta links from another generation have expired
+	lists         []*listRecord
+	failures      []*Failure
+	tokenFailures []*Failure
+	requests      []string
+	offline       bool
+
+	account       Account
+	accessTokens  map[string]bool
+	refreshTokens map[string]bool
+	deviceCodes   map[string]*deviceCode
+	authCodes     map[string]*authCode
+	denySignIn    bool
+}
+
+// NewServer starts a fake server with no lists. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		PageSize:      100,
+		account:       DefaultAccount,
+		accessTokens:  map[string]bool{},
+		refreshTokens: map[string]bool{},
+		deviceCodes:   map[string]*deviceCode{},
+		authCodes:     map[string]*authCode{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// GraphURL returns the Graph endpoint to pass to client.WithBaseURL.
+func (s *Server) GraphURL() string {
+	return s.URL + apiVersion
+}
+
+// AuthorityHost returns the identity platform host to pass to
+// auth.WithAuthorityHost.
+func (s *Server) AuthorityHost() string {
+	return s.URL
+}
+
+// Failure makes the server answer matching Graph requests with an error.
+type Failure struct {
+	// Method matches requests with this HTTP method; empty matches any.
+	Method string
+
+	// Path matches requests whose path, below the API version, starts with
+	// it, such as "/me/todo/lists". Empty matches any request except a
+	// "/$batch" call itself; requests inside a batch are matched on their
+	// own path.
+	Path string
+
+	Status int
+
+	// Code is the Graph error code; it is derived from Status if empty.
+	Code string
+
+	// RetryAfter is sent as the Retry-After header, in seconds, if positive.
+	RetryAfter int
+
+	// Times is how many matching requests fail; zero fails all of them.
+	Times int
+
+	// After is how many matching requests succeed before the failure
+	// starts.
+	After int
+
+	// AfterProcessing carries out the request before failing it, as when
+	// the response is lost on its way back to the client.
+	AfterProcessing bool
+
+	// Disconnect drops the connection instead of answering, as when the
+	// network fails in the middle of a request; Status is then ignored.
+	// Inside a batch, it drops the whole "/$batch" call.
+	Disconnect bool
+}
+
+// Fail adds a failure. Failures are matched in the order they were added.
+func (s *Server) Fail(f Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &f)
+}
+
+// FailTokens makes the token endpoint answer with an OAuth error, as when
+// the identity platform is throttling or down. Method and Path are ignored;
+// Code is sent as the OAuth error code.
+func (s *Server) FailTokens(f Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokenFailures = append(s.tokenFailures, &f)
+}
+
+// ClearFailures removes all failures.
+func (s *Server) ClearFailures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = nil
+	s.tokenFailures = nil
+}
+
+// SetOffline makes the server refuse connections, as if it could not be
+// reached, until it is called again with false.
+func (s *Server) SetOffline(offline bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if offline == s.offline {
+		return
+	}
+	s.offline = offline
+
+	if offline {
+		s.Listener.Close()
+		s.CloseClientConnections()
+		return
+	}
+	addr := s.Listener.Addr().String()
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		panic(fmt.Sprintf("graphtest: listening on %s again: %v", addr, err))
+	}
+	s.Listener = listener
+	go s.Config.Serve(listener)
+}
+
+// Requests returns the Graph requests received so far, as "METHOD /path"
+// with the path below the API version and without the query. Requests
+// inside a JSON batch are not included; the batch is "POST /$batch".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, apiVersion+"/") {
+		s.serveIdentity(w, r)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, apiVersion)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+path)
+	s.mu.Unlock()
+
+	var resp *response
+	if r.Method == "POST" && path == "/$batch" {
+		s.mu.Lock()
+		resp = s.check(r.Method, path, r.Header)
+		s.mu.Unlock()
+		if resp == nil {
+			resp = s.serveBatch(r.Header, body)
+		}
+	} else {
+		resp = s.handle(r.Method, path, r.URL.Query(), r.Header, body)
+	}
+	if resp.disconnect {
+		panic(http.ErrAbortHandler)
+	}
+
+	for key, values := range resp.header {
+		w.Header()[key] = values
+	}
+	if resp.body != nil {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+}
+
+// response is the answer to one Graph request, direct or batched.
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+
+	// disconnect drops the connection instead of sending the response.
+	disconnect bool
+}
+
+func jsonResponse(status int, v any) *response {
+	if v == nil {
+		return &response{status: status, header: http.Header{}}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errorResponse(http.StatusInternalServerError, "generalException", err.Error())
+	}
+	return &response{status: status, header: http.Header{}, body: data}
+}
+
+var requestIDs struct {
+	sync.Mutex
+	next int
+}
+
+// errorResponse builds a Graph error with a fresh request-id.
+func errorResponse(status int, code, message string) *response {
+	requestIDs.Lock()
+	requestIDs.next++
+	requestID := fmt.Sprintf("00000000-0000-0000-0000-%012d", requestIDs.next)
+	requestIDs.Unlock()
+
+	resp := jsonResponse(status, types.GraphError{Error: types.GraphErrorDetail{
+		Code:    code,
+		Message: message,
+		InnerError: &types.GraphInnerError{
+			Date:      time.Now().UTC().Format(time.RFC3339),
+			RequestID: requestID,
+		},
+	}})
+	resp.header.Set("request-id", requestID)
+	return resp
+}
+
+// defaultCodes are the Graph error codes used when a Failure has none.
+var defaultCodes = map[int]string{
+	http.StatusBadRequest:          "invalidRequest",
+	http.StatusUnauthorized:        "InvalidAuthenticationToken",
+	http.StatusForbidden:           "accessDenied",
+	http.StatusNotFound:            "ErrorItemNotFound",
+	http.StatusConflict:            "conflict",
+	http.StatusTooManyRequests:     "TooManyRequests",
+	http.StatusInternalServerError: "generalException",
+	http.StatusBadGateway:          "badGateway",
+	http.StatusServiceUnavailable:  "serviceNotAvailable",
+	http.StatusGatewayTimeout:      "gatewayTimeout",
+}
+
+// handle answers one Graph request after checking the access token and the
+// configured failures.
+func (s *Server) handle(method, path string, query url.Values, header http.Header, body []byte) *response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if resp := s.checkToken(header); resp != nil {
+		return resp
+	}
+	f := s.failure(method, path)
+	if f != nil && !f.AfterProcessing {
+		return f.response()
+	}
+	resp := s.route(request{method: method, path: path, query: query, body: body})
+	if f != nil {
+		return f.response()
+	}
+	return resp
+}
+
+// check returns the error response for a request with an invalid access
+// token or matching a failure, or nil. Callers must hold s.mu.
+func (s *Server) check(method, path string, header http.Header) *response {
+	if resp := s.checkToken(header); resp != nil {
+		return resp
+	}
+	if f := s.failure(method, path); f != nil {
+		return f.response()
+	}
+	return nil
+}
+
+// checkToken returns the error response for a request with an invalid
+// access token, or nil. Callers must hold s.mu.
+func (s *Server) checkToken(header http.Header) *response {
+	token, ok := strings.CutPrefix(header.Get("Authorization"), "Bearer ")
+	if !ok || !s.accessTokens[token] {
+		return errorResponse(http.StatusUnauthorized, "InvalidAuthenticationToken", "Access token is empty or invalid.")
+	}
+	return nil
+}
+
+// response is the answer to a request matching an injected failure.
+func (f *Failure) response() *response {
+	if f.Disconnect {
+		return &response{disconnect: true}
+	}
+	code := f.Code
+	if code == "" {
+		code = defaultCodes[f.Status]
+	}
+	resp := errorResponse(f.Status, code, fmt.Sprintf("Injected %d failure.", f.Status))
+	if f.RetryAfter > 0 {
+		resp.header.Set("Retry-After", strconv.Itoa(f.RetryAfter))
+	}
+	return resp
+}
+
+// failure returns the first failure matching a request, using it up.
+// Callers must hold s.mu.
+func (s *Server) failure(method, path string) *Failure {
+	for i, f := range s.failures {
+		if f.Method != "" && f.Method != method || !strings.HasPrefix(path, f.Path) || f.Path == "" && path == "/$batch" {
+			continue
+		}
+		if f.After > 0 {
+			f.After--
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+type batchRequest struct {
+	ID        string            `json:"id"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	Body      json.RawMessage   `json:"body"`
+	DependsOn []string          `json:"dependsOn"`
+}
+
+type batchResponse struct {
+	ID      string            `json:"id"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// serveBatch runs the requests of a JSON batch in order. A request whose
+// dependency failed is answered with 424 Failed Dependency.
+func (s *Server) serveBatch(header http.Header, body []byte) *response {
+	var payload struct {
+		Requests []batchRequest `json:"requests"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return errorResponse(http.StatusBadRequest, "BadRequest", "Invalid batch payload.")
+	}
+	if len(payload.Requests) > 20 {
+		return errorResponse(http.StatusBadRequest, "BadRequest", "Number of batch request steps exceeds the maximum of 20.")
+	}
+
+	succeeded := map[string]bool{}
+	var responses []batchResponse
+	for _, req := range payload.Requests {
+		result := batchResponse{ID: req.ID}
+
+		failedDependency := false
+		for _, dep := range req.DependsOn {
+			if !succeeded[dep] {
+				failedDependency = true
+			}
+		}
+		var resp *response
+		if failedDependency {
+			resp = errorResponse(http.StatusFailedDependency, "FailedDependency", "A dependent request failed.")
+		} else {
+			path, rawQuery, _ := strings.Cut(strings.TrimPrefix(req.URL, apiVersion), "?")
+			query, _ := url.ParseQuery(rawQuery)
+			resp = s.handle(req.Method, path, query, header, bytes.TrimSpace(req.Body))
+		}
+		if resp.disconnect {
+			return resp
+		}
+
+		result.Status = resp.status
+		result.Body = resp.body
+		if len(resp.header) > 0 {
+			result.Headers = map[string]string{}
+			for key := range resp.header {
+				result.Headers[key] = resp.header.Get(key)
+			}
+		}
+		succeeded[req.ID] = resp.status >= 200 && resp.status < 300
+		responses = append(responses, result)
+	}
+	return jsonResponse(http.StatusOK, map[string]any{"responses": responses})
+}
@@ -0,0 +1,780 @@
+package graphtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// graphDateTime is the layout of the dateTime property of dateTimeTimeZone
+// values returned by Graph.
+const graphDateTime = "2006-01-02T15:04:05.0000000"
+
+type listRecord struct {
+	list    types.TodoTaskList
+	seq     int
+	deleted bool
+	tasks   []*taskRecord
+}
+
+type taskRecord struct {
+	task        types.TodoTask // without checklist items and linked resources
+	seq         int
+	deleted     bool
+	checklist   []types.ChecklistItem
+	links       []types.LinkedResource
+	attachments []types.TaskFileAttachment
+}
+
+// request is a Graph request, below the API version.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   []byte
+}
+
+// newID returns a fresh ID such as "task-7". Callers must hold s.mu.
+func (s *Server) newID(kind string) string {
+	s.nextID++
+	return fmt.Sprintf("%s-%d", kind, s.nextID)
+}
+
+// touch records a change and returns its sequence number. Callers must
+// hold s.mu.
+func (s *Server) touch() int {
+	s.seq++
+	return s.seq
+}
+
+// AddList adds a list and returns it with its ID. Give WellknownName
+// "defaultList" to add the built-in Tasks list.
+func (s *Server) AddList(list types.TodoTaskList) types.TodoTaskList {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if list.ID == "" {
+		list.ID = s.newID("list")
+	}
+	if list.WellknownName == "" {
+		list.WellknownName = "none"
+	}
+	list.IsOwner = true
+	s.lists = append(s.lists, &listRecord{list: list, seq: s.touch()})
+	return list
+}
+
+// AddTask adds a task, with its checklist items and linked resources, to a
+// list and returns it with its ID and timestamps. It panics if the list
+// does not exist.
+func (s *Server) AddTask(listID string, task types.TodoTask) types.TodoTask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := s.findList(listID)
+	if list == nil {
+		panic(fmt.Sprintf("graphtest: no list %q", listID))
+	}
+	return s.addTask(list, task).view(true, true)
+}
+
+// addTask stores a new task. Callers must hold s.mu.
+func (s *Server) addTask(list *listRecord, task types.TodoTask) *taskRecord {
+	now := time.Now().UTC()
+	rec := &taskRecord{}
+	for _, item := range task.ChecklistItems {
+		item.ID = s.newID("item")
+		item.CreatedDateTime = &now
+		rec.checklist = append(rec.checklist, item)
+	}
+	for _, link := range task.LinkedResources {
+		link.ID = s.newID("link")
+		rec.links = append(rec.links, link)
+	}
+
+	task.ID = s.newID("task")
+	task.ChecklistItems = nil
+	task.LinkedResources = nil
+	task.CreatedDateTime = &now
+	task.LastModifiedDateTime = &now
+	if task.Status == "" {
+		task.Status = "notStarted"
+	}
+	if task.Importance == "" {
+		task.Importance = "normal"
+	}
+	if task.Body == nil {
+		task.Body = &types.ItemBody{ContentType: "text"}
+	}
+	rec.task = task
+	rec.seq = s.touch()
+	list.tasks = append(list.tasks, rec)
+	return rec
+}
+
+// Lists returns the lists on the server.
+func (s *Server) Lists() []types.TodoTaskList {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lists []types.TodoTaskList
+	for _, rec := range s.lists {
+		if !rec.deleted {
+			lists = append(lists, rec.list)
+		}
+	}
+	return lists
+}
+
+// Tasks returns the tasks of a list with their checklist items and linked
+// resources, or nil if the list does not exist.
+func (s *Server) Tasks(listID string) []types.TodoTask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := s.findList(listID)
+	if list == nil {
+		return nil
+	}
+	var tasks []types.TodoTask
+	for _, rec := range list.tasks {
+		if !rec.deleted {
+			tasks = append(tasks, rec.view(true, true))
+		}
+	}
+	return tasks
+}
+
+// Task returns one task with its checklist items and linked resources.
+func (s *Server) Task(listID, taskID string) (types.TodoTask, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := s.findList(listID)
+	if list == nil {
+		return types.TodoTask{}, false
+	}
+	rec := list.findTask(taskID)
+	if rec == nil {
+		return types.TodoTask{}, false
+	}
+	return rec.view(true, true), true
+}
+
+// ModifyTask changes a task as another client would, updating its
+// lastModifiedDateTime. It reports whether the task exists.
+func (s *Server) ModifyTask(listID, taskID string, modify func(task *types.TodoTask)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := s.findList(listID)
+	if list == nil {
+		return false
+	}
+	rec := list.findTask(taskID)
+	if rec == nil {
+		return false
+	}
+	modify(&rec.task)
+	now := time.Now().UTC()
+	rec.task.LastModifiedDateTime = &now
+	rec.seq = s.touch()
+	return true
+}
+
+// ExpireDeltaLinks makes every delta link issued so far fail with 410 Gone,
+// as Graph does when it no longer has the sync state.
+func (s *Server) ExpireDeltaLinks() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deltaGen++
+}
+
+// findList returns a list that has not been deleted. Callers must hold s.mu.
+func (s *Server) findList(id string) *listRecord {
+	for _, rec := range s.lists {
+		if rec.list.ID == id && !rec.deleted {
+			return rec
+		}
+	}
+	return nil
+}
+
+func (l *listRecord) findTask(id string) *taskRecord {
+	for _, rec := range l.tasks {
+		if rec.task.ID == id && !rec.deleted {
+			return rec
+		}
+	}
+	return nil
+}
+
+// view returns the task as Graph would, optionally with its checklist items
+// and linked resources expanded.
+func (t *taskRecord) view(checklist, links bool) types.TodoTask {
+	task := t.task
+	if checklist {
+		task.ChecklistItems = slices.Clone(t.checklist)
+	}
+	if links {
+		task.LinkedResources = slices.Clone(t.links)
+	}
+	return task
+}
+
+func notFound() *response {
+	return errorResponse(http.StatusNotFound, "ErrorItemNotFound", "The specified object was not found in the store.")
+}
+
+func badSegment(path string) *response {
+	return errorResponse(http.StatusBadRequest, "BadRequest", fmt.Sprintf("Resource not found for the segment '%s'.", path))
+}
+
+func methodNotAllowed() *response {
+	return errorResponse(http.StatusMethodNotAllowed, "BadRequest", "The HTTP method is not allowed for this resource.")
+}
+
+func invalidRequest(message string) *response {
+	return errorResponse(http.StatusBadRequest, "invalidRequest", message)
+}
+
+// route dispatches a request to /me/todo/lists and below. Callers must hold
+// s.mu.
+func (s *Server) route(r request) *response {
+	segments := strings.Split(strings.Trim(r.path, "/"), "/")
+	if len(segments) < 3 || segments[0] != "me" || segments[1] != "todo" || segments[2] != "lists" {
+		return badSegment(r.path)
+	}
+	segments = segments[3:]
+
+	switch {
+	case len(segments) == 0:
+		return s.serveLists(r)
+	case len(segments) == 1 && segments[0] == "delta":
+		return s.serveDelta(r, s.listChanges)
+	}
+
+	list := s.findList(segments[0])
+	if list == nil {
+		return notFound()
+	}
+	switch {
+	case len(segments) == 1:
+		return s.serveList(r, list)
+	case segments[1] != "tasks":
+		return badSegment(segments[1])
+	case len(segments) == 2:
+		return s.serveTasks(r, list)
+	case len(segments) == 3 && segments[2] == "delta":
+		return s.serveDelta(r, func(since int) []any { return list.taskChanges(since) })
+	}
+
+	task := list.findTask(segments[2])
+	if task == nil {
+		return notFound()
+	}
+	if len(segments) == 3 {
+		return s.serveTask(r, list, task)
+	}
+
+	rest := segments[4:]
+	switch segments[3] {
+	case "checklistItems":
+		return serveChildren(s, r, task, rest, &task.checklist, children[types.ChecklistItem]{
+			kind: "item",
+			id:   func(item *types.ChecklistItem) *string { return &item.ID },
+			created: func(item *types.ChecklistItem) {
+				now := time.Now().UTC()
+				item.CreatedDateTime = &now
+			},
+		})
+	case "linkedResources":
+		return serveChildren(s, r, task, rest, &task.links, children[types.LinkedResource]{
+			kind: "link",
+			id:   func(link *types.LinkedResource) *string { return &link.ID },
+		})
+	case "attachments":
+		if len(rest) == 1 && rest[0] == "createUploadSession" {
+			return errorResponse(http.StatusNotImplemented, "notSupported", "graphtest does not support upload sessions.")
+		}
+		return serveChildren(s, r, task, rest, &task.attachments, children[types.TaskFileAttachment]{
+			kind: "attachment",
+			id:   func(a *types.TaskFileAttachment) *string { return &a.ID },
+			created: func(a *types.TaskFileAttachment) {
+				now := time.Now().UTC()
+				a.ODataType = "#microsoft.graph.taskFileAttachment"
+				a.Size = int64(len(a.ContentBytes))
+				a.LastModifiedDateTime = &now
+			},
+			listed: func(a types.TaskFileAttachment) types.TaskFileAttachment {
+				a.ContentBytes = nil
+				return a
+			},
+		})
+	}
+	return badSegment(segments[3])
+}
+
+func (s *Server) serveLists(r request) *response {
+	switch r.method {
+	case "GET":
+		match, err := parseFilter(r.query.Get("$filter"))
+		if err != nil {
+			return invalidRequest(err.Error())
+		}
+		var lists []any
+		for _, rec := range s.lists {
+			if !rec.deleted && match(toMap(rec.list)) {
+				lists = append(lists, rec.list)
+			}
+		}
+		return jsonResponse(http.StatusOK, s.page(r, lists))
+	case "POST":
+		var list types.TodoTaskList
+		if err := json.Unmarshal(r.body, &list); err != nil || list.DisplayName == "" {
+			return invalidRequest("displayName is required.")
+		}
+		list = types.TodoTaskList{ID: s.newID("list"), DisplayName: list.DisplayName, IsOwner: true, WellknownName: "none"}
+		s.lists = append(s.lists, &listRecord{list: list, seq: s.touch()})
+		return jsonResponse(http.StatusCreated, list)
+	}
+	return methodNotAllowed()
+}
+
+func (s *Server) serveList(r request, list *listRecord) *response {
+	switch r.method {
+	case "GET":
+		return jsonResponse(http.StatusOK, list.list)
+	case "PATCH":
+		if err := merge(&list.list, r.body); err != nil {
+			return invalidRequest(err.Error())
+		}
+		list.seq = s.touch()
+		return jsonResponse(http.StatusOK, list.list)
+	case "DELETE":
+		if list.list.WellknownName != "none" {
+			return invalidRequest("The default list cannot be deleted.")
+		}
+		list.deleted = true
+		list.seq = s.touch()
+		return jsonResponse(http.StatusNoContent, nil)
+	}
+	return methodNotAllowed()
+}
+
+func (s *Server) serveTasks(r request, list *listRecord) *response {
+	switch r.method {
+	case "GET":
+		match, err := parseFilter(r.query.Get("$filter"))
+		if err != nil {
+			return invalidRequest(err.Error())
+		}
+		expand := strings.Split(r.query.Get("$expand"), ",")
+		var tasks []map[string]any
+		for _, rec := range list.tasks {
+			if rec.deleted {
+				continue
+			}
+			task := toMap(rec.view(slices.Contains(expand, "checklistItems"), slices.Contains(expand, "linkedResources")))
+			if match(task) {
+				tasks = append(tasks, task)
+			}
+		}
+		if err := orderBy(tasks, r.query.Get("$orderby")); err != nil {
+			return invalidRequest(err.Error())
+		}
+		if fields := r.query.Get("$select"); fields != "" {
+			selectFields(tasks, append(strings.Split(fields, ","), expand...))
+		}
+
+		items := make([]any, len(tasks))
+		for i, task := range tasks {
+			items[i] = task
+		}
+		return jsonResponse(http.StatusOK, s.page(r, items))
+	case "POST":
+		var task types.TodoTask
+		if err := json.Unmarshal(r.body, &task); err != nil {
+			return invalidRequest(err.Error())
+		}
+		if task.Title == "" {
+			return invalidRequest("The title of a task is required.")
+		}
+		if msg := validateTask(task); msg != "" {
+			return invalidRequest(msg)
+		}
+		if task.Status != "completed" {
+			task.CompletedDateTime = nil
+		} else if task.CompletedDateTime == nil {
+			task.CompletedDateTime = &types.DateTimeZone{DateTime: time.Now().UTC().Format(graphDateTime), TimeZone: "UTC"}
+		}
+		rec := s.addTask(list, task)
+		return jsonResponse(http.StatusCreated, rec.view(false, true))
+	}
+	return methodNotAllowed()
+}
+
+func (s *Server) serveTask(r request, list *listRecord, rec *taskRecord) *response {
+	switch r.method {
+	case "GET":
+		return jsonResponse(http.StatusOK, rec.view(false, false))
+	case "PATCH":
+		wasCompleted := rec.task.Status == "completed"
+		task := rec.task
+		if err := merge(&task, r.body); err != nil {
+			return invalidRequest(err.Error())
+		}
+		if msg := validateTask(task); msg != "" {
+			return invalidRequest(msg)
+		}
+		task.ID = rec.task.ID
+		task.ChecklistItems, task.LinkedResources = nil, nil
+
+		now := time.Now().UTC()
+		switch completed := task.Status == "completed"; {
+		case completed && !wasCompleted:
+			task.CompletedDateTime = &types.DateTimeZone{DateTime: now.Format(graphDateTime), TimeZone: "UTC"}
+		case !completed:
+			task.CompletedDateTime = nil
+		}
+		task.LastModifiedDateTime = &now
+		rec.task = task
+		rec.seq = s.touch()
+		return jsonResponse(http.StatusOK, rec.view(false, false))
+	case "DELETE":
+		rec.deleted = true
+		rec.seq = s.touch()
+		return jsonResponse(http.StatusNoContent, nil)
+	}
+	return methodNotAllowed()
+}
+
+func validateTask(task types.TodoTask) string {
+	if task.Importance != "" && !slices.Contains([]string{"low", "normal", "high"}, task.Importance) {
+		return fmt.Sprintf("Invalid importance '%s'.", task.Importance)
+	}
+	statuses := []string{"notStarted", "inProgress", "completed", "waitingOnOthers", "deferred"}
+	if task.Status != "" && !slices.Contains(statuses, task.Status) {
+		return fmt.Sprintf("Invalid status '%s'.", task.Status)
+	}
+	return ""
+}
+
+// children describes a collection of items belonging to a task.
+type children[T any] struct {
+	kind    string
+	id      func(item *T) *string
+	created func(item *T)  // sets server-assigned properties; may be nil
+	listed  func(item T) T // trims items in collection responses; may be nil
+}
+
+func serveChildren[T any](s *Server, r request, task *taskRecord, rest []string, items *[]T, c children[T]) *response {
+	if len(rest) == 0 {
+		switch r.method {
+		case "GET":
+			var values []any
+			for _, item := range *items {
+				if c.listed != nil {
+					item = c.listed(item)
+				}
+				values = append(values, item)
+			}
+			return jsonResponse(http.StatusOK, s.page(r, values))
+		case "POST":
+			var item T
+			if err := json.Unmarshal(r.body, &item); err != nil {
+				return invalidRequest(err.Error())
+			}
+			*c.id(&item) = s.newID(c.kind)
+			if c.created != nil {
+				c.created(&item)
+			}
+			*items = append(*items, item)
+			task.seq = s.touch()
+			return jsonResponse(http.StatusCreated, item)
+		}
+		return methodNotAllowed()
+	}
+	if len(rest) > 1 {
+		return badSegment(rest[1])
+	}
+
+	i := slices.IndexFunc(*items, func(item T) bool { return *c.id(&item) == rest[0] })
+	if i < 0 {
+		return notFound()
+	}
+	switch r.method {
+	case "GET":
+		return jsonResponse(http.StatusOK, (*items)[i])
+	case "PATCH":
+		item := (*items)[i]
+		if err := merge(&item, r.body); err != nil {
+			return invalidRequest(err.Error())
+		}
+		*c.id(&item) = rest[0]
+		(*items)[i] = item
+		task.seq = s.touch()
+		return jsonResponse(http.StatusOK, item)
+	case "DELETE":
+		*items = slices.Delete(*items, i, i+1)
+		task.seq = s.touch()
+		return jsonResponse(http.StatusNoContent, nil)
+	}
+	return methodNotAllowed()
+}
+
+// page returns one page of items, with a next link if more follow. The page
+// size is $top if given, or s.PageSize.
+func (s *Server) page(r request, items []any) map[string]any {
+	size := s.PageSize
+	if top, err := strconv.Atoi(r.query.Get("$top")); err == nil && top > 0 {
+		size = top
+	}
+	skip, _ := strconv.Atoi(r.query.Get("$skiptoken"))
+	skip = min(max(skip, 0), len(items))
+	end := min(skip+size, len(items))
+
+	body := map[string]any{"value": append([]any{}, items[skip:end]...)}
+	if end < len(items) {
+		query := url.Values{}
+		for key, values := range r.query {
+			query[key] = values
+		}
+		query.Set("$skiptoken", strconv.Itoa(end))
+		body["@odata.nextLink"] = s.URL + apiVersion + r.path + "?" + query.Encode()
+	}
+	return body
+}
+
+// serveDelta answers a delta query with the items changes returns for the
+// sequence number in the delta token, or every item for an initial query.
+func (s *Server) serveDelta(r request, changes func(since int) []any) *response {
+	if r.method != "GET" {
+		return methodNotAllowed()
+	}
+	since := 0
+	if token := r.query.Get("$deltatoken"); token != "" {
+		gen, seq, _ := strings.Cut(token, ".")
+		var err error
+		since, err = strconv.Atoi(seq)
+		if err != nil || gen != strconv.Itoa(s.deltaGen) {
+			return errorResponse(http.StatusGone, "syncStateNotFound", "The sync state generation is not found.")
+		}
+	}
+
+	body := s.page(r, changes(since))
+	if _, more := body["@odata.nextLink"]; !more {
+		body["@odata.deltaLink"] = fmt.Sprintf("%s%s%s?$deltatoken=%d.%d", s.URL, apiVersion, r.path, s.deltaGen, s.seq)
+	}
+	return jsonResponse(http.StatusOK, body)
+}
+
+func (s *Server) listChanges(since int) []any {
+	var changes []any
+	for _, rec := range s.lists {
+		switch {
+		case rec.seq <= since, rec.deleted && since == 0:
+		case rec.deleted:
+			changes = append(changes, types.TodoTaskList{ID: rec.list.ID, Removed: &types.Removed{Reason: "deleted"}})
+		default:
+			changes = append(changes, rec.list)
+		}
+	}
+	return changes
+}
+
+func (l *listRecord) taskChanges(since int) []any {
+	var changes []any
+	for _, rec := range l.tasks {
+		switch {
+		case rec.seq <= since, rec.deleted && since == 0:
+		case rec.deleted:
+			changes = append(changes, types.TodoTask{ID: rec.task.ID, Removed: &types.Removed{Reason: "deleted"}})
+		default:
+			changes = append(changes, rec.view(false, false))
+		}
+	}
+	return changes
+}
+
+// merge applies a JSON merge patch to v. Properties set to null are removed.
+func merge[T any](v *T, patch []byte) error {
+	var changes map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return fmt.Errorf("invalid patch: %w", err)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for key, value := range changes {
+		if string(value) == "null" {
+			delete(fields, key)
+		} else {
+			fields[key] = value
+		}
+	}
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	var merged T
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return fmt.Errorf("invalid patch: %w", err)
+	}
+	*v = merged
+	return nil
+}
+
+func toMap(v any) map[string]any {
+	data, _ := json.Marshal(v)
+	var m map[string]any
+	json.Unmarshal(data, &m)
+	return m
+}
+
+// property returns a property of a JSON object by a path such as
+// "dueDateTime/dateTime", or nil if it is not set.
+func property(m map[string]any, path string) any {
+	var value any = m
+	for _, name := range strings.Split(path, "/") {
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = obj[name]
+	}
+	return value
+}
+
+// sortKey returns a property value as a string that sorts correctly.
+// Date-times lose their fractional seconds, so they compare with the
+// literals in filters.
+func sortKey(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		if _, err := time.Parse(graphDateTime, v); err == nil {
+			v, _, _ = strings.Cut(v, ".")
+		}
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// anyClause matches a "categories/any(v:v eq 'x')" filter clause.
+var anyClause = regexp.MustCompile(`^([A-Za-z]+)/any\(v:v eq '(.*)'\)$`)
+
+// parseFilter supports the $filter expressions the client builds: property
+// comparisons and any() on string collections, joined by "and". any()
+// compares strings without regard to case, as store.ApplyQuery does.
+func parseFilter(filter string) (func(map[string]any) bool, error) {
+	if filter == "" {
+		return func(map[string]any) bool { return true }, nil
+	}
+	if strings.Contains(filter, ") or (") {
+		return nil, fmt.Errorf("graphtest does not support 'or' in $filter")
+	}
+
+	type clause struct{ field, op, value string }
+	var clauses []clause
+	parts := strings.Split(filter, ") and (")
+	if len(parts) > 1 {
+		parts[0] = strings.TrimPrefix(parts[0], "(")
+		parts[len(parts)-1] = strings.TrimSuffix(parts[len(parts)-1], ")")
+	}
+	for _, part := range parts {
+		if m := anyClause.FindStringSubmatch(part); m != nil {
+			clauses = append(clauses, clause{m[1], "any", strings.ReplaceAll(m[2], "''", "'")})
+			continue
+		}
+		fields := strings.SplitN(part, " ", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid filter clause '%s'", part)
+		}
+		value := fields[2]
+		if quoted, ok := strings.CutPrefix(value, "'"); ok {
+			value = strings.ReplaceAll(strings.TrimSuffix(quoted, "'"), "''", "'")
+		}
+		if !slices.Contains([]string{"eq", "ne", "lt", "le", "gt", "ge"}, fields[1]) {
+			return nil, fmt.Errorf("unsupported operator '%s'", fields[1])
+		}
+		clauses = append(clauses, clause{fields[0], fields[1], sortKey(value)})
+	}
+
+	return func(m map[string]any) bool {
+		for _, c := range clauses {
+			raw := property(m, c.field)
+			v := sortKey(raw)
+			var ok bool
+			switch c.op {
+			case "any":
+				items, _ := raw.([]any)
+				ok = slices.Contains(items, any(c.value))
+			case "eq":
+				ok = v == c.value
+			case "ne":
+				ok = v != c.value
+			case "lt":
+				ok = raw != nil && v < c.value
+			case "le":
+				ok = raw != nil && v <= c.value
+			case "gt":
+				ok = raw != nil && v > c.value
+			case "ge":
+				ok = raw != nil && v >= c.value
+			}
+			if !ok {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// orderBy sorts items by an $orderby expression such as "title desc".
+func orderBy(items []map[string]any, expr string) error {
+	if expr == "" {
+		return nil
+	}
+	field, direction, _ := strings.Cut(expr, " ")
+	if direction != "" && direction != "asc" && direction != "desc" {
+		return fmt.Errorf("invalid $orderby '%s'", expr)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := sortKey(property(items[i], field)), sortKey(property(items[j], field))
+		if direction == "desc" {
+			return a > b
+		}
+		return a < b
+	})
+	return nil
+}
+
+// selectFields keeps only the ID and the named properties of each item.
+func selectFields(items []map[string]any, fields []string) {
+	for _, item := range items {
+		for key := range item {
+			if key != "id" && !slices.Contains(fields, key) {
+				delete(item, key)
+			}
+		}
+	}
+}
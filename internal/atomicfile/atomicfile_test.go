@@ -0,0 +1,45 @@
+package atomicfile_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/internal/atomicfile"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "queue.json")
+
+	for _, contents := range []string{"first", "second"} {
+		if err := atomicfile.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if data, err := os.ReadFile(path); err != nil || string(data) != contents {
+			t.Errorf("file = %q, %v; want %q", data, err, contents)
+		}
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("file mode = %v, %v", info.Mode(), err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files were left behind: %v", entries)
+	}
+}
+
+func TestWriteFileCleansUpOnFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	// A directory in the way makes the rename fail after the data is written.
+	path := filepath.Join(dir, "cache.json")
+	if err := os.MkdirAll(filepath.Join(path, "child"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := atomicfile.WriteFile(path, []byte("new"), 0600); err == nil {
+		t.Fatal("WriteFile over a non-empty directory succeeded")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files were left behind: %v", entries)
+	}
+}
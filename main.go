@@ -1,17 +1,76 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/mark3labs/mcp-go/server"
 
 	"github.com/michMartineau/mcp-server-microsoft-todo/auth"
+	"github.com/michMartineau/mcp-server-microsoft-todo/cassette"
 	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
 	"github.com/michMartineau/mcp-server-microsoft-todo/tools"
 )
 
+// cloud is a national cloud's login authority and Graph endpoint.
+type cloud struct {
+	authorityHost string
+	graphURL      string
+	graphResource string // empty for the global service
+}
+
+var clouds = map[string]cloud{
+	"global":    {auth.GlobalAuthority, client.GlobalEndpoint, ""},
+	"usgov":     {auth.USGovAuthority, client.USGovEndpoint, "https://graph.microsoft.us"},
+	"usgov-dod": {auth.USGovAuthority, client.USGovDoDEndpoint, "https://dod-graph.microsoft.us"},
+	"china":     {auth.ChinaAuthority, client.ChinaEndpoint, "https://microsoftgraph.chinacloudapi.cn"},
+}
+
+// fileConfig is the optional config.json in the configuration directory.
+// Environment variables take precedence over it.
+type fileConfig struct {
+	Tenant string `json:"tenant,omitempty"`
+
+	// Accounts configures account profiles other than the default one,
+	// by profile name.
+	Accounts map[string]accountConfig `json:"accounts,omitempty"`
+}
+
+// accountConfig is the configuration of one account profile.
+type accountConfig struct {
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// loadFileConfig reads config.json, returning an empty config if there is
+// none.
+func loadFileConfig() (fileConfig, error) {
+	var config fileConfig
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return config, nil
+	}
+	path := filepath.Join(configDir, "mcp-server-microsoft-todo", "config.json")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return config, nil
+}
+
 func main() {
 	clientID := os.Getenv("MS_TODO_CLIENT_ID")
 	if clientID == "" {
@@ -19,21 +78,160 @@ func main() {
 		os.Exit(1)
 	}
 
-	tokenManager, err := auth.NewTokenManager(clientID)
+	cloudName := os.Getenv("MS_TODO_CLOUD")
+	if cloudName == "" {
+		cloudName = "global"
+	}
+	cloud, ok := clouds[cloudName]
+	if !ok {
+		log.Fatalf("Invalid MS_TODO_CLOUD %q: must be global, usgov, usgov-dod or china", cloudName)
+	}
+	if v := os.Getenv("MS_TODO_GRAPH_URL"); v != "" {
+		cloud.graphURL = v
+	}
+	if v := os.Getenv("MS_TODO_AUTHORITY_HOST"); v != "" {
+		cloud.authorityHost = v
+	}
+
+	config, err := loadFileConfig()
 	if err != nil {
-		log.Fatalf("Failed to create token manager: %v", err)
+		log.Fatalf("Failed to read configuration: %v", err)
+	}
+	tenant := config.Tenant
+	if v := os.Getenv("MS_TODO_TENANT"); v != "" {
+		tenant = v
+	}
+	if tenant == "" {
+		tenant = auth.TenantConsumers
+	}
+	if err := auth.ValidateTenant(tenant); err != nil {
+		log.Fatalf("Invalid MS_TODO_TENANT: %v", err)
+	}
+	for name, account := range config.Accounts {
+		if err := auth.ValidateProfile(name); err != nil {
+			log.Fatalf("Invalid account in configuration: %v", err)
+		}
+		if account.Tenant != "" {
+			if err := auth.ValidateTenant(account.Tenant); err != nil {
+				log.Fatalf("Invalid tenant for account %s: %v", name, err)
+			}
+		}
+	}
+
+	activeAccount := os.Getenv("MS_TODO_ACCOUNT")
+	if activeAccount == "" {
+		activeAccount = auth.DefaultProfile
+	}
+	if err := auth.ValidateProfile(activeAccount); err != nil {
+		log.Fatalf("Invalid MS_TODO_ACCOUNT: %v", err)
+	}
+
+	authOpts := []auth.Option{
+		auth.WithAuthorityHost(cloud.authorityHost),
+		auth.WithGraphResource(cloud.graphResource),
+	}
+	passphrase, keyFile := os.Getenv("MS_TODO_TOKEN_PASSPHRASE"), os.Getenv("MS_TODO_TOKEN_KEY_FILE")
+	if passphrase != "" || keyFile != "" {
+		var key *auth.TokenKey
+		switch {
+		case passphrase != "" && keyFile != "":
+			log.Fatalf("Set only one of MS_TODO_TOKEN_PASSPHRASE and MS_TODO_TOKEN_KEY_FILE")
+		case passphrase != "":
+			key, err = auth.PassphraseKey(passphrase)
+		default:
+			key, err = auth.ReadKeyFile(keyFile)
+		}
+		if err != nil {
+			log.Fatalf("Invalid token encryption key: %v", err)
+		}
+		authOpts = append(authOpts, auth.WithTokenKey(key))
+	}
+	clientOpts := []client.Option{client.WithBaseURL(cloud.graphURL)}
+	var proxyURL *url.URL
+	if v := os.Getenv("MS_TODO_PROXY"); v != "" {
+		var err error
+		proxyURL, err = url.Parse(v)
+		if err != nil || proxyURL.Host == "" {
+			log.Fatalf("Invalid MS_TODO_PROXY %q: must be a URL such as http://proxy.example.com:8080", v)
+		}
+		authOpts = append(authOpts, auth.WithProxy(proxyURL))
+		clientOpts = append(clientOpts, client.WithProxy(proxyURL))
+	}
+	var recorder *cassette.Recorder
+	if path := os.Getenv("MS_TODO_RECORD"); path != "" {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if proxyURL != nil {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+		recorder = cassette.NewRecorder(path, transport)
+		authOpts = append(authOpts, auth.WithTransport(recorder))
+		clientOpts = append(clientOpts, client.WithTransport(recorder))
+		log.Printf("Recording Graph requests to %s", path)
+	}
+
+	retryPolicy := client.DefaultRetryPolicy()
+	if v := os.Getenv("MS_TODO_MAX_RETRIES"); v != "" {
+		retryPolicy.MaxRetries, err = strconv.Atoi(v)
+		if err != nil || retryPolicy.MaxRetries < 0 {
+			log.Fatalf("Invalid MS_TODO_MAX_RETRIES %q: must be a non-negative integer", v)
+		}
+	}
+	if v := os.Getenv("MS_TODO_RETRY_MAX_DELAY"); v != "" {
+		retryPolicy.MaxDelay, err = time.ParseDuration(v)
+		if err != nil || retryPolicy.MaxDelay <= 0 {
+			log.Fatalf("Invalid MS_TODO_RETRY_MAX_DELAY %q: must be a positive duration such as 30s", v)
+		}
+	}
+
+	clientOpts = append(clientOpts, client.WithRetryPolicy(retryPolicy))
+
+	cacheMaxAge := 2 * time.Minute
+	if v := os.Getenv("MS_TODO_CACHE_MAX_AGE"); v != "" {
+		cacheMaxAge, err = time.ParseDuration(v)
+		if err != nil || cacheMaxAge <= 0 {
+			log.Fatalf("Invalid MS_TODO_CACHE_MAX_AGE %q: must be a positive duration such as 2m", v)
+		}
 	}
 
-	graphClient := client.NewGraphClient(tokenManager)
+	// Each account profile has its own tokens, cache and queue. Profiles
+	// other than the default one sign in to their configured tenant, or the
+	// default tenant.
+	openAccount := func(profile string) (*tools.Account, error) {
+		accountTenant := tenant
+		if account, ok := config.Accounts[profile]; ok && account.Tenant != "" {
+			accountTenant = account.Tenant
+		}
+		opts := append(authOpts[:len(authOpts):len(authOpts)], auth.WithProfile(profile), auth.WithTenant(accountTenant))
+		tokenManager, err := auth.NewTokenManager(clientID, opts...)
+		if err != nil {
+			return nil, err
+		}
+		graphClient := client.NewGraphClient(tokenManager, clientOpts...)
+		cache := store.NewCache(graphClient, tokenManager.StatePath("cache.json"), cacheMaxAge)
+		queue := store.NewQueue(graphClient, cache, tokenManager.StatePath("queue.json"))
+		return &tools.Account{Tokens: tokenManager, Graph: graphClient, Cache: cache, Queue: queue}, nil
+	}
 
 	mcpServer := server.NewMCPServer(
 		"microsoft-todo",
 		"0.1.0",
 	)
 
-	tools.Register(mcpServer, graphClient, tokenManager)
+	configured := make([]string, 0, len(config.Accounts))
+	for name := range config.Accounts {
+		configured = append(configured, name)
+	}
+	if err := tools.Register(mcpServer, openAccount, activeAccount, configured); err != nil {
+		log.Fatalf("Failed to open account %s: %v", activeAccount, err)
+	}
 
-	if err := server.ServeStdio(mcpServer); err != nil {
+	err = server.ServeStdio(mcpServer)
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			log.Printf("Failed to save the recording: %v", err)
+		}
+	}
+	if err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
-}
\ No newline at end of file
+}
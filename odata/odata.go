@@ -0,0 +1,194 @@
+// Package odata builds OData $filter expressions for Microsoft Graph from
+// typed, whitelisted parts, so user input can never change the shape of a query.
+package odata
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Op is a comparison operator.
+type Op string
+
+// Supported comparison operators.
+const (
+	Eq Op = "eq"
+	Ne Op = "ne"
+	Lt Op = "lt"
+	Le Op = "le"
+	Gt Op = "gt"
+	Ge Op = "ge"
+)
+
+// Kind is the type of a filterable property.
+type Kind int
+
+const (
+	// String properties take string values and support eq and ne.
+	String Kind = iota
+	// Bool properties take bool values and support eq and ne.
+	Bool
+	// DateTime properties take time.Time values, rendered as the UTC local
+	// date/time strings Graph uses in dateTimeTimeZone.dateTime, and
+	// support every operator.
+	DateTime
+	// StringCollection properties hold lists of strings and are filtered
+	// with Any only.
+	StringCollection
+)
+
+// dateTimeLayout matches the dateTime strings Graph stores.
+const dateTimeLayout = "2006-01-02T15:04:05"
+
+// Field describes a property that may appear in a filter.
+type Field struct {
+	Kind Kind
+
+	// Values, if set, restricts a String field to these values.
+	Values []string
+}
+
+// Fields is a whitelist of filterable properties, keyed by property path
+// such as "status" or "dueDateTime/dateTime".
+type Fields map[string]Field
+
+// Expr is a validated filter expression. The zero value is an empty filter.
+type Expr struct {
+	s string
+}
+
+// String returns the expression in OData syntax.
+func (e Expr) String() string {
+	return e.s
+}
+
+// IsZero reports whether the expression is empty.
+func (e Expr) IsZero() bool {
+	return e.s == ""
+}
+
+// Compare builds "field op value" after checking that field is whitelisted,
+// op is valid for its kind and value has the right type.
+func (f Fields) Compare(field string, op Op, value any) (Expr, error) {
+	def, ok := f[field]
+	if !ok {
+		return Expr{}, fmt.Errorf("field %q cannot be filtered on", field)
+	}
+	if !validOp(def.Kind, op) {
+		return Expr{}, fmt.Errorf("operator %q is not allowed on field %q", op, field)
+	}
+
+	var literal string
+	switch def.Kind {
+	case StringCollection:
+		return Expr{}, fmt.Errorf("field %q is a collection; use Any", field)
+	case String:
+		s, ok := value.(string)
+		if !ok {
+			return Expr{}, fmt.Errorf("field %q expects a string, got %T", field, value)
+		}
+		if len(def.Values) > 0 && !slices.Contains(def.Values, s) {
+			return Expr{}, fmt.Errorf("field %q must be one of %v, got %q", field, def.Values, s)
+		}
+		var err error
+		if literal, err = Quote(s); err != nil {
+			return Expr{}, err
+		}
+	case Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return Expr{}, fmt.Errorf("field %q expects a bool, got %T", field, value)
+		}
+		literal = fmt.Sprintf("%t", b)
+	case DateTime:
+		t, ok := value.(time.Time)
+		if !ok {
+			return Expr{}, fmt.Errorf("field %q expects a time.Time, got %T", field, value)
+		}
+		literal = "'" + t.UTC().Format(dateTimeLayout) + "'"
+	default:
+		return Expr{}, fmt.Errorf("field %q has unknown kind %d", field, def.Kind)
+	}
+
+	return Expr{s: fmt.Sprintf("%s %s %s", field, op, literal)}, nil
+}
+
+// Any builds "field/any(v:v eq value)", which matches when any item of the
+// collection field equals value, after checking that field is a whitelisted
+// StringCollection and value is a string.
+func (f Fields) Any(field string, value any) (Expr, error) {
+	def, ok := f[field]
+	if !ok {
+		return Expr{}, fmt.Errorf("field %q cannot be filtered on", field)
+	}
+	if def.Kind != StringCollection {
+		return Expr{}, fmt.Errorf("field %q is not a collection", field)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return Expr{}, fmt.Errorf("field %q expects a string, got %T", field, value)
+	}
+	if len(def.Values) > 0 && !slices.Contains(def.Values, s) {
+		return Expr{}, fmt.Errorf("field %q must be one of %v, got %q", field, def.Values, s)
+	}
+	literal, err := Quote(s)
+	if err != nil {
+		return Expr{}, err
+	}
+	return Expr{s: fmt.Sprintf("%s/any(v:v eq %s)", field, literal)}, nil
+}
+
+func validOp(kind Kind, op Op) bool {
+	switch op {
+	case Eq, Ne:
+		return true
+	case Lt, Le, Gt, Ge:
+		return kind == DateTime
+	default:
+		return false
+	}
+}
+
+// And joins expressions with "and", skipping empty ones.
+func And(exprs ...Expr) Expr {
+	return join("and", exprs)
+}
+
+// Or joins expressions with "or", skipping empty ones.
+func Or(exprs ...Expr) Expr {
+	return join("or", exprs)
+}
+
+func join(op string, exprs []Expr) Expr {
+	var parts []string
+	for _, e := range exprs {
+		if !e.IsZero() {
+			parts = append(parts, e.s)
+		}
+	}
+	switch len(parts) {
+	case 0:
+		return Expr{}
+	case 1:
+		return Expr{s: parts[0]}
+	default:
+		return Expr{s: "(" + strings.Join(parts, ") "+op+" (") + ")"}
+	}
+}
+
+// Quote renders s as an OData string literal, doubling embedded single
+// quotes. Invalid UTF-8 and control characters are rejected.
+func Quote(s string) (string, error) {
+	if !utf8.ValidString(s) {
+		return "", fmt.Errorf("string value is not valid UTF-8")
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			return "", fmt.Errorf("string value contains control character %U", r)
+		}
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'", nil
+}
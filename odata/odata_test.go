@@ -0,0 +1,140 @@
+package odata
+
+import (
+	"testing"
+	"time"
+)
+
+var testFields = Fields{
+	"displayName":          {Kind: String},
+	"status":               {Kind: String, Values: []string{"notStarted", "completed"}},
+	"isReminderOn":         {Kind: Bool},
+	"dueDateTime/dateTime": {Kind: DateTime},
+	"categories":           {Kind: StringCollection},
+}
+
+func TestCompare(t *testing.T) {
+	due := time.Date(2025, 12, 31, 17, 0, 0, 0, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		name    string
+		field   string
+		op      Op
+		value   any
+		want    string
+		wantErr bool
+	}{
+		{name: "plain string", field: "displayName", op: Eq, value: "Groceries", want: "displayName eq 'Groceries'"},
+		{name: "apostrophe", field: "displayName", op: Eq, value: "Mike's list", want: "displayName eq 'Mike''s list'"},
+		{name: "only quotes", field: "displayName", op: Ne, value: "''", want: "displayName ne ''''''"},
+		{
+			name:  "quote injection",
+			field: "displayName", op: Eq, value: "x' or displayName ne 'y",
+			want: "displayName eq 'x'' or displayName ne ''y'",
+		},
+		{
+			name:  "parenthesis injection",
+			field: "displayName", op: Eq, value: "a') or (1 eq 1",
+			want: "displayName eq 'a'') or (1 eq 1'",
+		},
+		{name: "query string characters", field: "displayName", op: Eq, value: "a&$top=1#", want: "displayName eq 'a&$top=1#'"},
+		{name: "unicode", field: "displayName", op: Eq, value: "Café ☕", want: "displayName eq 'Café ☕'"},
+		{name: "enum value", field: "status", op: Eq, value: "completed", want: "status eq 'completed'"},
+		{name: "bool", field: "isReminderOn", op: Eq, value: true, want: "isReminderOn eq true"},
+		{name: "datetime converted to UTC", field: "dueDateTime/dateTime", op: Lt, value: due, want: "dueDateTime/dateTime lt '2025-12-31T16:00:00'"},
+
+		{name: "unknown field", field: "id", op: Eq, value: "x", wantErr: true},
+		{name: "field injection", field: "displayName eq 'a' or displayName", op: Eq, value: "x", wantErr: true},
+		{name: "unknown operator", field: "displayName", op: "eq 'a' or displayName eq", value: "x", wantErr: true},
+		{name: "function operator", field: "displayName", op: "startswith", value: "x", wantErr: true},
+		{name: "range operator on string", field: "displayName", op: Gt, value: "x", wantErr: true},
+		{name: "value outside enum", field: "status", op: Eq, value: "completed' or 'a' eq 'a", wantErr: true},
+		{name: "wrong type for string", field: "displayName", op: Eq, value: 42, wantErr: true},
+		{name: "wrong type for bool", field: "isReminderOn", op: Eq, value: "true or 1 eq 1", wantErr: true},
+		{name: "wrong type for datetime", field: "dueDateTime/dateTime", op: Lt, value: "2025-01-01' or 'a", wantErr: true},
+		{name: "control character", field: "displayName", op: Eq, value: "a\x00b", wantErr: true},
+		{name: "newline", field: "displayName", op: Eq, value: "a\nb", wantErr: true},
+		{name: "invalid utf8", field: "displayName", op: Eq, value: "a\xffb", wantErr: true},
+		{name: "comparison on collection", field: "categories", op: Eq, value: "x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testFields.Compare(tt.field, tt.op, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Compare() = %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Compare() error = %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("Compare() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAny(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		value   any
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", field: "categories", value: "Work", want: "categories/any(v:v eq 'Work')"},
+		{name: "quote injection", field: "categories", value: "x') or (1 eq 1", want: "categories/any(v:v eq 'x'') or (1 eq 1')"},
+
+		{name: "unknown field", field: "tags", value: "x", wantErr: true},
+		{name: "not a collection", field: "displayName", value: "x", wantErr: true},
+		{name: "wrong type", field: "categories", value: 1, wantErr: true},
+		{name: "control character", field: "categories", value: "a\nb", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testFields.Any(tt.field, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Any() = %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Any() error = %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("Any() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAndOr(t *testing.T) {
+	a, _ := testFields.Compare("displayName", Eq, "a")
+	b, _ := testFields.Compare("status", Eq, "completed")
+
+	tests := []struct {
+		name string
+		got  Expr
+		want string
+	}{
+		{name: "empty and", got: And(), want: ""},
+		{name: "single", got: And(a), want: "displayName eq 'a'"},
+		{name: "skips empty", got: And(Expr{}, a, Expr{}), want: "displayName eq 'a'"},
+		{name: "and", got: And(a, b), want: "(displayName eq 'a') and (status eq 'completed')"},
+		{name: "or", got: Or(a, b), want: "(displayName eq 'a') or (status eq 'completed')"},
+		{name: "nested", got: And(Or(a, b), a), want: "((displayName eq 'a') or (status eq 'completed')) and (displayName eq 'a')"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got.String() != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,407 @@
+// Package store keeps a persistent local copy of the user's To-Do lists and
+// tasks, so read tools keep working when Microsoft Graph is unreachable or
+// throttling requests.
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/internal/atomicfile"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// Freshness tells a caller how current the data returned by the cache is.
+type Freshness struct {
+	// FetchedAt is when the data was last refreshed from Graph.
+	FetchedAt time.Time
+
+	// Cached is true when the data was served from the local copy instead
+	// of being refreshed during this call.
+	Cached bool
+
+	// RefreshErr is set when a refresh was attempted but failed, and stale
+	// data was served instead.
+	RefreshErr error
+}
+
+// Age returns how old the data is.
+func (f Freshness) Age() time.Duration {
+	return time.Since(f.FetchedAt)
+}
+
+type cachedLists struct {
+	Lists     []types.TodoTaskList `json:"lists"`
+	DeltaLink string               `json:"delta_link"`
+	FetchedAt time.Time            `json:"fetched_at"`
+	Stale     bool                 `json:"stale"`
+}
+
+type cachedTasks struct {
+	Tasks     []types.TodoTask `json:"tasks"`
+	DeltaLink string           `json:"delta_link"`
+	FetchedAt time.Time        `json:"fetched_at"`
+	Stale     bool             `json:"stale"`
+}
+
+type cacheFile struct {
+	Lists *cachedLists            `json:"lists,omitempty"`
+	Tasks map[string]*cachedTasks `json:"tasks"`
+}
+
+// Cache is a local copy of lists and tasks, persisted as JSON. Entries
+// younger than the staleness bound are served without contacting Graph;
+// older or invalidated entries are refreshed incrementally with delta
+// queries, falling back to the local copy if Graph cannot be reached.
+// Cached tasks include their checklist items and linked resources.
+type Cache struct {
+	graph  *client.GraphClient
+	path   string
+	maxAge time.Duration
+
+	mu   sync.Mutex
+	data *cacheFile
+}
+
+// NewCache creates a cache persisted at path. Data younger than maxAge is
+// served without contacting Graph. Writes made through graph invalidate the
+// affected entries.
+func NewCache(graph *client.GraphClient, path string, maxAge time.Duration) *Cache {
+	c := &Cache{graph: graph, path: path, maxAge: maxAge}
+	graph.OnWrite(c.Invalidate)
+	return c
+}
+
+// load reads the cache file on first use. Callers must hold c.mu.
+func (c *Cache) load() error {
+	if c.data != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		c.data = &cacheFile{Tasks: map[string]*cachedTasks{}}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading cache: %w", err)
+	}
+
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		// A corrupt cache is not worth failing over; start afresh.
+		file = cacheFile{}
+	}
+	if file.Tasks == nil {
+		file.Tasks = map[string]*cachedTasks{}
+	}
+	c.data = &file
+	return nil
+}
+
+// save writes the cache file. Callers must hold c.mu.
+func (c *Cache) save() error {
+	data, err := json.Marshal(c.data)
+	if err != nil {
+		return fmt.Errorf("marshaling cache: %w", err)
+	}
+	if err := atomicfile.WriteFile(c.path, data, 0600); err != nil {
+		return fmt.Errorf("writing cache: %w", err)
+	}
+	return nil
+}
+
+func (c *Cache) fresh(fetchedAt time.Time, stale bool) bool {
+	return !stale && time.Since(fetchedAt) <= c.maxAge
+}
+
+// Invalidate marks cached data affected by a write as stale. It matches
+// client.WriteHook.
+func (c *Cache) Invalidate(listID string, listChanged bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return
+	}
+	if listChanged && c.data.Lists != nil {
+		c.data.Lists.Stale = true
+	}
+	if entry, ok := c.data.Tasks[listID]; ok {
+		entry.Stale = true
+	}
+	_ = c.save()
+}
+
+// Lists returns the user's task lists.
+func (c *Cache) Lists(ctx context.Context) ([]types.TodoTaskList, Freshness, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return nil, Freshness{}, err
+	}
+	entry := c.data.Lists
+	if entry != nil && c.fresh(entry.FetchedAt, entry.Stale) {
+		return entry.Lists, Freshness{FetchedAt: entry.FetchedAt, Cached: true}, nil
+	}
+
+	var previous []types.TodoTaskList
+	var deltaLink string
+	if entry != nil {
+		previous, deltaLink = entry.Lists, entry.DeltaLink
+	}
+
+	now := time.Now()
+	changes, next, err := c.graph.ListsDelta(ctx, deltaLink)
+	if err != nil && deltaLink != "" && client.IsDeltaExpired(err) {
+		previous = nil
+		changes, next, err = c.graph.ListsDelta(ctx, "")
+	}
+	if err != nil {
+		if entry == nil {
+			return nil, Freshness{}, err
+		}
+		return entry.Lists, Freshness{FetchedAt: entry.FetchedAt, Cached: true, RefreshErr: err}, nil
+	}
+
+	lists := mergeLists(previous, changes)
+	c.data.Lists = &cachedLists{Lists: lists, DeltaLink: next, FetchedAt: now}
+	if err := c.save(); err != nil {
+		return nil, Freshness{}, err
+	}
+	return lists, Freshness{FetchedAt: now}, nil
+}
+
+// Tasks returns every task in a list.
+func (c *Cache) Tasks(ctx context.Context, listID string) ([]types.TodoTask, Freshness, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return nil, Freshness{}, err
+	}
+	entry := c.data.Tasks[listID]
+	if entry != nil && c.fresh(entry.FetchedAt, entry.Stale) {
+		return entry.Tasks, Freshness{FetchedAt: entry.FetchedAt, Cached: true}, nil
+	}
+
+	var previous []types.TodoTask
+	var deltaLink string
+	if entry != nil {
+		previous, deltaLink = entry.Tasks, entry.DeltaLink
+	}
+
+	now := time.Now()
+	changes, next, err := c.graph.TasksDelta(ctx, listID, deltaLink)
+	if err != nil && deltaLink != "" && client.IsDeltaExpired(err) {
+		previous = nil
+		changes, next, err = c.graph.TasksDelta(ctx, listID, "")
+	}
+	if err == nil {
+		err = c.graph.ExpandTasks(ctx, listID, changes)
+	}
+	if err != nil {
+		if entry == nil {
+			return nil, Freshness{}, err
+		}
+		return entry.Tasks, Freshness{FetchedAt: entry.FetchedAt, Cached: true, RefreshErr: err}, nil
+	}
+
+	tasks := mergeTasks(previous, changes)
+	c.data.Tasks[listID] = &cachedTasks{Tasks: tasks, DeltaLink: next, FetchedAt: now}
+	if err := c.save(); err != nil {
+		return nil, Freshness{}, err
+	}
+	return tasks, Freshness{FetchedAt: now}, nil
+}
+
+// AllTasks returns every task in each of the given lists, keyed by list ID.
+// Lists whose cache entry is out of date are refreshed together in JSON
+// batches rather than one request each. The freshness describes the oldest
+// data returned.
+func (c *Cache) AllTasks(ctx context.Context, listIDs []string) (map[string][]types.TodoTask, Freshness, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return nil, Freshness{}, err
+	}
+
+	result := make(map[string][]types.TodoTask, len(listIDs))
+	freshness := Freshness{FetchedAt: time.Now()}
+	stale := map[string]string{}
+	for _, listID := range listIDs {
+		entry := c.data.Tasks[listID]
+		if entry != nil && c.fresh(entry.FetchedAt, entry.Stale) {
+			result[listID] = entry.Tasks
+			freshness = older(freshness, Freshness{FetchedAt: entry.FetchedAt, Cached: true})
+			continue
+		}
+		stale[listID] = ""
+		if entry != nil {
+			stale[listID] = entry.DeltaLink
+		}
+	}
+	if len(stale) == 0 {
+		return result, freshness, nil
+	}
+
+	now := time.Now()
+	for listID, delta := range c.graph.TasksDeltaBatch(ctx, stale) {
+		entry := c.data.Tasks[listID]
+		var previous []types.TodoTask
+		if entry != nil {
+			previous = entry.Tasks
+		}
+		if delta.Err != nil && stale[listID] != "" && client.IsDeltaExpired(delta.Err) {
+			// Refetch the list from scratch rather than merging into it.
+			previous = nil
+			delta = &client.TaskDelta{}
+			delta.Changes, delta.DeltaLink, delta.Err = c.graph.TasksDelta(ctx, listID, "")
+		}
+		if delta.Err == nil {
+			delta.Err = c.graph.ExpandTasks(ctx, listID, delta.Changes)
+		}
+		if delta.Err != nil {
+			if entry == nil {
+				return nil, Freshness{}, delta.Err
+			}
+			result[listID] = entry.Tasks
+			freshness = older(freshness, Freshness{FetchedAt: entry.FetchedAt, Cached: true, RefreshErr: delta.Err})
+			continue
+		}
+
+		tasks := mergeTasks(previous, delta.Changes)
+		c.data.Tasks[listID] = &cachedTasks{Tasks: tasks, DeltaLink: delta.DeltaLink, FetchedAt: now}
+		result[listID] = tasks
+		freshness = older(freshness, Freshness{FetchedAt: now})
+	}
+	if err := c.save(); err != nil {
+		return nil, Freshness{}, err
+	}
+	return result, freshness, nil
+}
+
+// older returns whichever freshness describes older data, keeping any
+// refresh error.
+func older(a, b Freshness) Freshness {
+	oldest := a
+	if b.FetchedAt.Before(a.FetchedAt) {
+		oldest = b
+	}
+	oldest.Cached = a.Cached || b.Cached
+	if oldest.RefreshErr == nil {
+		oldest.RefreshErr = a.RefreshErr
+	}
+	if oldest.RefreshErr == nil {
+		oldest.RefreshErr = b.RefreshErr
+	}
+	return oldest
+}
+
+// QueryTasks returns the tasks in a list that match q. A fresh cache entry
+// answers locally; an unfiltered query refreshes the entry incrementally;
+// a filtered query goes to Graph so only the matching tasks are downloaded.
+// If Graph cannot be reached, any cached copy is queried instead.
+func (c *Cache) QueryTasks(ctx context.Context, listID string, q client.TaskQuery) ([]types.TodoTask, Freshness, error) {
+	if q.IsZero() {
+		return c.Tasks(ctx, listID)
+	}
+
+	c.mu.Lock()
+	if err := c.load(); err != nil {
+		c.mu.Unlock()
+		return nil, Freshness{}, err
+	}
+	var cached []types.TodoTask
+	var fetchedAt time.Time
+	entry, ok := c.data.Tasks[listID]
+	fresh := ok && c.fresh(entry.FetchedAt, entry.Stale)
+	if ok {
+		cached, fetchedAt = entry.Tasks, entry.FetchedAt
+	}
+	c.mu.Unlock()
+
+	if fresh {
+		return ApplyQuery(cached, q), Freshness{FetchedAt: fetchedAt, Cached: true}, nil
+	}
+
+	now := time.Now()
+	tasks, err := c.graph.ListTasks(ctx, listID, q)
+	if err != nil {
+		if !ok {
+			return nil, Freshness{}, err
+		}
+		return ApplyQuery(cached, q), Freshness{FetchedAt: fetchedAt, Cached: true, RefreshErr: err}, nil
+	}
+	return tasks, Freshness{FetchedAt: now}, nil
+}
+
+// Task returns a single cached task without contacting Graph.
+func (c *Cache) Task(listID, taskID string) (*types.TodoTask, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return nil, false
+	}
+	entry := c.data.Tasks[listID]
+	if entry == nil {
+		return nil, false
+	}
+	for _, task := range entry.Tasks {
+		if task.ID == taskID {
+			return &task, true
+		}
+	}
+	return nil, false
+}
+
+func mergeLists(previous, changes []types.TodoTaskList) []types.TodoTaskList {
+	return merge(previous, changes, func(l types.TodoTaskList) (string, bool) { return l.ID, l.Removed != nil })
+}
+
+func mergeTasks(previous, changes []types.TodoTask) []types.TodoTask {
+	return merge(previous, changes, func(t types.TodoTask) (string, bool) { return t.ID, t.Removed != nil })
+}
+
+// merge applies delta changes to a previous set of items: changed items
+// replace their old version, new items are appended and removed items dropped.
+func merge[T any](previous, changes []T, key func(T) (id string, removed bool)) []T {
+	byID := map[string]int{}
+	merged := append([]T(nil), previous...)
+	for i, item := range merged {
+		id, _ := key(item)
+		byID[id] = i
+	}
+
+	removed := map[string]bool{}
+	for _, change := range changes {
+		id, isRemoved := key(change)
+		if isRemoved {
+			removed[id] = true
+			continue
+		}
+		if i, ok := byID[id]; ok {
+			merged[i] = change
+		} else {
+			byID[id] = len(merged)
+			merged = append(merged, change)
+		}
+	}
+
+	result := merged[:0]
+	for _, item := range merged {
+		if id, _ := key(item); !removed[id] {
+			result = append(result, item)
+		}
+	}
+	return result
+}
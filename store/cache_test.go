@@ -0,0 +1,311 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/auth"
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/graphtest"
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// testRetryPolicy retries like the default policy, without the long waits.
+var testRetryPolicy = client.RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+// newTestClient returns a client signed in to a fresh fake server.
+func newTestClient(t *testing.T) (*graphtest.Server, *client.GraphClient) {
+	t.Helper()
+	srv := graphtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	tm, err := auth.NewTokenManager("test-client", auth.WithAuthorityHost(srv.AuthorityHost()), auth.WithConfigDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewTokenManager: %v", err)
+	}
+	if err := tm.SaveTokens(srv.Tokens()); err != nil {
+		t.Fatalf("SaveTokens: %v", err)
+	}
+	return srv, client.NewGraphClient(tm, client.WithBaseURL(srv.GraphURL()), client.WithRetryPolicy(testRetryPolicy))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func titles(tasks []types.TodoTask) []string {
+	var result []string
+	for _, task := range tasks {
+		result = append(result, task.Title)
+	}
+	return result
+}
+
+func TestCacheServesFreshData(t *testing.T) {
+	srv, graph := newTestClient(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.json")
+	cache := store.NewCache(graph, path, time.Hour)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	srv.AddTask(list.ID, types.TodoTask{Title: "Buy milk"})
+
+	tasks, freshness, err := cache.Tasks(ctx, list.ID)
+	if err != nil {
+		t.Fatalf("Tasks: %v", err)
+	}
+	if len(tasks) != 1 || freshness.Cached {
+		t.Fatalf("first Tasks = %v, %+v; want one task from Graph", titles(tasks), freshness)
+	}
+	requests := len(srv.Requests())
+
+	srv.AddTask(list.ID, types.TodoTask{Title: "Call bank"})
+	tasks, freshness, err = cache.Tasks(ctx, list.ID)
+	if err != nil {
+		t.Fatalf("Tasks: %v", err)
+	}
+	if len(tasks) != 1 || !freshness.Cached || freshness.RefreshErr != nil {
+		t.Errorf("second Tasks = %v, %+v; want the cached task", titles(tasks), freshness)
+	}
+
+	// The cache outlives the process.
+	tasks, freshness, err = store.NewCache(graph, path, time.Hour).Tasks(ctx, list.ID)
+	if err != nil {
+		t.Fatalf("Tasks from a reopened cache: %v", err)
+	}
+	if len(tasks) != 1 || !freshness.Cached {
+		t.Errorf("Tasks from a reopened cache = %v, %+v", titles(tasks), freshness)
+	}
+	if got := len(srv.Requests()); got != requests {
+		t.Errorf("made %d requests for fresh data", got-requests)
+	}
+}
+
+func TestCacheRefreshesExpiredData(t *testing.T) {
+	srv, graph := newTestClient(t)
+	ctx := context.Background()
+	cache := store.NewCache(graph, filepath.Join(t.TempDir(), "cache.json"), time.Millisecond)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	milk := srv.AddTask(list.ID, types.TodoTask{Title: "Buy milk"})
+	srv.AddTask(list.ID, types.TodoTask{Title: "Call bank"})
+
+	if _, _, err := cache.Tasks(ctx, list.ID); err != nil {
+		t.Fatalf("Tasks: %v", err)
+	}
+	srv.ModifyTask(list.ID, milk.ID, func(task *types.TodoTask) { task.Title = "Buy oat milk" })
+	srv.AddTask(list.ID, types.TodoTask{Title: "Pay rent"})
+	time.Sleep(5 * time.Millisecond)
+
+	tasks, freshness, err := cache.Tasks(ctx, list.ID)
+	if err != nil {
+		t.Fatalf("Tasks: %v", err)
+	}
+	if freshness.Cached {
+		t.Errorf("expired data was served: %+v", freshness)
+	}
+	got := titles(tasks)
+	if len(got) != 3 || got[0] != "Buy oat milk" || got[1] != "Call bank" || got[2] != "Pay rent" {
+		t.Errorf("refreshed tasks = %v", got)
+	}
+
+	srv.SetOffline(true)
+	defer srv.SetOffline(false)
+	time.Sleep(5 * time.Millisecond)
+	tasks, freshness, err = cache.Tasks(ctx, list.ID)
+	if err != nil {
+		t.Fatalf("Tasks while offline: %v", err)
+	}
+	if len(tasks) != 3 || !freshness.Cached || freshness.RefreshErr == nil {
+		t.Errorf("Tasks while offline = %v, %+v; want the stale copy and the refresh error", titles(tasks), freshness)
+	}
+}
+
+func TestCacheResyncsWhenDeltaExpires(t *testing.T) {
+	srv, graph := newTestClient(t)
+	ctx := context.Background()
+	cache := store.NewCache(graph, filepath.Join(t.TempDir(), "cache.json"), time.Hour)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	milk := srv.AddTask(list.ID, types.TodoTask{Title: "Buy milk"})
+	srv.AddTask(list.ID, types.TodoTask{Title: "Call bank"})
+
+	if _, _, err := cache.Lists(ctx); err != nil {
+		t.Fatalf("Lists: %v", err)
+	}
+	if _, _, err := cache.Tasks(ctx, list.ID); err != nil {
+		t.Fatalf("Tasks: %v", err)
+	}
+	srv.ExpireDeltaLinks()
+	if err := graph.DeleteTask(ctx, list.ID, milk.ID); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if _, err := graph.CreateList(ctx, "Work"); err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+
+	tasks, freshness, err := cache.Tasks(ctx, list.ID)
+	if err != nil {
+		t.Fatalf("Tasks: %v", err)
+	}
+	if got := titles(tasks); len(got) != 1 || got[0] != "Call bank" || freshness.Cached {
+		t.Errorf("tasks after the delta link expired = %v, %+v", got, freshness)
+	}
+	lists, freshness, err := cache.Lists(ctx)
+	if err != nil {
+		t.Fatalf("Lists: %v", err)
+	}
+	if len(lists) != 2 || freshness.Cached {
+		t.Errorf("lists after the delta link expired = %+v, %+v", lists, freshness)
+	}
+}
+
+func TestCacheInvalidatedByWrites(t *testing.T) {
+	srv, graph := newTestClient(t)
+	ctx := context.Background()
+	cache := store.NewCache(graph, filepath.Join(t.TempDir(), "cache.json"), time.Hour)
+	home := srv.AddList(types.TodoTaskList{DisplayName: "Home"})
+	work := srv.AddList(types.TodoTaskList{DisplayName: "Work"})
+	milk := srv.AddTask(home.ID, types.TodoTask{Title: "Buy milk"})
+	srv.AddTask(work.ID, types.TodoTask{Title: "Report"})
+
+	if _, _, err := cache.Lists(ctx); err != nil {
+		t.Fatalf("Lists: %v", err)
+	}
+	if _, _, err := cache.AllTasks(ctx, []string{home.ID, work.ID}); err != nil {
+		t.Fatalf("AllTasks: %v", err)
+	}
+
+	if _, err := graph.UpdateTask(ctx, home.ID, milk.ID, client.TaskUpdate{Title: ptr("Buy oat milk")}); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	tasks, freshness, err := cache.Tasks(ctx, home.ID)
+	if err != nil {
+		t.Fatalf("Tasks: %v", err)
+	}
+	if got := titles(tasks); len(got) != 1 || got[0] != "Buy oat milk" || freshness.Cached {
+		t.Errorf("tasks after a write = %v, %+v", got, freshness)
+	}
+	if _, freshness, _ := cache.Tasks(ctx, work.ID); !freshness.Cached {
+		t.Error("a write to one list invalidated another")
+	}
+	if _, freshness, _ := cache.Lists(ctx); !freshness.Cached {
+		t.Error("a task write invalidated the lists")
+	}
+
+	if _, err := graph.CreateList(ctx, "Travel"); err != nil {
+		t.Fatalf("CreateList: %v", err)
+	}
+	lists, freshness, err := cache.Lists(ctx)
+	if err != nil {
+		t.Fatalf("Lists: %v", err)
+	}
+	if len(lists) != 3 || freshness.Cached {
+		t.Errorf("lists after creating one = %+v, %+v", lists, freshness)
+	}
+}
+
+func TestCacheKeepsTaskChildren(t *testing.T) {
+	srv, graph := newTestClient(t)
+	ctx := context.Background()
+	cache := store.NewCache(graph, filepath.Join(t.TempDir(), "cache.json"), time.Hour)
+	home := srv.AddList(types.TodoTaskList{DisplayName: "Home"})
+	work := srv.AddList(types.TodoTaskList{DisplayName: "Work"})
+	trip := srv.AddTask(home.ID, types.TodoTask{
+		Title:           "Plan trip",
+		ChecklistItems:  []types.ChecklistItem{{DisplayName: "Passport"}},
+		LinkedResources: []types.LinkedResource{{WebURL: "https://example.com/flights"}},
+	})
+	srv.AddTask(work.ID, types.TodoTask{
+		Title:          "Report",
+		ChecklistItems: []types.ChecklistItem{{DisplayName: "Outline"}, {DisplayName: "Draft"}},
+	})
+
+	tasks, _, err := cache.Tasks(ctx, home.ID)
+	if err != nil {
+		t.Fatalf("Tasks: %v", err)
+	}
+	if len(tasks) != 1 || len(tasks[0].ChecklistItems) != 1 || len(tasks[0].LinkedResources) != 1 {
+		t.Fatalf("cached task = %+v, want its checklist item and link", tasks)
+	}
+
+	if _, err := graph.CreateChecklistItem(ctx, home.ID, trip.ID, "Tickets"); err != nil {
+		t.Fatalf("CreateChecklistItem: %v", err)
+	}
+	all, _, err := cache.AllTasks(ctx, []string{home.ID, work.ID})
+	if err != nil {
+		t.Fatalf("AllTasks: %v", err)
+	}
+	if got := all[home.ID]; len(got) != 1 || len(got[0].ChecklistItems) != 2 || len(got[0].LinkedResources) != 1 {
+		t.Errorf("home tasks after adding a step = %+v", got)
+	}
+	if got := all[work.ID]; len(got) != 1 || len(got[0].ChecklistItems) != 2 {
+		t.Errorf("work tasks = %+v", got)
+	}
+
+	task, ok := cache.Task(home.ID, trip.ID)
+	if !ok || len(task.ChecklistItems) != 2 {
+		t.Errorf("Task = %+v, %v", task, ok)
+	}
+}
+
+func TestCacheExpandsLargeListsWithoutFanOut(t *testing.T) {
+	srv, graph := newTestClient(t)
+	cache := store.NewCache(graph, filepath.Join(t.TempDir(), "cache.json"), time.Hour)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Backlog"})
+	for i := range 30 {
+		srv.AddTask(list.ID, types.TodoTask{Title: fmt.Sprintf("Task %d", i)})
+	}
+	srv.AddTask(list.ID, types.TodoTask{Title: "Plan trip", ChecklistItems: []types.ChecklistItem{{DisplayName: "Passport"}}})
+
+	tasks, _, err := cache.Tasks(context.Background(), list.ID)
+	if err != nil {
+		t.Fatalf("Tasks: %v", err)
+	}
+	if len(tasks) != 31 || len(tasks[30].ChecklistItems) != 1 {
+		t.Fatalf("cached tasks = %d, last %+v; want the checklist item expanded", len(tasks), tasks[len(tasks)-1])
+	}
+	for _, request := range srv.Requests() {
+		if strings.Contains(request, "$batch") || strings.Contains(request, "/checklistItems") {
+			t.Errorf("request %s; want the children listed with the tasks, not per task", request)
+		}
+	}
+}
+
+func TestQueryTasks(t *testing.T) {
+	srv, graph := newTestClient(t)
+	ctx := context.Background()
+	cache := store.NewCache(graph, filepath.Join(t.TempDir(), "cache.json"), time.Hour)
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	srv.AddTask(list.ID, types.TodoTask{Title: "Buy milk", Categories: []string{"Errands"}})
+	srv.AddTask(list.ID, types.TodoTask{Title: "Call bank", Importance: "high"})
+	srv.AddTask(list.ID, types.TodoTask{Title: "Pay rent", Categories: []string{"Finance"}})
+	query := client.TaskQuery{Category: "Finance", Top: 1}
+
+	// Without a cached copy, the query goes to Graph.
+	tasks, freshness, err := cache.QueryTasks(ctx, list.ID, query)
+	if err != nil {
+		t.Fatalf("QueryTasks: %v", err)
+	}
+	if got := titles(tasks); len(got) != 1 || got[0] != "Pay rent" || freshness.Cached {
+		t.Errorf("QueryTasks from Graph = %v, %+v", got, freshness)
+	}
+
+	// With a fresh copy, it is answered locally.
+	if _, _, err := cache.Tasks(ctx, list.ID); err != nil {
+		t.Fatalf("Tasks: %v", err)
+	}
+	requests := len(srv.Requests())
+	tasks, freshness, err = cache.QueryTasks(ctx, list.ID, query)
+	if err != nil {
+		t.Fatalf("QueryTasks: %v", err)
+	}
+	if got := titles(tasks); len(got) != 1 || got[0] != "Pay rent" || !freshness.Cached {
+		t.Errorf("QueryTasks from the cache = %v, %+v", got, freshness)
+	}
+	if got := len(srv.Requests()); got != requests {
+		t.Errorf("made %d requests for a query on fresh data", got-requests)
+	}
+}
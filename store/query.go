@@ -0,0 +1,152 @@
+package store
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// ApplyQuery evaluates a task query against cached tasks, mirroring what
+// Graph does with the equivalent OData options. Like Graph, it compares
+// categories exactly, including case.
+func ApplyQuery(tasks []types.TodoTask, q client.TaskQuery) []types.TodoTask {
+	var result []types.TodoTask
+	for _, task := range tasks {
+		if matches(task, q) {
+			result = append(result, task)
+		}
+	}
+
+	if q.OrderBy != "" {
+		sort.SliceStable(result, func(i, j int) bool {
+			if q.Descending {
+				return less(result[j], result[i], q.OrderBy)
+			}
+			return less(result[i], result[j], q.OrderBy)
+		})
+	}
+
+	if q.Top > 0 && len(result) > q.Top {
+		result = result[:q.Top]
+	}
+	if len(q.Select) > 0 {
+		for i := range result {
+			result[i] = selectFields(result[i], q.Select)
+		}
+	}
+	return result
+}
+
+// selectFields keeps only the ID and the named JSON properties of a task.
+func selectFields(task types.TodoTask, fields []string) types.TodoTask {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return task
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return task
+	}
+
+	kept := map[string]json.RawMessage{"id": all["id"]}
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			kept[field] = value
+		}
+	}
+
+	data, err = json.Marshal(kept)
+	if err != nil {
+		return task
+	}
+	var selected types.TodoTask
+	if err := json.Unmarshal(data, &selected); err != nil {
+		return task
+	}
+	return selected
+}
+
+func matches(task types.TodoTask, q client.TaskQuery) bool {
+	if q.Status != "" && task.Status != q.Status {
+		return false
+	}
+	if q.NotStatus != "" && task.Status == q.NotStatus {
+		return false
+	}
+	if q.Importance != "" && task.Importance != q.Importance {
+		return false
+	}
+	if q.Category != "" && !slices.Contains(task.Categories, q.Category) {
+		return false
+	}
+	if q.DueBefore != nil || q.DueAfter != nil {
+		due, ok := utcDateTime(task.DueDateTime)
+		if !ok {
+			return false
+		}
+		if q.DueBefore != nil && !due.Before(q.DueBefore.UTC()) {
+			return false
+		}
+		if q.DueAfter != nil && !due.After(q.DueAfter.UTC()) {
+			return false
+		}
+	}
+	return true
+}
+
+var importanceRank = map[string]int{"low": 0, "normal": 1, "high": 2}
+
+func less(a, b types.TodoTask, field string) bool {
+	switch field {
+	case "due":
+		return dateTimeString(a.DueDateTime) < dateTimeString(b.DueDateTime)
+	case "start":
+		return dateTimeString(a.StartDateTime) < dateTimeString(b.StartDateTime)
+	case "importance":
+		return importanceRank[a.Importance] < importanceRank[b.Importance]
+	case "title":
+		return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+	case "status":
+		return a.Status < b.Status
+	case "created":
+		return timeOrZero(a.CreatedDateTime).Before(timeOrZero(b.CreatedDateTime))
+	case "modified":
+		return timeOrZero(a.LastModifiedDateTime).Before(timeOrZero(b.LastModifiedDateTime))
+	}
+	return false
+}
+
+func dateTimeString(dt *types.DateTimeZone) string {
+	if dt == nil {
+		return ""
+	}
+	return dt.DateTime
+}
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// utcDateTime parses a Graph date/time, which To-Do reports in UTC.
+func utcDateTime(dt *types.DateTimeZone) (time.Time, bool) {
+	if dt == nil {
+		return time.Time{}, false
+	}
+	value, _, _ := strings.Cut(dt.DateTime, ".")
+	t, err := time.Parse("2006-01-02T15:04:05", value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if loc, err := time.LoadLocation(dt.TimeZone); err == nil && dt.TimeZone != "" {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+	}
+	return t.UTC(), true
+}
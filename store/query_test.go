@@ -0,0 +1,63 @@
+package store_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+func TestApplyQuery(t *testing.T) {
+	due := func(dateTime string) *types.DateTimeZone {
+		return &types.DateTimeZone{DateTime: dateTime, TimeZone: "UTC"}
+	}
+	tasks := []types.TodoTask{
+		{ID: "1", Title: "Buy milk", Status: "notStarted", Importance: "normal", Categories: []string{"Errands"}, DueDateTime: due("2025-03-02T00:00:00.0000000")},
+		{ID: "2", Title: "call bank", Status: "completed", Importance: "high"},
+		{ID: "3", Title: "Expenses", Status: "notStarted", Importance: "low", Categories: []string{"Finance"}, DueDateTime: due("2025-03-05T00:00:00.0000000")},
+		{ID: "4", Title: "Pay rent", Status: "inProgress", Importance: "high", Categories: []string{"Finance", "Home"}, DueDateTime: due("2025-03-01T00:00:00.0000000")},
+	}
+	march3 := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		query client.TaskQuery
+		want  []string
+	}{
+		{"everything", client.TaskQuery{}, []string{"Buy milk", "call bank", "Expenses", "Pay rent"}},
+		{"status", client.TaskQuery{Status: "completed"}, []string{"call bank"}},
+		{"open", client.TaskQuery{NotStatus: "completed"}, []string{"Buy milk", "Expenses", "Pay rent"}},
+		{"importance", client.TaskQuery{Importance: "high"}, []string{"call bank", "Pay rent"}},
+		{"category", client.TaskQuery{Category: "Finance"}, []string{"Expenses", "Pay rent"}},
+		{"category is case-sensitive", client.TaskQuery{Category: "finance"}, nil},
+		{"due before", client.TaskQuery{DueBefore: &march3}, []string{"Buy milk", "Pay rent"}},
+		{"due after", client.TaskQuery{DueAfter: &march3}, []string{"Expenses"}},
+		{"order by due", client.TaskQuery{OrderBy: "due", NotStatus: "completed"}, []string{"Pay rent", "Buy milk", "Expenses"}},
+		{"order by title", client.TaskQuery{OrderBy: "title"}, []string{"Buy milk", "call bank", "Expenses", "Pay rent"}},
+		{"order by importance descending", client.TaskQuery{OrderBy: "importance", Descending: true}, []string{"call bank", "Pay rent", "Buy milk", "Expenses"}},
+		{"top", client.TaskQuery{OrderBy: "due", Descending: true, Top: 2}, []string{"Expenses", "Buy milk"}},
+		{"category before top", client.TaskQuery{Category: "Finance", Top: 1}, []string{"Expenses"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := titles(store.ApplyQuery(tasks, tt.query)); !slices.Equal(got, tt.want) {
+				t.Errorf("ApplyQuery = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyQuerySelect(t *testing.T) {
+	tasks := []types.TodoTask{{ID: "1", Title: "Buy milk", Status: "notStarted", Importance: "high"}}
+
+	got := store.ApplyQuery(tasks, client.TaskQuery{Select: []string{"title"}})
+	if len(got) != 1 || got[0].ID != "1" || got[0].Title != "Buy milk" || got[0].Status != "" || got[0].Importance != "" {
+		t.Errorf("ApplyQuery with select = %+v, want only the ID and title", got)
+	}
+	if tasks[0].Status != "notStarted" {
+		t.Errorf("ApplyQuery modified its input: %+v", tasks[0])
+	}
+}
@@ -0,0 +1,562 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/internal/atomicfile"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// Op is the kind of write held in the queue.
+type Op string
+
+const (
+	OpCreate   Op = "create"
+	OpUpdate   Op = "update"
+	OpComplete Op = "complete"
+	OpDelete   Op = "delete"
+)
+
+// EntryState is where a queued write stands.
+type EntryState string
+
+const (
+	// StatePending writes are applied on the next replay.
+	StatePending EntryState = "pending"
+
+	// StateConflict writes were not applied because the task changed on the
+	// server after the write was queued. They stay in the queue until
+	// discarded or forced.
+	StateConflict EntryState = "conflict"
+
+	// StateFailed writes were rejected by Graph. They stay in the queue
+	// until discarded or forced.
+	StateFailed EntryState = "failed"
+)
+
+// Entry is a write made while Graph was unreachable.
+type Entry struct {
+	ID       string    `json:"id"`
+	Op       Op        `json:"op"`
+	ListID   string    `json:"list_id"`
+	TaskID   string    `json:"task_id,omitempty"`
+	Title    string    `json:"title,omitempty"` // for display; the task may not exist yet
+	QueuedAt time.Time `json:"queued_at"`
+
+	Create *client.NewTask    `json:"create,omitempty"`
+	Update *client.TaskUpdate `json:"update,omitempty"`
+
+	// BaseModified is the task's lastModifiedDateTime as last seen before
+	// the write was queued. A newer server copy means a conflict. It is nil
+	// when the task was neither cached nor reachable; see BaseUnknown.
+	BaseModified *time.Time `json:"base_modified,omitempty"`
+
+	State EntryState `json:"state"`
+	Error string     `json:"error,omitempty"`
+
+	// Force applies the write on the next replay even if it conflicts.
+	Force bool `json:"force,omitempty"`
+}
+
+// BaseUnknown reports whether the write has no known version of its task
+// to detect conflicts against, so that it overwrites changes made
+// elsewhere.
+func (e Entry) BaseUnknown() bool {
+	return e.Op != OpCreate && e.BaseModified == nil
+}
+
+// Describe returns a one-line summary of the write.
+func (e Entry) Describe() string {
+	name := e.Title
+	if name == "" {
+		name = e.TaskID
+	}
+	switch e.Op {
+	case OpCreate:
+		return fmt.Sprintf("create task \"%s\" in list `%s`", name, e.ListID)
+	case OpUpdate:
+		return fmt.Sprintf("update task \"%s\"", name)
+	case OpComplete:
+		return fmt.Sprintf("complete task \"%s\"", name)
+	case OpDelete:
+		return fmt.Sprintf("delete task \"%s\"", name)
+	}
+	return string(e.Op)
+}
+
+type queueFile struct {
+	NextID  int     `json:"next_id"`
+	Entries []Entry `json:"entries"`
+}
+
+// Queue holds writes made while Graph was unreachable, persisted as JSON,
+// and replays them in order once it can be reached again.
+type Queue struct {
+	graph *client.GraphClient
+	cache *Cache
+	path  string
+
+	mu sync.Mutex
+}
+
+// NewQueue creates a queue persisted at path. The cache supplies the last
+// known version of tasks, against which conflicts are detected.
+func NewQueue(graph *client.GraphClient, cache *Cache, path string) *Queue {
+	return &Queue{graph: graph, cache: cache, path: path}
+}
+
+// load reads the queue file. Callers must hold q.mu.
+func (q *Queue) load() (*queueFile, error) {
+	data, err := os.ReadFile(q.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &queueFile{NextID: 1}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading write queue: %w", err)
+	}
+
+	var file queueFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		// Unlike the cache, queued writes cannot be fetched again.
+		return nil, fmt.Errorf("parsing write queue %s: %w", q.path, err)
+	}
+	return &file, nil
+}
+
+// save writes the queue file. Callers must hold q.mu.
+func (q *Queue) save(file *queueFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling write queue: %w", err)
+	}
+	if err := atomicfile.WriteFile(q.path, data, 0600); err != nil {
+		return fmt.Errorf("writing write queue: %w", err)
+	}
+	return nil
+}
+
+// Entries returns the queued writes in the order they will be replayed.
+func (q *Queue) Entries() ([]Entry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	file, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+	return file.Entries, nil
+}
+
+// Result is the outcome of Submit.
+type Result struct {
+	// Task is the task as returned by Graph, or nil for a delete or a
+	// queued write.
+	Task *types.TodoTask
+
+	// Queued is set when Graph could not be reached and the write was
+	// queued instead.
+	Queued *Entry
+}
+
+// Submit applies a write, or queues it if Graph cannot be reached. Writes
+// already in the queue are replayed first so they keep their order; if any
+// of them is still waiting, the new write is queued behind it. A create cut
+// off after it was sent is not queued; its error wraps ErrMaybeApplied.
+func (q *Queue) Submit(ctx context.Context, entry Entry) (*Result, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	file, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+
+	q.fillFromCache(&entry)
+
+	if len(file.Entries) > 0 {
+		report, err := q.replay(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		if modified, ok := report.modified[entry.TaskID]; ok {
+			entry.BaseModified = modified
+		}
+		if report.Offline {
+			return q.enqueue(file, entry)
+		}
+		if q.waiting(file, entry.TaskID) {
+			q.fetchBase(ctx, &entry)
+			return q.enqueue(file, entry)
+		}
+	}
+
+	task, err := q.apply(ctx, entry)
+	if queueable(entry, err) {
+		return q.enqueue(file, entry)
+	}
+	if err != nil {
+		return nil, maybeApplied(entry, err)
+	}
+	return &Result{Task: task}, nil
+}
+
+// ErrMaybeApplied is wrapped by the error of a create whose connection
+// failed after the request was sent. Graph may have created the task, so
+// the create is neither queued nor replayed again.
+var ErrMaybeApplied = errors.New("the connection failed after the task was sent, so it may have been created")
+
+// queueable reports whether a write that failed with err can be queued and
+// replayed later. Repeating an update, completion or delete does no harm,
+// but a create is only queued if it certainly never reached Graph, so that
+// replaying it cannot create the task twice.
+func queueable(entry Entry, err error) bool {
+	if entry.Op == OpCreate {
+		return client.IsNotSent(err)
+	}
+	return client.IsUnreachable(err)
+}
+
+// ignoreDeleted returns nil instead of the "not found" error of a delete,
+// since the task is gone either way.
+func ignoreDeleted(entry Entry, err error) error {
+	if entry.Op == OpDelete && client.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// maybeApplied wraps the error of a write that cannot be queued with
+// ErrMaybeApplied if the write may have been carried out.
+func maybeApplied(entry Entry, err error) error {
+	if entry.Op == OpCreate && client.IsUnreachable(err) && !client.IsNotSent(err) {
+		return fmt.Errorf("%w: %w", ErrMaybeApplied, err)
+	}
+	return err
+}
+
+// fillFromCache records the last known version of the task an entry
+// writes to, for conflict detection and display.
+func (q *Queue) fillFromCache(entry *Entry) {
+	if entry.Op == OpCreate || entry.BaseModified != nil {
+		return
+	}
+	if task, ok := q.cache.Task(entry.ListID, entry.TaskID); ok {
+		entry.BaseModified = task.LastModifiedDateTime
+		if entry.Title == "" {
+			entry.Title = task.Title
+		}
+	}
+}
+
+// fetchBase records the server's current version of the task as the base
+// of an entry queued while Graph is reachable, if the cache did not have
+// it. An entry left without a base overwrites changes made elsewhere.
+func (q *Queue) fetchBase(ctx context.Context, entry *Entry) {
+	if entry.Op == OpCreate || entry.BaseModified != nil {
+		return
+	}
+	if task, err := q.graph.GetTask(ctx, entry.ListID, entry.TaskID); err == nil {
+		entry.BaseModified = task.LastModifiedDateTime
+		if entry.Title == "" {
+			entry.Title = task.Title
+		}
+	}
+}
+
+// Outcome is the result of one write submitted with SubmitBatch.
+type Outcome struct {
+	Result
+	Err error
+}
+
+// SubmitBatch is Submit for several writes at once. The writes that can be
+// applied now are passed together to apply, which returns one result per
+// entry, typically using one of the batched client methods. Each write is
+// applied, queued or failed on its own; the outcomes are in the same order
+// as entries. Entries are updated in place with the title and last known
+// modification time of their task, where the cache has them.
+func (q *Queue) SubmitBatch(ctx context.Context, entries []Entry, apply func(ctx context.Context, entries []Entry) []client.TaskResult) ([]Outcome, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	file, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		q.fillFromCache(&entries[i])
+	}
+
+	outcomes := make([]Outcome, len(entries))
+	enqueue := func(i int) error {
+		result, err := q.enqueue(file, entries[i])
+		if err != nil {
+			return err
+		}
+		outcomes[i].Result = *result
+		return nil
+	}
+
+	offline := false
+	if len(file.Entries) > 0 {
+		report, err := q.replay(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		offline = report.Offline
+		for i := range entries {
+			if modified, ok := report.modified[entries[i].TaskID]; ok {
+				entries[i].BaseModified = modified
+			}
+		}
+	}
+
+	var direct []int
+	for i, entry := range entries {
+		if offline || q.waiting(file, entry.TaskID) {
+			if !offline {
+				q.fetchBase(ctx, &entries[i])
+			}
+			if err := enqueue(i); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		direct = append(direct, i)
+	}
+	if len(direct) == 0 {
+		return outcomes, nil
+	}
+
+	batch := make([]Entry, len(direct))
+	for j, i := range direct {
+		batch[j] = entries[i]
+	}
+	for j, result := range apply(ctx, batch) {
+		i := direct[j]
+		switch {
+		case queueable(entries[i], result.Err):
+			if err := enqueue(i); err != nil {
+				return nil, err
+			}
+		case ignoreDeleted(entries[i], result.Err) != nil:
+			outcomes[i].Err = maybeApplied(entries[i], result.Err)
+		default:
+			outcomes[i].Task = result.Task
+		}
+	}
+	return outcomes, nil
+}
+
+// waiting reports whether an earlier write to the task is still queued, in
+// which case a new write must queue behind it.
+func (q *Queue) waiting(file *queueFile, taskID string) bool {
+	if taskID == "" {
+		return false
+	}
+	for _, queued := range file.Entries {
+		if queued.TaskID == taskID {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue appends entry to the queue and saves it. Callers must hold q.mu.
+func (q *Queue) enqueue(file *queueFile, entry Entry) (*Result, error) {
+	entry.ID = fmt.Sprintf("q%d", file.NextID)
+	entry.State = StatePending
+	entry.QueuedAt = time.Now()
+	file.NextID++
+	file.Entries = append(file.Entries, entry)
+	if err := q.save(file); err != nil {
+		return nil, err
+	}
+	return &Result{Queued: &entry}, nil
+}
+
+// ReplayReport summarizes a replay.
+type ReplayReport struct {
+	Applied   []Entry
+	Conflicts []Entry
+	Failed    []Entry
+
+	// Remaining is the number of writes still queued.
+	Remaining int
+
+	// Offline is set when the replay stopped because Graph was unreachable.
+	Offline bool
+
+	// modified holds the lastModifiedDateTime of tasks written by the replay.
+	modified map[string]*time.Time
+}
+
+// Replay applies the queued writes in order. A write that conflicts with a
+// newer server copy of its task, or that Graph rejects, is kept in the
+// queue and holds back later writes to the same task, as is a create cut
+// off after it was sent. Replay stops at the first write that cannot reach
+// Graph.
+func (q *Queue) Replay(ctx context.Context) (*ReplayReport, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	file, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Entries) == 0 {
+		return &ReplayReport{}, nil
+	}
+	return q.replay(ctx, file)
+}
+
+// replay does the work of Replay. Callers must hold q.mu.
+func (q *Queue) replay(ctx context.Context, file *queueFile) (*ReplayReport, error) {
+	report := &ReplayReport{modified: map[string]*time.Time{}}
+	held := map[string]bool{} // tasks with an earlier write still queued
+	var remaining []Entry
+
+	for i, entry := range file.Entries {
+		if entry.TaskID != "" && held[entry.TaskID] {
+			remaining = append(remaining, entry)
+			continue
+		}
+		if entry.State != StatePending && !entry.Force {
+			held[entry.TaskID] = true
+			remaining = append(remaining, entry)
+			continue
+		}
+
+		// A write applied earlier in this replay is not a conflict.
+		if modified, ok := report.modified[entry.TaskID]; ok {
+			entry.BaseModified = modified
+		}
+
+		task, err := q.replayEntry(ctx, entry)
+		err = maybeApplied(entry, err)
+		switch {
+		case err == nil:
+			report.Applied = append(report.Applied, entry)
+			if task != nil && entry.TaskID != "" {
+				report.modified[entry.TaskID] = task.LastModifiedDateTime
+			}
+			continue
+		case client.IsUnreachable(err) && !errors.Is(err, ErrMaybeApplied):
+			report.Offline = true
+			// Writes applied in this replay are not conflicts next time
+			// either, so the entries put back take their new base.
+			for _, rest := range file.Entries[i:] {
+				if modified, ok := report.modified[rest.TaskID]; ok {
+					rest.BaseModified = modified
+				}
+				remaining = append(remaining, rest)
+			}
+		case errors.Is(err, errConflict):
+			entry.State = StateConflict
+			entry.Error = err.Error()
+			report.Conflicts = append(report.Conflicts, entry)
+			remaining = append(remaining, entry)
+		default:
+			entry.State = StateFailed
+			entry.Error = err.Error()
+			entry.Force = false
+			report.Failed = append(report.Failed, entry)
+			remaining = append(remaining, entry)
+		}
+		if report.Offline {
+			break
+		}
+		held[entry.TaskID] = true
+	}
+
+	file.Entries = remaining
+	report.Remaining = len(remaining)
+	if err := q.save(file); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+var errConflict = errors.New("task was changed on the server after this change was queued")
+
+// replayEntry checks a queued write for conflicts and applies it.
+func (q *Queue) replayEntry(ctx context.Context, entry Entry) (*types.TodoTask, error) {
+	if entry.Op != OpCreate && !entry.Force {
+		current, err := q.graph.GetTask(ctx, entry.ListID, entry.TaskID)
+		if err != nil && !(entry.Op == OpDelete && client.IsNotFound(err)) {
+			return nil, err
+		}
+		if current != nil && entry.BaseModified != nil && current.LastModifiedDateTime != nil &&
+			current.LastModifiedDateTime.After(*entry.BaseModified) {
+			return nil, fmt.Errorf("%w (server copy modified %s)", errConflict, current.LastModifiedDateTime.Format(time.RFC3339))
+		}
+	}
+	return q.apply(ctx, entry)
+}
+
+// apply performs the write against Graph. Deleting a task that is already
+// gone succeeds, since an earlier attempt may have deleted it before its
+// response was lost.
+func (q *Queue) apply(ctx context.Context, entry Entry) (*types.TodoTask, error) {
+	switch entry.Op {
+	case OpCreate:
+		if entry.Create == nil {
+			return nil, fmt.Errorf("queued create has no task")
+		}
+		return q.graph.CreateTask(ctx, entry.ListID, *entry.Create)
+	case OpUpdate:
+		if entry.Update == nil {
+			return nil, fmt.Errorf("queued update has no fields")
+		}
+		return q.graph.UpdateTask(ctx, entry.ListID, entry.TaskID, *entry.Update)
+	case OpComplete:
+		return q.graph.CompleteTask(ctx, entry.ListID, entry.TaskID)
+	case OpDelete:
+		return nil, ignoreDeleted(entry, q.graph.DeleteTask(ctx, entry.ListID, entry.TaskID))
+	}
+	return nil, fmt.Errorf("unknown queued operation %q", entry.Op)
+}
+
+// Discard removes a write from the queue without applying it.
+func (q *Queue) Discard(id string) (*Entry, error) {
+	return q.modify(id, func(file *queueFile, i int) {
+		file.Entries = append(file.Entries[:i], file.Entries[i+1:]...)
+	})
+}
+
+// Force marks a write to be applied on the next replay even if the task
+// changed on the server in the meantime, overwriting that change.
+func (q *Queue) Force(id string) (*Entry, error) {
+	return q.modify(id, func(file *queueFile, i int) {
+		file.Entries[i].Force = true
+		file.Entries[i].State = StatePending
+		file.Entries[i].Error = ""
+	})
+}
+
+func (q *Queue) modify(id string, change func(file *queueFile, i int)) (*Entry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	file, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range file.Entries {
+		if entry.ID == id {
+			change(file, i)
+			if err := q.save(file); err != nil {
+				return nil, err
+			}
+			return &entry, nil
+		}
+	}
+	return nil, fmt.Errorf("no queued change with ID %s", id)
+}
@@ -0,0 +1,348 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/graphtest"
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// newTestQueue returns a queue, and the cache it uses, writing to a fresh
+// fake server.
+func newTestQueue(t *testing.T) (*graphtest.Server, *client.GraphClient, *store.Queue) {
+	t.Helper()
+	srv, graph := newTestClient(t)
+	dir := t.TempDir()
+	cache := store.NewCache(graph, filepath.Join(dir, "cache.json"), time.Hour)
+	return srv, graph, store.NewQueue(graph, cache, filepath.Join(dir, "queue.json"))
+}
+
+func createEntry(listID, title string) store.Entry {
+	return store.Entry{Op: store.OpCreate, ListID: listID, Title: title, Create: &client.NewTask{Title: title}}
+}
+
+func TestSubmitQueuesWritesWhileOffline(t *testing.T) {
+	srv, _, queue := newTestQueue(t)
+	ctx := context.Background()
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	task := srv.AddTask(list.ID, types.TodoTask{Title: "Call bank"})
+
+	srv.SetOffline(true)
+	for _, entry := range []store.Entry{
+		createEntry(list.ID, "Written offline"),
+		{Op: store.OpComplete, ListID: list.ID, TaskID: task.ID},
+	} {
+		result, err := queue.Submit(ctx, entry)
+		if err != nil {
+			t.Fatalf("Submit %s: %v", entry.Op, err)
+		}
+		if result.Queued == nil {
+			t.Errorf("Submit %s = %+v, want it queued", entry.Op, result)
+		}
+	}
+
+	srv.SetOffline(false)
+	report, err := queue.Replay(ctx)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(report.Applied) != 2 || report.Remaining != 0 {
+		t.Errorf("Replay = %+v, want both writes applied", report)
+	}
+	tasks := srv.Tasks(list.ID)
+	if len(tasks) != 2 || tasks[0].Status != "completed" || tasks[1].Title != "Written offline" {
+		t.Errorf("tasks after replay = %+v", tasks)
+	}
+}
+
+func TestSubmitDoesNotQueueCreatesThatMayHaveBeenSent(t *testing.T) {
+	srv, _, queue := newTestQueue(t)
+	ctx := context.Background()
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	task := srv.AddTask(list.ID, types.TodoTask{Title: "Call bank"})
+
+	// The task is created, but the connection drops before the answer.
+	srv.Fail(graphtest.Failure{Method: "POST", Disconnect: true, AfterProcessing: true, Times: 1})
+	_, err := queue.Submit(ctx, createEntry(list.ID, "Lost answer"))
+	if !errors.Is(err, store.ErrMaybeApplied) {
+		t.Fatalf("Submit error = %v, want ErrMaybeApplied", err)
+	}
+
+	// An update that cannot get through is queued: repeating it is harmless.
+	srv.Fail(graphtest.Failure{Method: "PATCH", Disconnect: true})
+	result, err := queue.Submit(ctx, store.Entry{Op: store.OpUpdate, ListID: list.ID, TaskID: task.ID, Update: &client.TaskUpdate{Title: ptr("Call the bank")}})
+	if err != nil || result.Queued == nil {
+		t.Errorf("Submit update = %+v, %v; want it queued", result, err)
+	}
+	srv.ClearFailures()
+
+	entries, err := queue.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Op != store.OpUpdate {
+		t.Errorf("queued entries = %+v, want only the update", entries)
+	}
+	if _, err := queue.Replay(ctx); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if tasks := srv.Tasks(list.ID); len(tasks) != 2 || tasks[0].Title != "Call the bank" || tasks[1].Title != "Lost answer" {
+		t.Errorf("tasks = %+v, want the update applied and the task created once", tasks)
+	}
+}
+
+func TestSubmitBatchDoesNotQueueCreatesThatMayHaveBeenSent(t *testing.T) {
+	srv, graph, queue := newTestQueue(t)
+	ctx := context.Background()
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	create := func(ctx context.Context, entries []store.Entry) []client.TaskResult {
+		tasks := make([]client.NewTask, len(entries))
+		for i, entry := range entries {
+			tasks[i] = *entry.Create
+		}
+		return graph.CreateTasks(ctx, list.ID, tasks)
+	}
+
+	srv.Fail(graphtest.Failure{Method: "POST", Path: "/me/todo/lists", Disconnect: true, AfterProcessing: true, Times: 1})
+	outcomes, err := queue.SubmitBatch(ctx, []store.Entry{createEntry(list.ID, "One"), createEntry(list.ID, "Two")}, create)
+	if err != nil {
+		t.Fatalf("SubmitBatch: %v", err)
+	}
+	for i, outcome := range outcomes {
+		if outcome.Queued != nil || !errors.Is(outcome.Err, store.ErrMaybeApplied) {
+			t.Errorf("outcome %d = %+v, want ErrMaybeApplied", i, outcome)
+		}
+	}
+
+	srv.SetOffline(true)
+	outcomes, err = queue.SubmitBatch(ctx, []store.Entry{createEntry(list.ID, "Three")}, create)
+	if err != nil {
+		t.Fatalf("SubmitBatch: %v", err)
+	}
+	if outcomes[0].Queued == nil {
+		t.Errorf("outcome while offline = %+v, want it queued", outcomes[0])
+	}
+}
+
+func TestReplayHoldsCreatesThatMayHaveBeenSent(t *testing.T) {
+	srv, _, queue := newTestQueue(t)
+	ctx := context.Background()
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+
+	srv.SetOffline(true)
+	if _, err := queue.Submit(ctx, createEntry(list.ID, "Written offline")); err != nil {
+		t.Fatal(err)
+	}
+	srv.SetOffline(false)
+
+	srv.Fail(graphtest.Failure{Method: "POST", Disconnect: true, AfterProcessing: true, Times: 1})
+	report, err := queue.Replay(ctx)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(report.Failed) != 1 || report.Offline || report.Remaining != 1 {
+		t.Fatalf("Replay = %+v, want the create held as failed", report)
+	}
+
+	if report, err = queue.Replay(ctx); err != nil || len(report.Applied) != 0 {
+		t.Errorf("second Replay = %+v, %v; want nothing applied", report, err)
+	}
+	if tasks := srv.Tasks(list.ID); len(tasks) != 1 {
+		t.Errorf("tasks = %+v, want the task created once", tasks)
+	}
+}
+
+func TestSubmitDeleteWhenResponseIsLost(t *testing.T) {
+	srv, graph, queue := newTestQueue(t)
+	ctx := context.Background()
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	one := srv.AddTask(list.ID, types.TodoTask{Title: "One"})
+	two := srv.AddTask(list.ID, types.TodoTask{Title: "Two"})
+
+	// The DELETE succeeds, but its response is lost; the retry gets a 404.
+	srv.Fail(graphtest.Failure{Method: "DELETE", Status: 503, Times: 1, AfterProcessing: true})
+	result, err := queue.Submit(ctx, store.Entry{Op: store.OpDelete, ListID: list.ID, TaskID: one.ID})
+	if err != nil || result.Queued != nil {
+		t.Errorf("Submit delete = %+v, %v; want it applied", result, err)
+	}
+
+	if err := graph.DeleteTask(ctx, list.ID, two.ID); err != nil {
+		t.Fatal(err)
+	}
+	outcomes, err := queue.SubmitBatch(ctx, []store.Entry{{Op: store.OpDelete, ListID: list.ID, TaskID: two.ID}},
+		func(ctx context.Context, entries []store.Entry) []client.TaskResult {
+			return graph.DeleteTasks(ctx, list.ID, []string{entries[0].TaskID})
+		})
+	if err != nil || outcomes[0].Err != nil || outcomes[0].Queued != nil {
+		t.Errorf("SubmitBatch delete of a deleted task = %+v, %v; want it applied", outcomes, err)
+	}
+}
+
+func TestReplayKeepsBaseOfWritesPutBack(t *testing.T) {
+	srv, _, queue := newTestQueue(t)
+	ctx := context.Background()
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	task := srv.AddTask(list.ID, types.TodoTask{Title: "Call bank"})
+
+	srv.SetOffline(true)
+	for _, title := range []string{"Call the bank", "Call the bank today"} {
+		entry := store.Entry{Op: store.OpUpdate, ListID: list.ID, TaskID: task.ID, BaseModified: task.LastModifiedDateTime,
+			Update: &client.TaskUpdate{Title: ptr(title)}}
+		if _, err := queue.Submit(ctx, entry); err != nil {
+			t.Fatalf("Submit %q: %v", title, err)
+		}
+	}
+	srv.SetOffline(false)
+
+	// The first update goes through; the connection drops on the second.
+	srv.Fail(graphtest.Failure{Method: "PATCH", Disconnect: true, After: 1})
+	report, err := queue.Replay(ctx)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(report.Applied) != 1 || !report.Offline || report.Remaining != 1 {
+		t.Fatalf("Replay = %+v, want one write applied and one put back", report)
+	}
+	srv.ClearFailures()
+
+	report, err = queue.Replay(ctx)
+	if err != nil {
+		t.Fatalf("second Replay: %v", err)
+	}
+	if len(report.Applied) != 1 || len(report.Conflicts) != 0 || report.Remaining != 0 {
+		t.Errorf("second Replay = %+v, want the write applied without a conflict", report)
+	}
+	if got, _ := srv.Task(list.ID, task.ID); got.Title != "Call the bank today" {
+		t.Errorf("title = %q, want the second update applied", got.Title)
+	}
+}
+
+func TestSubmitFetchesBaseOfUncachedTask(t *testing.T) {
+	srv, _, queue := newTestQueue(t)
+	ctx := context.Background()
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	task := srv.AddTask(list.ID, types.TodoTask{Title: "Call bank"})
+	update := func(title string) store.Entry {
+		return store.Entry{Op: store.OpUpdate, ListID: list.ID, TaskID: task.ID, Update: &client.TaskUpdate{Title: ptr(title)}}
+	}
+
+	srv.SetOffline(true)
+	if _, err := queue.Submit(ctx, update("Call the bank")); err != nil {
+		t.Fatal(err)
+	}
+	srv.SetOffline(false)
+
+	// The first update is held as failed, so the second queues behind it.
+	srv.Fail(graphtest.Failure{Method: "PATCH", Status: 400, Times: 1})
+	result, err := queue.Submit(ctx, update("Call the bank today"))
+	if err != nil || result.Queued == nil {
+		t.Fatalf("Submit = %+v, %v; want it queued", result, err)
+	}
+
+	entries, err := queue.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || !entries[0].BaseUnknown() || entries[1].BaseUnknown() {
+		t.Errorf("entries = %+v, want only the write queued offline without a base", entries)
+	}
+}
+
+func TestReplayDetectsConflicts(t *testing.T) {
+	srv, _, queue := newTestQueue(t)
+	ctx := context.Background()
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	task := srv.AddTask(list.ID, types.TodoTask{Title: "Call bank"})
+	other := srv.AddTask(list.ID, types.TodoTask{Title: "Pay rent"})
+	update := func(title string) store.Entry {
+		return store.Entry{Op: store.OpUpdate, ListID: list.ID, TaskID: task.ID, BaseModified: task.LastModifiedDateTime,
+			Update: &client.TaskUpdate{Title: ptr(title)}}
+	}
+
+	srv.SetOffline(true)
+	for _, entry := range []store.Entry{
+		update("Call the bank"),
+		update("Call the bank today"),
+		{Op: store.OpComplete, ListID: list.ID, TaskID: other.ID, BaseModified: other.LastModifiedDateTime},
+	} {
+		if _, err := queue.Submit(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv.SetOffline(false)
+	srv.ModifyTask(list.ID, task.ID, func(task *types.TodoTask) { task.Title = "Call bank before noon" })
+
+	// The first update conflicts and holds back the second; the write to
+	// the other task goes through.
+	report, err := queue.Replay(ctx)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(report.Conflicts) != 1 || len(report.Applied) != 1 || report.Remaining != 2 {
+		t.Fatalf("Replay = %+v, want one conflict, one write held and one applied", report)
+	}
+	entries, err := queue.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].State != store.StateConflict || entries[1].State != store.StatePending {
+		t.Fatalf("entries = %+v, want the conflict and the write held behind it", entries)
+	}
+	if got, _ := srv.Task(list.ID, task.ID); got.Title != "Call bank before noon" {
+		t.Errorf("title = %q, want the server change kept", got.Title)
+	}
+
+	// Forcing the conflict applies it, and the write held behind it after it.
+	if _, err := queue.Force(entries[0].ID); err != nil {
+		t.Fatalf("Force: %v", err)
+	}
+	if forced, _ := queue.Entries(); !forced[0].Force || forced[0].State != store.StatePending || forced[0].Error != "" {
+		t.Errorf("forced entry = %+v, want it pending and forced", forced[0])
+	}
+	report, err = queue.Replay(ctx)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(report.Applied) != 2 || len(report.Conflicts) != 0 || report.Remaining != 0 {
+		t.Errorf("Replay after Force = %+v, want both writes applied", report)
+	}
+	if got, _ := srv.Task(list.ID, task.ID); got.Title != "Call the bank today" {
+		t.Errorf("title = %q, want the forced writes applied", got.Title)
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	srv, _, queue := newTestQueue(t)
+	ctx := context.Background()
+	list := srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	task := srv.AddTask(list.ID, types.TodoTask{Title: "Call bank"})
+
+	srv.SetOffline(true)
+	result, err := queue.Submit(ctx, store.Entry{Op: store.OpDelete, ListID: list.ID, TaskID: task.ID})
+	if err != nil || result.Queued == nil {
+		t.Fatalf("Submit = %+v, %v; want it queued", result, err)
+	}
+	srv.SetOffline(false)
+
+	if _, err := queue.Discard(result.Queued.ID); err != nil {
+		t.Fatalf("Discard: %v", err)
+	}
+	if _, err := queue.Discard(result.Queued.ID); err == nil {
+		t.Error("discarding an entry twice succeeded")
+	}
+	if entries, err := queue.Entries(); err != nil || len(entries) != 0 {
+		t.Errorf("entries = %+v, %v; want none", entries, err)
+	}
+	if report, err := queue.Replay(ctx); err != nil || len(report.Applied) != 0 {
+		t.Errorf("Replay = %+v, %v; want nothing applied", report, err)
+	}
+	if _, ok := srv.Task(list.ID, task.ID); !ok {
+		t.Error("the discarded delete was applied")
+	}
+}
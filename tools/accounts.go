@@ -0,0 +1,302 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/auth"
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
+)
+
+// Account is a signed-in profile and the clients that work with it.
+type Account struct {
+	Tokens *auth.TokenManager
+	Graph  *client.GraphClient
+	Cache  *store.Cache
+	Queue  *store.Queue
+}
+
+// OpenAccount returns the Account for a profile name, which has been
+// checked with auth.ValidateProfile. The profile need not be signed in yet.
+type OpenAccount func(profile string) (*Account, error)
+
+// accounts holds the profiles opened so far and which one tools use when
+// they are not given an account argument.
+type accounts struct {
+	open       OpenAccount
+	configured []string
+
+	// configDir is where the profiles' tokens are stored, set once the
+	// active account is opened.
+	configDir string
+
+	mu     sync.Mutex
+	active string
+	opened map[string]*openedAccount
+
+	// stored caches the profiles with stored tokens; nil until read.
+	stored []string
+}
+
+// openedAccount is an Account with the handlers of its tools.
+type openedAccount struct {
+	*Account
+	name     string
+	handlers map[string]server.ToolHandlerFunc
+}
+
+func newAccounts(open OpenAccount, active string, configured []string) *accounts {
+	return &accounts{open: open, configured: configured, active: active, opened: map[string]*openedAccount{}}
+}
+
+// get returns the named account, opening it on first use. The account must
+// exist: opened already, configured, or with stored tokens. A new account
+// is made with create, so that a mistyped name is not taken for one.
+func (a *accounts) get(name string) (*openedAccount, error) {
+	if err := auth.ValidateProfile(name); err != nil {
+		return nil, err
+	}
+	names, err := a.names(false)
+	if err == nil && !contains(names, name) {
+		// The account may have been signed in to by another process.
+		names, err = a.names(true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !contains(names, name) {
+		return nil, fmt.Errorf("unknown account %q; the accounts are %s. To add a new account, call login with account %q", name, strings.Join(names, ", "), name)
+	}
+	return a.create(name)
+}
+
+// create returns the named account, opening it on first use even if it
+// does not exist yet.
+func (a *accounts) create(name string) (*openedAccount, error) {
+	if err := auth.ValidateProfile(name); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if opened, ok := a.opened[name]; ok {
+		return opened, nil
+	}
+
+	account, err := a.open(name)
+	if err != nil {
+		return nil, fmt.Errorf("opening account %s: %w", name, err)
+	}
+	opened := &openedAccount{Account: account, name: name, handlers: map[string]server.ToolHandlerFunc{}}
+	for _, tool := range accountTools(account) {
+		opened.handlers[tool.Tool.Name] = tool.Handler
+	}
+	a.opened[name] = opened
+	if a.configDir == "" {
+		a.configDir = account.Tokens.ConfigDir()
+	}
+	return opened, nil
+}
+
+// activeName returns the name of the active account.
+func (a *accounts) activeName() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.active
+}
+
+// setActive makes the named account the active one.
+func (a *accounts) setActive(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.active = name
+}
+
+// names returns the accounts with stored tokens, those configured and those
+// opened since the server started, sorted. The accounts with stored tokens
+// are read from disk once, and again if refresh is set.
+func (a *accounts) names(refresh bool) ([]string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.stored == nil || refresh {
+		stored, err := auth.ListProfiles(a.configDir)
+		if err != nil {
+			return nil, err
+		}
+		a.stored = append([]string{}, stored...)
+	}
+	names := append([]string(nil), a.stored...)
+	for _, name := range a.configured {
+		if !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	for name := range a.opened {
+		if !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if !contains(names, a.active) {
+		names = append(names, a.active)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the account named by the request's argument, or the
+// active account. The result is non-nil if the account cannot be opened.
+func (a *accounts) resolve(request mcp.CallToolRequest, argument string) (*openedAccount, *mcp.CallToolResult) {
+	return a.resolveWith(request, argument, a.get)
+}
+
+// resolveWith is resolve with the function that opens the account.
+func (a *accounts) resolveWith(request mcp.CallToolRequest, argument string, get func(name string) (*openedAccount, error)) (*openedAccount, *mcp.CallToolResult) {
+	name := request.GetString(argument, "")
+	if name == "" {
+		name = a.activeName()
+	}
+	account, err := get(name)
+	if err != nil {
+		return nil, errorResult(err, anyResource)
+	}
+	return account, nil
+}
+
+// dispatch returns a handler that runs the named tool of the account given
+// in the request, or of the active account. Only login creates an account
+// that does not exist yet.
+func (a *accounts) dispatch(name string) server.ToolHandlerFunc {
+	get := a.get
+	if name == "login" {
+		get = a.create
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		account, result := a.resolveWith(request, "account", get)
+		if result != nil {
+			return result, nil
+		}
+		result, err := account.handlers[name](ctx, request)
+		if name == "login_complete" {
+			// The account now has stored tokens.
+			a.names(true)
+		}
+		return result, err
+	}
+}
+
+// accountDescription describes the account argument added to every tool.
+const accountDescription = "The account to use, such as work, when more than one is signed in (default: the active account, see list_accounts)"
+
+// withAccountArgument adds the optional account argument to a tool.
+func withAccountArgument(tool mcp.Tool) mcp.Tool {
+	properties := make(map[string]any, len(tool.InputSchema.Properties)+1)
+	for name, property := range tool.InputSchema.Properties {
+		properties[name] = property
+	}
+	properties["account"] = map[string]any{"type": "string", "description": accountDescription}
+	tool.InputSchema.Properties = properties
+	return tool
+}
+
+func listAccountsTool(accounts *accounts) server.ServerTool {
+	tool := mcp.NewTool(
+		"list_accounts",
+		mcp.WithDescription("List the Microsoft To-Do accounts this server can use, who is signed in to each, and which one is active. Tools use the active account unless given an account argument."),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		active, err := accounts.get(accounts.activeName())
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+		names, err := accounts.names(true)
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("Found %d account(s):\n\n", len(names)))
+		for _, name := range names {
+			sb.WriteString(fmt.Sprintf("- %s", name))
+			if name == active.name {
+				sb.WriteString(" (active)")
+			}
+
+			account, err := accounts.get(name)
+			if err != nil {
+				sb.WriteString(fmt.Sprintf(": %s\n", err))
+				continue
+			}
+			tokens, err := account.Tokens.LoadTokens()
+			switch {
+			case err != nil:
+				sb.WriteString(fmt.Sprintf(": %s\n", err))
+			case tokens == nil:
+				sb.WriteString(fmt.Sprintf(": not signed in, call login with account \"%s\"\n", name))
+			default:
+				sb.WriteString(fmt.Sprintf(": %s\n", auth.DescribeAccount(tokens.Account)))
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: sb.String()}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func switchAccountTool(accounts *accounts) server.ServerTool {
+	tool := mcp.NewTool(
+		"switch_account",
+		mcp.WithDescription("Make another Microsoft To-Do account the active one, used by tools called without an account argument. The account must be listed by list_accounts; to add one, call login with its name first."),
+		mcp.WithString(
+			"account",
+			mcp.Description("The account name, such as personal or work"),
+			mcp.Required(),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name := request.GetString("account", "")
+		account, err := accounts.get(name)
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+		accounts.setActive(name)
+
+		tokens, err := account.Tokens.LoadTokens()
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+		msg := fmt.Sprintf("Switched to account %s", name)
+		if tokens == nil {
+			msg += ", which is not signed in. Call login to sign in."
+		} else {
+			msg += fmt.Sprintf(", signed in as %s.", auth.DescribeAccount(tokens.Account))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: msg}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
@@ -0,0 +1,295 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+)
+
+func attachFileTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"attach_file",
+		mcp.WithDescription("Attach a local file (up to 25 MB) to a Microsoft To-Do task"),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list containing the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"task_id",
+			mcp.Description("The ID of the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"file_path",
+			mcp.Description("Absolute path of the local file to attach"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"name",
+			mcp.Description("Optional attachment name (defaults to the file name)"),
+		),
+		mcp.WithString(
+			"content_type",
+			mcp.Description("Optional MIME type (detected from the file when omitted)"),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskID := request.GetString("task_id", "")
+		filePath := request.GetString("file_path", "")
+		if listID == "" || taskID == "" || filePath == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id, task_id and file_path are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+		if info.Size() > client.MaxAttachmentSize {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Error: %s is %s, larger than the 25 MB attachment limit", filePath, formatSize(info.Size()))}},
+				IsError: true,
+			}, nil
+		}
+
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+
+		name := request.GetString("name", filepath.Base(filePath))
+		contentType := request.GetString("content_type", "")
+		if contentType == "" {
+			contentType = detectContentType(filePath, data)
+		}
+
+		attachment, err := graphClient.AttachFile(ctx, listID, taskID, name, contentType, data)
+		if err != nil {
+			return errorResult(err, taskResource), nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("File \"%s\" (%s) attached. (ID: %s)", attachment.Name, formatSize(int64(len(data))), attachment.ID)}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func listAttachmentsTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"list_attachments",
+		mcp.WithDescription("List the files attached to a Microsoft To-Do task"),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list containing the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"task_id",
+			mcp.Description("The ID of the task"),
+			mcp.Required(),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskID := request.GetString("task_id", "")
+		if listID == "" || taskID == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id and task_id are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		attachments, err := graphClient.ListAttachments(ctx, listID, taskID)
+		if err != nil {
+			return errorResult(err, taskResource), nil
+		}
+
+		if len(attachments) == 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "This task has no attachments."}},
+			}, nil
+		}
+
+		var sb strings.Builder
+		for _, a := range attachments {
+			sb.WriteString(fmt.Sprintf("- **%s** (%s, %s) (ID: `%s`)\n", a.Name, a.ContentType, formatSize(a.Size), a.ID))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: sb.String()}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func downloadAttachmentTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"download_attachment",
+		mcp.WithDescription("Download a file attached to a Microsoft To-Do task and save it locally"),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list containing the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"task_id",
+			mcp.Description("The ID of the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"attachment_id",
+			mcp.Description("The ID of the attachment. Use list_attachments to find it."),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"output_path",
+			mcp.Description("Absolute path of the file or directory to save to. A directory keeps the attachment's name."),
+			mcp.Required(),
+		),
+		mcp.WithBoolean(
+			"overwrite",
+			mcp.Description("Whether to replace an existing file at the output path (default false)"),
+			mcp.DefaultBool(false),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskID := request.GetString("task_id", "")
+		attachmentID := request.GetString("attachment_id", "")
+		outputPath := request.GetString("output_path", "")
+		overwrite := request.GetBool("overwrite", false)
+		if listID == "" || taskID == "" || attachmentID == "" || outputPath == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id, task_id, attachment_id and output_path are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		attachment, err := graphClient.GetAttachment(ctx, listID, taskID, attachmentID)
+		if err != nil {
+			return errorResult(err, attachmentResource), nil
+		}
+
+		if info, err := os.Stat(outputPath); err == nil && info.IsDir() {
+			outputPath = filepath.Join(outputPath, filepath.Base(attachment.Name))
+		}
+		if err := saveFile(outputPath, attachment.ContentBytes, overwrite); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Error: %s already exists. Choose another output_path, or pass overwrite true to replace it.", outputPath)}},
+					IsError: true,
+				}, nil
+			}
+			return errorResult(err, anyResource), nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Attachment \"%s\" (%s) saved to %s.", attachment.Name, formatSize(int64(len(attachment.ContentBytes))), outputPath)}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+// saveFile writes data to a new file at path. An existing file is only
+// replaced if overwrite is set; otherwise the error wraps fs.ErrExist.
+func saveFile(path string, data []byte, overwrite bool) error {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flag, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func deleteAttachmentTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"delete_attachment",
+		mcp.WithDescription("Delete a file attached to a Microsoft To-Do task"),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list containing the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"task_id",
+			mcp.Description("The ID of the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"attachment_id",
+			mcp.Description("The ID of the attachment to delete"),
+			mcp.Required(),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskID := request.GetString("task_id", "")
+		attachmentID := request.GetString("attachment_id", "")
+		if listID == "" || taskID == "" || attachmentID == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id, task_id and attachment_id are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		if err := graphClient.DeleteAttachment(ctx, listID, taskID, attachmentID); err != nil {
+			return errorResult(err, attachmentResource), nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Attachment deleted successfully."}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+// detectContentType guesses a MIME type from the file extension, falling
+// back to sniffing the content.
+func detectContentType(path string, data []byte) string {
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+		return contentType
+	}
+	return http.DetectContentType(data)
+}
+
+func formatSize(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}
@@ -0,0 +1,334 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
+)
+
+// maxBulkItems bounds a single bulk call, to stay well clear of throttling.
+const maxBulkItems = 100
+
+func bulkUpdateTasksTool(graphClient *client.GraphClient, queue *store.Queue) server.ServerTool {
+	tool := mcp.NewTool(
+		"bulk_update_tasks",
+		mcp.WithDescription(fmt.Sprintf("Complete, delete or update up to %d tasks of one Microsoft To-Do list in a single call. Returns a table with the result for each task.", maxBulkItems)),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list containing the tasks"),
+			mcp.Required(),
+		),
+		mcp.WithArray(
+			"task_ids",
+			mcp.Description("The IDs of the tasks to change"),
+			mcp.WithStringItems(),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"action",
+			mcp.Description("What to do with every task. update applies the fields below."),
+			mcp.Enum("complete", "delete", "update"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"importance",
+			mcp.Description("For update: new importance level"),
+			mcp.Enum("low", "normal", "high"),
+		),
+		mcp.WithString(
+			"status",
+			mcp.Description("For update: new task status"),
+			mcp.Enum("notStarted", "inProgress", "completed", "waitingOnOthers", "deferred"),
+		),
+		mcp.WithString(
+			"due_date",
+			mcp.Description("For update: new due date in ISO 8601 format. Pass an empty string to clear."),
+		),
+		mcp.WithString(
+			"start_date",
+			mcp.Description("For update: new start date in ISO 8601 format. Pass an empty string to clear."),
+		),
+		mcp.WithString(
+			"reminder_date",
+			mcp.Description("For update: new reminder date and time in ISO 8601 format. Pass an empty string to turn the reminder off."),
+		),
+		mcp.WithString(
+			"time_zone",
+			mcp.Description("IANA time zone the new dates are in, e.g. Europe/Paris (default UTC)"),
+		),
+		mcp.WithArray(
+			"categories",
+			mcp.Description("For update: new categories (tags), replacing the existing ones. Pass an empty array to remove all."),
+			mcp.WithStringItems(),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskIDs := request.GetStringSlice("task_ids", nil)
+		action := request.GetString("action", "")
+		if listID == "" || len(taskIDs) == 0 || action == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id, task_ids and action are required"}},
+				IsError: true,
+			}, nil
+		}
+		if len(taskIDs) > maxBulkItems {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Error: at most %d tasks can be changed per call, got %d", maxBulkItems, len(taskIDs))}},
+				IsError: true,
+			}, nil
+		}
+
+		update := client.TaskUpdate{
+			Importance:   optionalString(request, "importance"),
+			Status:       optionalString(request, "status"),
+			DueDate:      optionalString(request, "due_date"),
+			StartDate:    optionalString(request, "start_date"),
+			ReminderDate: optionalString(request, "reminder_date"),
+			Categories:   optionalStringSlice(request, "categories"),
+			TimeZone:     request.GetString("time_zone", ""),
+		}
+
+		var op store.Op
+		var apply func(ctx context.Context, entries []store.Entry) []client.TaskResult
+		switch action {
+		case "complete":
+			op = store.OpComplete
+			apply = func(ctx context.Context, entries []store.Entry) []client.TaskResult {
+				return graphClient.CompleteTasks(ctx, listID, entryTaskIDs(entries))
+			}
+		case "delete":
+			op = store.OpDelete
+			apply = func(ctx context.Context, entries []store.Entry) []client.TaskResult {
+				return graphClient.DeleteTasks(ctx, listID, entryTaskIDs(entries))
+			}
+		case "update":
+			if update.IsEmpty() {
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: no fields to update were provided"}},
+					IsError: true,
+				}, nil
+			}
+			op = store.OpUpdate
+			apply = func(ctx context.Context, entries []store.Entry) []client.TaskResult {
+				return graphClient.UpdateTasks(ctx, listID, entryTaskIDs(entries), update)
+			}
+		default:
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Error: unknown action %q", action)}},
+				IsError: true,
+			}, nil
+		}
+
+		entries := make([]store.Entry, len(taskIDs))
+		for i, taskID := range taskIDs {
+			entries[i] = store.Entry{Op: op, ListID: listID, TaskID: taskID}
+			if op == store.OpUpdate {
+				entries[i].Update = &update
+			}
+		}
+
+		outcomes, err := queue.SubmitBatch(ctx, entries, apply)
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+
+		done := map[string]string{"complete": "completed", "delete": "deleted", "update": "updated"}[action]
+		return bulkResult(entries, outcomes, done), nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+// taskSpec is one task passed to bulk_create_tasks.
+type taskSpec struct {
+	Title        string   `json:"title"`
+	Body         string   `json:"body"`
+	Importance   string   `json:"importance"`
+	DueDate      string   `json:"due_date"`
+	StartDate    string   `json:"start_date"`
+	ReminderDate string   `json:"reminder_date"`
+	Categories   []string `json:"categories"`
+}
+
+func bulkCreateTasksTool(graphClient *client.GraphClient, queue *store.Queue) server.ServerTool {
+	tool := mcp.NewTool(
+		"bulk_create_tasks",
+		mcp.WithDescription(fmt.Sprintf("Create up to %d tasks in one Microsoft To-Do list in a single call. Returns a table with the result for each task.", maxBulkItems)),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list. Use list_todo_lists to find it."),
+			mcp.Required(),
+		),
+		mcp.WithArray(
+			"tasks",
+			mcp.Description("The tasks to create"),
+			mcp.Required(),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"title":         map[string]any{"type": "string", "description": "The title of the task"},
+					"body":          map[string]any{"type": "string", "description": "Optional description/notes"},
+					"importance":    map[string]any{"type": "string", "enum": []string{"low", "normal", "high"}},
+					"due_date":      map[string]any{"type": "string", "description": "Optional due date in ISO 8601 format"},
+					"start_date":    map[string]any{"type": "string", "description": "Optional start date in ISO 8601 format"},
+					"reminder_date": map[string]any{"type": "string", "description": "Optional reminder date and time in ISO 8601 format"},
+					"categories":    map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+				},
+				"required": []string{"title"},
+			}),
+		),
+		mcp.WithString(
+			"time_zone",
+			mcp.Description("IANA time zone the dates are in, e.g. Europe/Paris or America/New_York (default UTC)"),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		timeZone := request.GetString("time_zone", "")
+		specs, err := taskSpecsArg(request)
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+		if listID == "" || len(specs) == 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id and tasks are required"}},
+				IsError: true,
+			}, nil
+		}
+		if len(specs) > maxBulkItems {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Error: at most %d tasks can be created per call, got %d", maxBulkItems, len(specs))}},
+				IsError: true,
+			}, nil
+		}
+
+		entries := make([]store.Entry, len(specs))
+		for i, spec := range specs {
+			if spec.Title == "" {
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Error: task %d has no title", i+1)}},
+					IsError: true,
+				}, nil
+			}
+			entries[i] = store.Entry{
+				Op:     store.OpCreate,
+				ListID: listID,
+				Title:  spec.Title,
+				Create: &client.NewTask{
+					Title:        spec.Title,
+					Body:         spec.Body,
+					Importance:   spec.Importance,
+					DueDate:      spec.DueDate,
+					StartDate:    spec.StartDate,
+					ReminderDate: spec.ReminderDate,
+					TimeZone:     timeZone,
+					Categories:   spec.Categories,
+				},
+			}
+		}
+
+		apply := func(ctx context.Context, entries []store.Entry) []client.TaskResult {
+			tasks := make([]client.NewTask, len(entries))
+			for i, entry := range entries {
+				tasks[i] = *entry.Create
+			}
+			return graphClient.CreateTasks(ctx, listID, tasks)
+		}
+		outcomes, err := queue.SubmitBatch(ctx, entries, apply)
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+		return bulkResult(entries, outcomes, "created"), nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func taskSpecsArg(request mcp.CallToolRequest) ([]taskSpec, error) {
+	raw, ok := request.GetArguments()["tasks"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tasks: %w", err)
+	}
+	var specs []taskSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("invalid tasks: %w", err)
+	}
+	return specs, nil
+}
+
+func entryTaskIDs(entries []store.Entry) []string {
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.TaskID
+	}
+	return ids
+}
+
+// bulkResult renders one table row per task, followed by a summary.
+func bulkResult(entries []store.Entry, outcomes []store.Outcome, done string) *mcp.CallToolResult {
+	var sb strings.Builder
+	sb.WriteString("| # | Task | Result |\n|---|------|--------|\n")
+
+	succeeded, queued, failed := 0, 0, 0
+	for i, outcome := range outcomes {
+		entry := entries[i]
+		title, id := entry.Title, entry.TaskID
+		if outcome.Task != nil {
+			title, id = outcome.Task.Title, outcome.Task.ID
+		}
+
+		name := fmt.Sprintf("`%s`", id)
+		switch {
+		case title != "" && id != "":
+			name = fmt.Sprintf("%s (`%s`)", title, id)
+		case title != "":
+			name = title
+		}
+
+		var result string
+		switch {
+		case outcome.Err != nil:
+			failed++
+			result = "failed: " + describeError(outcome.Err, taskResource)
+		case outcome.Queued != nil:
+			queued++
+			result = fmt.Sprintf("queued as %s", outcome.Queued.ID)
+		default:
+			succeeded++
+			result = done
+		}
+		sb.WriteString(fmt.Sprintf("| %d | %s | %s |\n", i+1, tableCell(name), tableCell(result)))
+	}
+
+	sb.WriteString(fmt.Sprintf("\n%d %s, %d failed", succeeded, done, failed))
+	if queued > 0 {
+		sb.WriteString(fmt.Sprintf(", %d queued because Microsoft To-Do could not be reached (see pending_changes)", queued))
+	}
+	sb.WriteString(".\n")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{mcp.TextContent{Type: "text", Text: sb.String()}},
+		IsError: succeeded == 0 && queued == 0,
+	}
+}
+
+// tableCell keeps a value on one line of a markdown table.
+func tableCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
@@ -0,0 +1,266 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+func listChecklistItemsTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"list_checklist_items",
+		mcp.WithDescription("List the checklist items (steps) of a Microsoft To-Do task"),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list containing the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"task_id",
+			mcp.Description("The ID of the task"),
+			mcp.Required(),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskID := request.GetString("task_id", "")
+		if listID == "" || taskID == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id and task_id are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		items, err := graphClient.ListChecklistItems(ctx, listID, taskID)
+		if err != nil {
+			return errorResult(err, taskResource), nil
+		}
+
+		if len(items) == 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "This task has no checklist items."}},
+			}, nil
+		}
+
+		var sb strings.Builder
+		for _, item := range items {
+			sb.WriteString(formatChecklistItem(item, ""))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: sb.String()}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func addChecklistItemTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"add_checklist_item",
+		mcp.WithDescription("Add a checklist item (step) to a Microsoft To-Do task"),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list containing the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"task_id",
+			mcp.Description("The ID of the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"display_name",
+			mcp.Description("The text of the checklist item"),
+			mcp.Required(),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskID := request.GetString("task_id", "")
+		displayName := request.GetString("display_name", "")
+		if listID == "" || taskID == "" || displayName == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id, task_id and display_name are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		item, err := graphClient.CreateChecklistItem(ctx, listID, taskID, displayName)
+		if err != nil {
+			return errorResult(err, taskResource), nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Checklist item \"%s\" added. (ID: %s)", item.DisplayName, item.ID)}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func updateChecklistItemTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"update_checklist_item",
+		mcp.WithDescription("Rename a checklist item (step) of a Microsoft To-Do task"),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list containing the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"task_id",
+			mcp.Description("The ID of the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"item_id",
+			mcp.Description("The ID of the checklist item"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"display_name",
+			mcp.Description("The new text of the checklist item"),
+			mcp.Required(),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskID := request.GetString("task_id", "")
+		itemID := request.GetString("item_id", "")
+		displayName := request.GetString("display_name", "")
+		if listID == "" || taskID == "" || itemID == "" || displayName == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id, task_id, item_id and display_name are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		item, err := graphClient.UpdateChecklistItem(ctx, listID, taskID, itemID, displayName)
+		if err != nil {
+			return errorResult(err, checklistResource), nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Checklist item renamed to \"%s\".", item.DisplayName)}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func checkChecklistItemTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"check_checklist_item",
+		mcp.WithDescription("Tick off (or untick) a checklist item (step) of a Microsoft To-Do task"),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list containing the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"task_id",
+			mcp.Description("The ID of the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"item_id",
+			mcp.Description("The ID of the checklist item"),
+			mcp.Required(),
+		),
+		mcp.WithBoolean(
+			"checked",
+			mcp.Description("Whether the item is checked (default true). Pass false to uncheck it."),
+			mcp.DefaultBool(true),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskID := request.GetString("task_id", "")
+		itemID := request.GetString("item_id", "")
+		checked := request.GetBool("checked", true)
+		if listID == "" || taskID == "" || itemID == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id, task_id and item_id are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		item, err := graphClient.CheckChecklistItem(ctx, listID, taskID, itemID, checked)
+		if err != nil {
+			return errorResult(err, checklistResource), nil
+		}
+
+		state := "checked"
+		if !item.IsChecked {
+			state = "unchecked"
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Checklist item \"%s\" %s.", item.DisplayName, state)}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func deleteChecklistItemTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"delete_checklist_item",
+		mcp.WithDescription("Delete a checklist item (step) from a Microsoft To-Do task"),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list containing the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"task_id",
+			mcp.Description("The ID of the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"item_id",
+			mcp.Description("The ID of the checklist item to delete"),
+			mcp.Required(),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskID := request.GetString("task_id", "")
+		itemID := request.GetString("item_id", "")
+		if listID == "" || taskID == "" || itemID == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id, task_id and item_id are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		if err := graphClient.DeleteChecklistItem(ctx, listID, taskID, itemID); err != nil {
+			return errorResult(err, checklistResource), nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Checklist item deleted successfully."}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func formatChecklistItem(item types.ChecklistItem, indent string) string {
+	checkbox := "☐"
+	if item.IsChecked {
+		checkbox = "☑"
+	}
+	return fmt.Sprintf("%s- %s %s (ID: `%s`)\n", indent, checkbox, item.DisplayName, item.ID)
+}
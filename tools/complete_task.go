@@ -7,10 +7,10 @@ import (
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
-	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
 )
 
-func completeTaskTool(graphClient *client.GraphClient) server.ServerTool {
+func completeTaskTool(queue *store.Queue) server.ServerTool {
 	tool := mcp.NewTool(
 		"complete_task",
 		mcp.WithDescription("Mark a Microsoft To-Do task as completed"),
@@ -36,16 +36,18 @@ func completeTaskTool(graphClient *client.GraphClient) server.ServerTool {
 			}, nil
 		}
 
-		task, err := graphClient.CompleteTask(ctx, listID, taskID)
+		result, err := queue.Submit(ctx, store.Entry{Op: store.OpComplete, ListID: listID, TaskID: taskID})
 		if err != nil {
+			return errorResult(err, taskResource), nil
+		}
+		if result.Queued != nil {
 			return &mcp.CallToolResult{
-				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Error: %s", err)}},
-				IsError: true,
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: queuedMessage(result.Queued)}},
 			}, nil
 		}
 
 		return &mcp.CallToolResult{
-			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Task \"%s\" marked as completed.", task.Title)}},
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Task \"%s\" marked as completed.", result.Task.Title)}},
 		}, nil
 	}
 
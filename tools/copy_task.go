@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func copyTaskTool(accounts *accounts) server.ServerTool {
+	tool := mcp.NewTool(
+		"copy_task",
+		mcp.WithDescription("Copy a task to a list, possibly in another account, keeping its notes, dates, importance, categories, recurrence, checklist items, links and attachments. The original task is unchanged."),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list containing the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"task_id",
+			mcp.Description("The ID of the task to copy"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"target_list_id",
+			mcp.Description("The ID of the list to copy the task to, in the target account"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"account",
+			mcp.Description(accountDescription),
+		),
+		mcp.WithString(
+			"target_account",
+			mcp.Description("The account to copy the task to (default: the same account as the task)"),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskID := request.GetString("task_id", "")
+		targetListID := request.GetString("target_list_id", "")
+		if listID == "" || taskID == "" || targetListID == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id, task_id and target_list_id are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		source, result := accounts.resolve(request, "account")
+		if result != nil {
+			return result, nil
+		}
+		target := source
+		if name := request.GetString("target_account", ""); name != "" {
+			var err error
+			if target, err = accounts.get(name); err != nil {
+				return errorResult(err, anyResource), nil
+			}
+		}
+
+		task, err := source.Graph.CopyTask(ctx, listID, taskID, target.Graph, targetListID)
+		if err != nil {
+			return errorResult(err, taskResource), nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Task \"%s\" copied to account %s. (New ID: %s)", task.Title, target.name, task.ID)}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
@@ -31,10 +31,7 @@ func createListTool(graphClient *client.GraphClient) server.ServerTool {
 		}
 		list, err := graphClient.CreateList(ctx, displayName)
 		if err != nil {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Error: %s", err)}},
-				IsError: true,
-			}, nil
+			return errorResult(err, anyResource), nil
 		}
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("List \"%s\" created successfully. (ID: %s)", list.DisplayName, list.ID)}},
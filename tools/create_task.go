@@ -8,9 +8,10 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 
 	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
 )
 
-func createTaskTool(graphClient *client.GraphClient) server.ServerTool {
+func createTaskTool(queue *store.Queue) server.ServerTool {
 	tool := mcp.NewTool(
 		"create_task",
 		mcp.WithDescription("Create a new task in a Microsoft To-Do task list"),
@@ -37,6 +38,41 @@ func createTaskTool(graphClient *client.GraphClient) server.ServerTool {
 			"due_date",
 			mcp.Description("Optional due date in ISO 8601 format (e.g. 2025-12-31T17:00:00)"),
 		),
+		mcp.WithString(
+			"start_date",
+			mcp.Description("Optional start date in ISO 8601 format"),
+		),
+		mcp.WithString(
+			"reminder_date",
+			mcp.Description("Optional reminder date and time in ISO 8601 format. Turns the reminder on."),
+		),
+		mcp.WithString(
+			"time_zone",
+			mcp.Description("IANA time zone the dates are in, e.g. Europe/Paris or America/New_York (default UTC)"),
+		),
+		mcp.WithObject(
+			"recurrence",
+			mcp.Description("Optional recurrence. "+recurrenceDescription),
+		),
+		mcp.WithArray(
+			"categories",
+			mcp.Description("Optional categories (tags) such as backend or oncall. Use list_categories to reuse existing ones."),
+			mcp.WithStringItems(),
+		),
+		mcp.WithArray(
+			"links",
+			mcp.Description("Optional external resources to link to the task, such as the issue or page it came from"),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"web_url":          map[string]any{"type": "string", "description": "The URL of the linked resource"},
+					"display_name":     map[string]any{"type": "string", "description": "Title of the linked resource"},
+					"application_name": map[string]any{"type": "string", "description": "Name of the source application, e.g. GitHub"},
+					"external_id":      map[string]any{"type": "string", "description": "ID of the resource in the source application"},
+				},
+				"required": []string{"web_url"},
+			}),
+		),
 	)
 
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -45,6 +81,9 @@ func createTaskTool(graphClient *client.GraphClient) server.ServerTool {
 		body := request.GetString("body", "")
 		importance := request.GetString("importance", "")
 		dueDate := request.GetString("due_date", "")
+		startDate := request.GetString("start_date", "")
+		reminderDate := request.GetString("reminder_date", "")
+		timeZone := request.GetString("time_zone", "")
 		if listID == "" || title == "" {
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id and title are required"}},
@@ -52,16 +91,44 @@ func createTaskTool(graphClient *client.GraphClient) server.ServerTool {
 			}, nil
 		}
 
-		task, err := graphClient.CreateTask(ctx, listID, title, body, importance, dueDate)
+		recurrence, err := recurrenceArg(request)
 		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+
+		links, err := linksArg(request)
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+
+		result, err := queue.Submit(ctx, store.Entry{
+			Op:     store.OpCreate,
+			ListID: listID,
+			Title:  title,
+			Create: &client.NewTask{
+				Title:           title,
+				Body:            body,
+				Importance:      importance,
+				DueDate:         dueDate,
+				StartDate:       startDate,
+				ReminderDate:    reminderDate,
+				TimeZone:        timeZone,
+				Recurrence:      recurrence,
+				Categories:      request.GetStringSlice("categories", nil),
+				LinkedResources: links,
+			},
+		})
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+		if result.Queued != nil {
 			return &mcp.CallToolResult{
-				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Error: %s", err)}},
-				IsError: true,
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: queuedMessage(result.Queued)}},
 			}, nil
 		}
 
 		return &mcp.CallToolResult{
-			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Task \"%s\" created successfully.", task.Title)}},
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Task \"%s\" created successfully.", result.Task.Title)}},
 		}, nil
 	}
 
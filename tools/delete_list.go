@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+)
+
+func deleteListTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"delete_list",
+		mcp.WithDescription("Delete a Microsoft To-Do task list and all of its tasks. Built-in lists such as the default Tasks list and Flagged email cannot be deleted."),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list to delete"),
+			mcp.Required(),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		if listID == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id is required"}},
+				IsError: true,
+			}, nil
+		}
+
+		if err := graphClient.DeleteList(ctx, listID); err != nil {
+			return errorResult(err, listResource), nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "List deleted successfully."}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
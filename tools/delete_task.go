@@ -2,15 +2,14 @@ package tools
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
-	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
 )
 
-func deleteTaskTool(graphClient *client.GraphClient) server.ServerTool {
+func deleteTaskTool(queue *store.Queue) server.ServerTool {
 	tool := mcp.NewTool(
 		"delete_task",
 		mcp.WithDescription("Delete a task from a Microsoft To-Do task list"),
@@ -36,11 +35,13 @@ func deleteTaskTool(graphClient *client.GraphClient) server.ServerTool {
 			}, nil
 		}
 
-		err := graphClient.DeleteTask(ctx, listID, taskID)
+		result, err := queue.Submit(ctx, store.Entry{Op: store.OpDelete, ListID: listID, TaskID: taskID})
 		if err != nil {
+			return errorResult(err, taskResource), nil
+		}
+		if result.Queued != nil {
 			return &mcp.CallToolResult{
-				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Error: %s", err)}},
-				IsError: true,
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: queuedMessage(result.Queued)}},
 			}, nil
 		}
 
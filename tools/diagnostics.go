@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+)
+
+func diagnosticsTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"diagnostics",
+		mcp.WithDescription("Show how often Microsoft Graph has throttled or failed requests since the server started, and how they were retried. Useful when tools are slow or failing intermittently."),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		policy := graphClient.RetryPolicy()
+		stats := graphClient.RetryStats()
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("Retry policy: up to %d retries, backoff from %s, at most %s per wait.\n\n",
+			policy.MaxRetries, policy.BaseDelay, policy.MaxDelay))
+		sb.WriteString(fmt.Sprintf("Requests sent: %d\n", stats.Requests))
+		sb.WriteString(fmt.Sprintf("Retries: %d (%s spent waiting)\n", stats.Retries, stats.TotalWait.Round(time.Millisecond)))
+		sb.WriteString(fmt.Sprintf("Throttled (429): %d\n", stats.Throttled))
+		sb.WriteString(fmt.Sprintf("Service unavailable (502/503/504): %d\n", stats.Unavailable))
+		sb.WriteString(fmt.Sprintf("Network errors: %d\n", stats.NetworkErrors))
+		sb.WriteString(fmt.Sprintf("Gave up after retrying: %d\n", stats.GaveUp))
+		if !stats.LastThrottled.IsZero() {
+			sb.WriteString(fmt.Sprintf("\nLast throttled %s ago", formatAge(time.Since(stats.LastThrottled))))
+			if stats.LastRetryAfter > 0 {
+				sb.WriteString(fmt.Sprintf(", asked to wait %s", stats.LastRetryAfter))
+			}
+			sb.WriteString(".\n")
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: sb.String()}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
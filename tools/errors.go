@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/auth"
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
+)
+
+// resource is the kind of item a tool call was about, used to tell the
+// assistant how to recover when it was not found.
+type resource int
+
+const (
+	anyResource resource = iota
+	listResource
+	taskResource
+	checklistResource
+	linkResource
+	attachmentResource
+)
+
+var notFoundMessages = map[resource]string{
+	anyResource:        "Not found — the item may have been deleted.",
+	listResource:       "List not found — it may have been deleted; call list_todo_lists to refresh IDs.",
+	taskResource:       "Task not found — it may have been deleted or moved to another list; call list_tasks to refresh IDs.",
+	checklistResource:  "Checklist item not found — it may have been deleted; call list_checklist_items to refresh IDs.",
+	linkResource:       "Link not found — it may have been removed; call list_links to refresh IDs.",
+	attachmentResource: "Attachment not found — it may have been deleted; call list_attachments to refresh IDs.",
+}
+
+// errorResult turns an error into a tool error result that tells the
+// assistant what went wrong and what to do about it.
+func errorResult(err error, res resource) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{mcp.TextContent{Type: "text", Text: describeError(err, res)}},
+		IsError: true,
+	}
+}
+
+// describeError is the message of errorResult, for errors reported inside
+// a larger result.
+func describeError(err error, res resource) string {
+	if errors.Is(err, auth.ErrNotAuthenticated) {
+		return "Not signed in to Microsoft To-Do, or the sign-in has expired. Call login to sign in, then retry."
+	}
+
+	if errors.Is(err, auth.ErrSignInUnavailable) {
+		return fmt.Sprintf("The Microsoft sign-in service is unavailable or throttling requests, so the sign-in could not be renewed. It is still valid; try again in a minute. (%s)", err)
+	}
+	if errors.Is(err, store.ErrMaybeApplied) {
+		return fmt.Sprintf("The connection to Microsoft To-Do failed after the task was sent, so it may have been created. Call list_tasks to check before trying again. (%s)", err)
+	}
+
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		if client.IsUnreachable(err) {
+			return fmt.Sprintf("Microsoft To-Do could not be reached; check the network connection and retry. (%s)", err)
+		}
+		return fmt.Sprintf("Error: %s", err)
+	}
+
+	var msg string
+	switch status := apiErr.StatusCode; {
+	case status == http.StatusNotFound:
+		msg = notFoundMessages[res]
+	case status == http.StatusUnauthorized:
+		msg = "Microsoft rejected the sign-in; it may have expired or been revoked. Call login to sign in again."
+	case status == http.StatusForbidden:
+		msg = fmt.Sprintf("Access denied: %s. The account may not have access to this item, or the app registration lacks the Tasks.ReadWrite permission.", apiErr.Message)
+	case status == http.StatusConflict, status == http.StatusPreconditionFailed:
+		msg = fmt.Sprintf("The item was changed at the same time by someone else: %s. Read it again and retry.", apiErr.Message)
+	case status == http.StatusTooManyRequests:
+		msg = "Microsoft To-Do is throttling requests and retrying did not help."
+		if apiErr.RetryAfter > 0 {
+			msg += fmt.Sprintf(" Wait %s before trying again.", apiErr.RetryAfter)
+		} else {
+			msg += " Wait a minute before trying again."
+		}
+	case status >= 500:
+		msg = fmt.Sprintf("Microsoft To-Do is having problems (status %d: %s). Try again shortly.", status, apiErr.Message)
+	case status == http.StatusBadRequest:
+		msg = fmt.Sprintf("Microsoft To-Do rejected the request: %s. Check the arguments.", strings.TrimSuffix(apiErr.Message, "."))
+	default:
+		msg = fmt.Sprintf("Error: %s", apiErr)
+	}
+	if apiErr.RequestID != "" {
+		msg += fmt.Sprintf(" (request-id: %s)", apiErr.RequestID)
+	}
+	return msg
+}
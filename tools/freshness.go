@@ -0,0 +1,33 @@
+package tools
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
+)
+
+// freshnessNote tells the assistant when cached data was served, and why.
+func freshnessNote(f store.Freshness) string {
+	if !f.Cached {
+		return ""
+	}
+	age := formatAge(f.Age())
+	if f.RefreshErr != nil {
+		return fmt.Sprintf("\n_Microsoft To-Do could not be reached (%s). Showing cached data from %s ago._\n", f.RefreshErr, age)
+	}
+	return fmt.Sprintf("\n_From local cache, updated %s ago._\n", age)
+}
+
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
@@ -0,0 +1,239 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+func addLinkTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"add_link",
+		mcp.WithDescription("Link a Microsoft To-Do task to an external resource such as a GitHub issue, pull request or wiki page"),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list containing the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"task_id",
+			mcp.Description("The ID of the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"web_url",
+			mcp.Description("The URL of the linked resource"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"display_name",
+			mcp.Description("Optional title of the linked resource (defaults to the URL)"),
+		),
+		mcp.WithString(
+			"application_name",
+			mcp.Description("Optional name of the source application, e.g. GitHub (defaults to the URL host)"),
+		),
+		mcp.WithString(
+			"external_id",
+			mcp.Description("Optional ID of the resource in the source application, e.g. an issue number"),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskID := request.GetString("task_id", "")
+		if listID == "" || taskID == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id and task_id are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		link, err := newLink(linkArg{
+			WebURL:          request.GetString("web_url", ""),
+			DisplayName:     request.GetString("display_name", ""),
+			ApplicationName: request.GetString("application_name", ""),
+			ExternalID:      request.GetString("external_id", ""),
+		})
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+
+		created, err := graphClient.CreateLinkedResource(ctx, listID, taskID, link)
+		if err != nil {
+			return errorResult(err, taskResource), nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Link \"%s\" added. (ID: %s)", created.DisplayName, created.ID)}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func listLinksTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"list_links",
+		mcp.WithDescription("List the external resources linked to a Microsoft To-Do task"),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list containing the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"task_id",
+			mcp.Description("The ID of the task"),
+			mcp.Required(),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskID := request.GetString("task_id", "")
+		if listID == "" || taskID == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id and task_id are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		links, err := graphClient.ListLinkedResources(ctx, listID, taskID)
+		if err != nil {
+			return errorResult(err, taskResource), nil
+		}
+
+		if len(links) == 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "This task has no linked resources."}},
+			}, nil
+		}
+
+		var sb strings.Builder
+		for _, link := range links {
+			sb.WriteString(formatLink(link, ""))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: sb.String()}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func removeLinkTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"remove_link",
+		mcp.WithDescription("Remove a linked resource from a Microsoft To-Do task"),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list containing the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"task_id",
+			mcp.Description("The ID of the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"link_id",
+			mcp.Description("The ID of the linked resource to remove. Use list_links to find it."),
+			mcp.Required(),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskID := request.GetString("task_id", "")
+		linkID := request.GetString("link_id", "")
+		if listID == "" || taskID == "" || linkID == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id, task_id and link_id are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		if err := graphClient.DeleteLinkedResource(ctx, listID, taskID, linkID); err != nil {
+			return errorResult(err, linkResource), nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Link removed successfully."}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+// linkArg is the tool argument shape of a linked resource.
+type linkArg struct {
+	WebURL          string `json:"web_url"`
+	DisplayName     string `json:"display_name"`
+	ApplicationName string `json:"application_name"`
+	ExternalID      string `json:"external_id"`
+}
+
+// linksArg decodes the optional "links" array argument.
+func linksArg(request mcp.CallToolRequest) ([]types.LinkedResource, error) {
+	raw, ok := request.GetArguments()["links"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid links: %w", err)
+	}
+	var args []linkArg
+	if err := json.Unmarshal(data, &args); err != nil {
+		return nil, fmt.Errorf("invalid links: %w", err)
+	}
+
+	links := make([]types.LinkedResource, 0, len(args))
+	for _, arg := range args {
+		link, err := newLink(arg)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// newLink validates a link argument and fills in default names.
+func newLink(arg linkArg) (types.LinkedResource, error) {
+	if arg.WebURL == "" {
+		return types.LinkedResource{}, fmt.Errorf("web_url is required")
+	}
+	u, err := url.Parse(arg.WebURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return types.LinkedResource{}, fmt.Errorf("web_url %q is not a valid http(s) URL", arg.WebURL)
+	}
+
+	link := types.LinkedResource{
+		WebURL:          arg.WebURL,
+		DisplayName:     arg.DisplayName,
+		ApplicationName: arg.ApplicationName,
+		ExternalID:      arg.ExternalID,
+	}
+	if link.DisplayName == "" {
+		link.DisplayName = arg.WebURL
+	}
+	if link.ApplicationName == "" {
+		link.ApplicationName = u.Hostname()
+	}
+	return link, nil
+}
+
+func formatLink(link types.LinkedResource, indent string) string {
+	return fmt.Sprintf("%s- [%s](%s) — %s (ID: `%s`)\n", indent, link.DisplayName, link.WebURL, link.ApplicationName, link.ID)
+}
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
+)
+
+func listCategoriesTool(cache *store.Cache) server.ServerTool {
+	tool := mcp.NewTool(
+		"list_categories",
+		mcp.WithDescription("List the categories (tags) used on tasks across all Microsoft To-Do lists, with how often each is used. Reuse these instead of inventing new tags."),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		lists, freshness, err := cache.Lists(ctx)
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+
+		listIDs := make([]string, len(lists))
+		for i, list := range lists {
+			listIDs[i] = list.ID
+		}
+		allTasks, taskFreshness, err := cache.AllTasks(ctx, listIDs)
+		if err != nil {
+			return errorResult(err, listResource), nil
+		}
+		if taskFreshness.Cached && (!freshness.Cached || taskFreshness.FetchedAt.Before(freshness.FetchedAt)) {
+			freshness = taskFreshness
+		}
+
+		counts := map[string]int{}
+		for _, tasks := range allTasks {
+			for _, task := range tasks {
+				for _, category := range task.Categories {
+					counts[category]++
+				}
+			}
+		}
+
+		if len(counts) == 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "No categories are in use."}},
+			}, nil
+		}
+
+		categories := make([]string, 0, len(counts))
+		for category := range counts {
+			categories = append(categories, category)
+		}
+		sort.Slice(categories, func(i, j int) bool {
+			if counts[categories[i]] != counts[categories[j]] {
+				return counts[categories[i]] > counts[categories[j]]
+			}
+			return categories[i] < categories[j]
+		})
+
+		var sb strings.Builder
+		for _, category := range categories {
+			sb.WriteString(fmt.Sprintf("- %s (%d tasks)\n", category, counts[category]))
+		}
+		sb.WriteString(freshnessNote(freshness))
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: sb.String()}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
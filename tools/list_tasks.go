@@ -4,23 +4,67 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
 	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
 	"github.com/michMartineau/mcp-server-microsoft-todo/types"
 )
 
-func listTasksTool(graphClient *client.GraphClient) server.ServerTool {
+func listTasksTool(cache *store.Cache) server.ServerTool {
 	tool := mcp.NewTool(
 		"list_tasks",
-		mcp.WithDescription("List all tasks in a Microsoft To-Do task list"),
+		mcp.WithDescription("List tasks in a Microsoft To-Do task list. Use the filters, sort and limit to keep large lists manageable."),
 		mcp.WithString(
 			"list_id",
 			mcp.Description("The ID of the task list. Use list_todo_lists to find it."),
 			mcp.Required(),
 		),
+		mcp.WithString(
+			"category",
+			mcp.Description("Optional category (tag) to filter by, spelled exactly as list_categories shows it; case matters."),
+		),
+		mcp.WithString(
+			"status",
+			mcp.Description("Only return tasks with this status. \"open\" means every status except completed."),
+			mcp.Enum("open", "notStarted", "inProgress", "completed", "waitingOnOthers", "deferred"),
+		),
+		mcp.WithString(
+			"importance",
+			mcp.Description("Only return tasks with this importance"),
+			mcp.Enum("low", "normal", "high"),
+		),
+		mcp.WithString(
+			"due_before",
+			mcp.Description("Only return tasks due before this date/time (ISO 8601, e.g. 2025-12-31 or 2025-12-31T17:00:00Z)"),
+		),
+		mcp.WithString(
+			"due_after",
+			mcp.Description("Only return tasks due after this date/time (ISO 8601)"),
+		),
+		mcp.WithString(
+			"sort_by",
+			mcp.Description("Field to sort by"),
+			mcp.Enum("due", "start", "importance", "title", "status", "created", "modified"),
+		),
+		mcp.WithString(
+			"sort_order",
+			mcp.Description("Sort direction (default asc)"),
+			mcp.Enum("asc", "desc"),
+		),
+		mcp.WithNumber(
+			"limit",
+			mcp.Description("Maximum number of tasks to return"),
+			mcp.Min(1),
+		),
+		mcp.WithArray(
+			"fields",
+			mcp.Description("Only return these task fields, e.g. [\"title\", \"status\", \"dueDateTime\"]. The ID is always returned."),
+			mcp.WithStringEnumItems(client.TaskSelectFields),
+		),
 	)
 
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -32,17 +76,19 @@ func listTasksTool(graphClient *client.GraphClient) server.ServerTool {
 			}, nil
 		}
 
-		tasks, err := graphClient.ListTasks(ctx, listID)
+		query, err := taskQueryArgs(request)
 		if err != nil {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Error: %s", err)}},
-				IsError: true,
-			}, nil
+			return errorResult(err, anyResource), nil
+		}
+
+		tasks, freshness, err := cache.QueryTasks(ctx, listID, query)
+		if err != nil {
+			return errorResult(err, listResource), nil
 		}
 
 		if len(tasks) == 0 {
 			return &mcp.CallToolResult{
-				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "No tasks found in this list."}},
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "No matching tasks found in this list."}},
 			}, nil
 		}
 
@@ -51,6 +97,7 @@ func listTasksTool(graphClient *client.GraphClient) server.ServerTool {
 			sb.WriteString(formatTask(task))
 			sb.WriteString("\n")
 		}
+		sb.WriteString(freshnessNote(freshness))
 
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: sb.String()}},
@@ -60,6 +107,49 @@ func listTasksTool(graphClient *client.GraphClient) server.ServerTool {
 	return server.ServerTool{Tool: tool, Handler: handler}
 }
 
+// taskQueryArgs builds a server-side query from the list_tasks arguments.
+func taskQueryArgs(request mcp.CallToolRequest) (client.TaskQuery, error) {
+	query := client.TaskQuery{
+		Importance: request.GetString("importance", ""),
+		Category:   request.GetString("category", ""),
+		OrderBy:    request.GetString("sort_by", ""),
+		Descending: request.GetString("sort_order", "") == "desc",
+		Top:        request.GetInt("limit", 0),
+		Select:     request.GetStringSlice("fields", nil),
+	}
+
+	switch status := request.GetString("status", ""); status {
+	case "open":
+		query.NotStatus = "completed"
+	default:
+		query.Status = status
+	}
+
+	var err error
+	if query.DueBefore, err = dateArg(request, "due_before"); err != nil {
+		return query, err
+	}
+	if query.DueAfter, err = dateArg(request, "due_after"); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// dateArg parses an optional ISO 8601 date or date/time argument. Values
+// without an offset are taken as UTC.
+func dateArg(request mcp.CallToolRequest, key string) (*time.Time, error) {
+	value := request.GetString(key, "")
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %q is not an ISO 8601 date", key, value)
+}
+
 func formatTask(task types.TodoTask) string {
 	var sb strings.Builder
 
@@ -75,12 +165,36 @@ func formatTask(task types.TodoTask) string {
 	if task.Status != "" {
 		sb.WriteString(fmt.Sprintf("  Status: %s\n", task.Status))
 	}
+	if task.StartDateTime != nil {
+		sb.WriteString(fmt.Sprintf("  Start: %s\n", formatDateTime(task.StartDateTime)))
+	}
 	if task.DueDateTime != nil {
-		sb.WriteString(fmt.Sprintf("  Due: %s\n", task.DueDateTime.DateTime))
+		sb.WriteString(fmt.Sprintf("  Due: %s\n", formatDateTime(task.DueDateTime)))
+	}
+	if task.IsReminderOn && task.ReminderDateTime != nil {
+		sb.WriteString(fmt.Sprintf("  Reminder: %s\n", formatDateTime(task.ReminderDateTime)))
+	}
+	if task.Recurrence != nil {
+		sb.WriteString(fmt.Sprintf("  Repeats: %s\n", summarizeRecurrence(task.Recurrence)))
+	}
+	if len(task.Categories) > 0 {
+		sb.WriteString(fmt.Sprintf("  Categories: %s\n", strings.Join(task.Categories, ", ")))
 	}
 	if task.Body != nil && task.Body.Content != "" {
 		sb.WriteString(fmt.Sprintf("  Notes: %s\n", task.Body.Content))
 	}
+	if len(task.ChecklistItems) > 0 {
+		sb.WriteString("  Steps:\n")
+		for _, item := range task.ChecklistItems {
+			sb.WriteString(formatChecklistItem(item, "    "))
+		}
+	}
+	if len(task.LinkedResources) > 0 {
+		sb.WriteString("  Links:\n")
+		for _, link := range task.LinkedResources {
+			sb.WriteString(formatLink(link, "    "))
+		}
+	}
 
 	return sb.String()
-}
\ No newline at end of file
+}
@@ -9,9 +9,12 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 
 	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/odata"
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
 )
 
-func listTodoListsTool(graphClient *client.GraphClient) server.ServerTool {
+func listTodoListsTool(graphClient *client.GraphClient, cache *store.Cache) server.ServerTool {
 	tool := mcp.NewTool(
 		"list_todo_lists",
 		mcp.WithDescription("List Microsoft To-Do task lists. Optionally filter by name."),
@@ -22,17 +25,20 @@ func listTodoListsTool(graphClient *client.GraphClient) server.ServerTool {
 	)
 
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		name := request.GetString("name", "")
-		filter := ""
-		if name != "" {
-			filter = "displayName eq '" + name + "'"
+		var lists []types.TodoTaskList
+		var freshness store.Freshness
+		var err error
+		if name := request.GetString("name", ""); name != "" {
+			var filter odata.Expr
+			if filter, err = client.ListFields.Compare("displayName", odata.Eq, name); err != nil {
+				return errorResult(err, anyResource), nil
+			}
+			lists, err = graphClient.ListTodoLists(ctx, filter)
+		} else {
+			lists, freshness, err = cache.Lists(ctx)
 		}
-		lists, err := graphClient.ListTodoLists(ctx, filter)
 		if err != nil {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Error: %s", err)}},
-				IsError: true,
-			}, nil
+			return errorResult(err, anyResource), nil
 		}
 
 		if len(lists) == 0 {
@@ -43,8 +49,9 @@ func listTodoListsTool(graphClient *client.GraphClient) server.ServerTool {
 
 		var sb strings.Builder
 		for _, list := range lists {
-			sb.WriteString(fmt.Sprintf("- **%s** (ID: `%s`)\n", list.DisplayName, list.ID))
+			sb.WriteString(fmt.Sprintf("- **%s** (ID: `%s`)%s\n", list.DisplayName, list.ID, listLabels(list)))
 		}
+		sb.WriteString(freshnessNote(freshness))
 
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: sb.String()}},
@@ -53,3 +60,26 @@ func listTodoListsTool(graphClient *client.GraphClient) server.ServerTool {
 
 	return server.ServerTool{Tool: tool, Handler: handler}
 }
+
+// listLabels describes what is special about a list, so the assistant knows
+// which one holds "my tasks" and which ones other people can see.
+func listLabels(list types.TodoTaskList) string {
+	var labels []string
+	switch list.WellknownName {
+	case "defaultList":
+		labels = append(labels, "default list — \"my tasks\"")
+	case "flaggedEmails":
+		labels = append(labels, "flagged emails")
+	}
+	if list.IsShared {
+		if list.IsOwner {
+			labels = append(labels, "shared by you")
+		} else {
+			labels = append(labels, "shared with you")
+		}
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	return " — " + strings.Join(labels, ", ")
+}
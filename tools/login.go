@@ -3,7 +3,6 @@ package tools
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -15,19 +14,40 @@ import (
 func loginTool(tm *auth.TokenManager) server.ServerTool {
 	tool := mcp.NewTool(
 		"login",
-		mcp.WithDescription("Start Microsoft authentication. Returns a URL and code for the user to complete sign-in."),
+		mcp.WithDescription("Start Microsoft authentication. Returns a URL, and for the device code flow a code, for the user to complete sign-in. "+
+			"Given an account name that does not exist yet, creates that account."),
+		mcp.WithString(
+			"flow",
+			mcp.Description("device_code shows a code to enter at microsoft.com/devicelogin; browser opens Microsoft's sign-in page and returns to this computer, for organizations that block the device code flow (default device_code)"),
+			mcp.Enum("device_code", "browser"),
+		),
 	)
 
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		deviceCode, err := tm.RequestDeviceCode(ctx)
-		if err != nil {
+		tm.SetPendingLogin(nil, nil)
+
+		if request.GetString("flow", "device_code") == "browser" {
+			login, err := tm.StartAuthCodeLogin()
+			if err != nil {
+				return errorResult(err, anyResource), nil
+			}
+			tm.SetPendingLogin(nil, login)
+
+			msg := fmt.Sprintf(
+				"Please open this URL in a browser on this computer and sign in:\n%s\n\nOnce the browser shows that you are signed in, call the 'login_complete' tool to finish authentication.",
+				login.AuthURL,
+			)
 			return &mcp.CallToolResult{
-				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Error requesting device code: %s", err)}},
-				IsError: true,
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: msg}},
 			}, nil
 		}
 
-		tm.PendingDeviceCode = deviceCode
+		deviceCode, err := tm.RequestDeviceCode(ctx)
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+
+		tm.SetPendingLogin(deviceCode, nil)
 
 		msg := fmt.Sprintf(
 			"Please visit: %s\nEnter code: %s\n\nOnce you have entered the code, call the 'login_complete' tool to finish authentication.",
@@ -46,41 +66,46 @@ func loginTool(tm *auth.TokenManager) server.ServerTool {
 func loginCompleteTool(tm *auth.TokenManager) server.ServerTool {
 	tool := mcp.NewTool(
 		"login_complete",
-		mcp.WithDescription("Complete Microsoft authentication after the user has entered the device code."),
+		mcp.WithDescription("Complete Microsoft authentication after the user has entered the device code or signed in in the browser."),
 	)
 
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		if tm.PendingDeviceCode == nil {
+		deviceCode, authCode := tm.TakePendingLogin()
+		var tokenResp *types.TokenResponse
+		var err error
+		switch {
+		case authCode != nil:
+			tokenResp, err = authCode.Wait(ctx)
+		case deviceCode != nil:
+			tokenResp, err = tm.PollForToken(ctx, deviceCode)
+		default:
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "No login in progress. Call 'login' first."}},
 				IsError: true,
 			}, nil
 		}
-
-		tokenResp, err := tm.PollForToken(ctx, tm.PendingDeviceCode)
 		if err != nil {
-			tm.PendingDeviceCode = nil
+			if authCode != nil {
+				authCode.Close()
+			}
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Authentication failed: %s", err)}},
 				IsError: true,
 			}, nil
 		}
 
-		tokens := &types.StoredTokens{
-			AccessToken:  tokenResp.AccessToken,
-			RefreshToken: tokenResp.RefreshToken,
-			ExpiresAt:    time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
-		}
-		if err := tm.SaveTokens(tokens); err != nil {
+		tokens, err := tm.SaveTokenResponse(tokenResp)
+		if err != nil {
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Failed to save tokens: %s", err)}},
 				IsError: true,
 			}, nil
 		}
 
-		tm.PendingDeviceCode = nil
 		return &mcp.CallToolResult{
-			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Authentication successful! You can now use Microsoft To-Do tools."}},
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf(
+				"Authentication successful! Signed in as %s. You can now use Microsoft To-Do tools.",
+				auth.DescribeAccount(tokens.Account))}},
 		}, nil
 	}
 
@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+)
+
+func moveTaskTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"move_task",
+		mcp.WithDescription("Move a task to another Microsoft To-Do list, keeping its notes, dates, importance, categories, recurrence, checklist items, links and attachments. The moved task gets a new ID."),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list currently containing the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"task_id",
+			mcp.Description("The ID of the task to move"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"target_list_id",
+			mcp.Description("The ID of the list to move the task to"),
+			mcp.Required(),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskID := request.GetString("task_id", "")
+		targetListID := request.GetString("target_list_id", "")
+		if listID == "" || taskID == "" || targetListID == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id, task_id and target_list_id are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		task, err := graphClient.MoveTask(ctx, listID, taskID, targetListID)
+		if err != nil {
+			return errorResult(err, taskResource), nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Task \"%s\" moved. (New ID: %s)", task.Title, task.ID)}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
+)
+
+func pendingChangesTool(queue *store.Queue) server.ServerTool {
+	tool := mcp.NewTool(
+		"pending_changes",
+		mcp.WithDescription("Show task changes that were queued while Microsoft To-Do was unreachable, replay them, or resolve entries that conflict with changes made elsewhere. "+
+			"Replay is manual: queued changes are not replayed when the connection comes back, only with the next task change or with action replay here."),
+		mcp.WithString(
+			"action",
+			mcp.Description("list shows the queue; replay applies it now; discard drops one entry; force applies one entry on the next replay even if the task changed on the server (default list)"),
+			mcp.Enum("list", "replay", "discard", "force"),
+		),
+		mcp.WithString(
+			"entry_id",
+			mcp.Description("The ID of the queued change, e.g. q3. Required for discard and force."),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		action := request.GetString("action", "list")
+		entryID := request.GetString("entry_id", "")
+		if (action == "discard" || action == "force") && entryID == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Error: entry_id is required for %s", action)}},
+				IsError: true,
+			}, nil
+		}
+
+		var sb strings.Builder
+		switch action {
+		case "discard":
+			entry, err := queue.Discard(entryID)
+			if err != nil {
+				return errorResult(err, anyResource), nil
+			}
+			sb.WriteString(fmt.Sprintf("Discarded %s: %s.\n\n", entry.ID, entry.Describe()))
+		case "force":
+			entry, err := queue.Force(entryID)
+			if err != nil {
+				return errorResult(err, anyResource), nil
+			}
+			sb.WriteString(fmt.Sprintf("%s will be applied on the next replay, overwriting changes made elsewhere: %s.\n", entry.ID, entry.Describe()))
+			sb.WriteString("Use action replay to apply it now.\n\n")
+		case "replay":
+			report, err := queue.Replay(ctx)
+			if err != nil {
+				return errorResult(err, anyResource), nil
+			}
+			sb.WriteString(describeReplay(report))
+			sb.WriteString("\n")
+		}
+
+		entries, err := queue.Entries()
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+		if len(entries) == 0 {
+			sb.WriteString("No pending changes.")
+		} else {
+			sb.WriteString(fmt.Sprintf("%d pending change(s), in replay order:\n", len(entries)))
+		}
+		for _, entry := range entries {
+			sb.WriteString(fmt.Sprintf("- %s: %s, queued %s ago [%s]", entry.ID, entry.Describe(), formatAge(time.Since(entry.QueuedAt)), entry.State))
+			if entry.Force {
+				sb.WriteString(" (forced)")
+			}
+			if entry.BaseUnknown() {
+				sb.WriteString(" (task was not seen before, so changes made elsewhere cannot be detected and will be overwritten)")
+			}
+			sb.WriteString("\n")
+			if entry.Error != "" {
+				sb.WriteString(fmt.Sprintf("  %s\n", entry.Error))
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: sb.String()}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func describeReplay(report *store.ReplayReport) string {
+	var sb strings.Builder
+	for _, entry := range report.Applied {
+		sb.WriteString(fmt.Sprintf("Applied %s: %s.\n", entry.ID, entry.Describe()))
+	}
+	for _, entry := range report.Conflicts {
+		sb.WriteString(fmt.Sprintf("Conflict on %s: %s. Discard it, or force it to overwrite the server copy.\n", entry.ID, entry.Describe()))
+	}
+	for _, entry := range report.Failed {
+		sb.WriteString(fmt.Sprintf("Failed %s: %s: %s\n", entry.ID, entry.Describe(), entry.Error))
+	}
+	if report.Offline {
+		sb.WriteString("Microsoft To-Do is still unreachable; the remaining changes stay queued.\n")
+	}
+	if sb.Len() == 0 {
+		sb.WriteString("Nothing to replay.\n")
+	}
+	return sb.String()
+}
+
+// queuedMessage tells the assistant that a write was queued rather than applied.
+func queuedMessage(entry *store.Entry) string {
+	return fmt.Sprintf("Microsoft To-Do could not be reached, so the change was queued as %s (%s). "+
+		"It will be applied with the next change made once the connection is back, or with pending_changes.", entry.ID, entry.Describe())
+}
@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+const recurrenceDescription = "Recurrence in Microsoft Graph patternedRecurrence format: " +
+	`{"pattern": {"type": "daily|weekly|absoluteMonthly|relativeMonthly|absoluteYearly|relativeYearly", "interval": 2, ` +
+	`"daysOfWeek": ["monday", "thursday"], "dayOfMonth": 15, "month": 3, "index": "first|second|third|fourth|last"}, ` +
+	`"range": {"type": "noEnd|endDate|numbered", "startDate": "2025-01-06", "endDate": "2027-01-01", "numberOfOccurrences": 10}}. ` +
+	"weekly needs daysOfWeek; absoluteMonthly needs dayOfMonth; relativeMonthly needs daysOfWeek and index; " +
+	"yearly types also need month. Range defaults to noEnd starting today."
+
+// recurrenceArg decodes the optional "recurrence" object argument.
+func recurrenceArg(request mcp.CallToolRequest) (*types.PatternedRecurrence, error) {
+	raw, ok := request.GetArguments()["recurrence"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence: %w", err)
+	}
+	var recurrence types.PatternedRecurrence
+	if err := json.Unmarshal(data, &recurrence); err != nil {
+		return nil, fmt.Errorf("invalid recurrence: %w", err)
+	}
+	return &recurrence, nil
+}
+
+// summarizeRecurrence describes a recurrence in words, e.g.
+// "every 2 weeks on Mon, Thu until 2027-01-01".
+func summarizeRecurrence(r *types.PatternedRecurrence) string {
+	if r == nil || r.Pattern == nil {
+		return ""
+	}
+	p := r.Pattern
+
+	var sb strings.Builder
+	switch p.Type {
+	case "daily":
+		sb.WriteString(every(p.Interval, "day"))
+	case "weekly":
+		sb.WriteString(every(p.Interval, "week"))
+		sb.WriteString(" on " + shortDays(p.DaysOfWeek))
+	case "absoluteMonthly":
+		sb.WriteString(every(p.Interval, "month"))
+		sb.WriteString(fmt.Sprintf(" on day %d", p.DayOfMonth))
+	case "relativeMonthly":
+		sb.WriteString(every(p.Interval, "month"))
+		sb.WriteString(fmt.Sprintf(" on the %s %s", indexOrFirst(p.Index), shortDays(p.DaysOfWeek)))
+	case "absoluteYearly":
+		sb.WriteString(every(p.Interval, "year"))
+		sb.WriteString(fmt.Sprintf(" on %s %d", shortMonth(p.Month), p.DayOfMonth))
+	case "relativeYearly":
+		sb.WriteString(every(p.Interval, "year"))
+		sb.WriteString(fmt.Sprintf(" on the %s %s of %s", indexOrFirst(p.Index), shortDays(p.DaysOfWeek), shortMonth(p.Month)))
+	default:
+		sb.WriteString(p.Type)
+	}
+
+	if r.Range != nil {
+		switch r.Range.Type {
+		case "endDate":
+			sb.WriteString(" until " + r.Range.EndDate)
+		case "numbered":
+			sb.WriteString(fmt.Sprintf(", %d times", r.Range.NumberOfOccurrences))
+		}
+	}
+	return sb.String()
+}
+
+func every(interval int, unit string) string {
+	if interval <= 1 {
+		return "every " + unit
+	}
+	return fmt.Sprintf("every %d %ss", interval, unit)
+}
+
+func indexOrFirst(index string) string {
+	if index == "" {
+		return "first"
+	}
+	return index
+}
+
+func shortDays(days []string) string {
+	short := make([]string, len(days))
+	for i, day := range days {
+		if len(day) >= 3 {
+			short[i] = strings.ToUpper(day[:1]) + day[1:3]
+		} else {
+			short[i] = day
+		}
+	}
+	return strings.Join(short, ", ")
+}
+
+func shortMonth(month int) string {
+	if month < 1 || month > 12 {
+		return fmt.Sprintf("month %d", month)
+	}
+	return time.Month(month).String()[:3]
+}
@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+)
+
+func renameListTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"rename_list",
+		mcp.WithDescription("Rename a Microsoft To-Do task list"),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list to rename"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"display_name",
+			mcp.Description("The new name of the task list"),
+			mcp.Required(),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		displayName := request.GetString("display_name", "")
+		if listID == "" || displayName == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id and display_name are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		list, err := graphClient.RenameList(ctx, listID, displayName)
+		if err != nil {
+			return errorResult(err, listResource), nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("List renamed to \"%s\".", list.DisplayName)}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
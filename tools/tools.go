@@ -3,21 +3,68 @@ package tools
 
 import (
 	"github.com/mark3labs/mcp-go/server"
-
-	"github.com/michMartineau/mcp-server-microsoft-todo/auth"
-	"github.com/michMartineau/mcp-server-microsoft-todo/client"
 )
 
 // Register adds all Microsoft To-Do tools to the MCP server.
-func Register(srv *server.MCPServer, graphClient *client.GraphClient, tokenManager *auth.TokenManager) {
+//
+// Tools work with the active account, which starts as active, or with the
+// account named by their account argument. Accounts are opened with open
+// when first used. The accounts that exist are active, those in
+// configured, those with stored tokens and those created by login.
+func Register(srv *server.MCPServer, open OpenAccount, active string, configured []string) error {
+	accounts := newAccounts(open, active, configured)
+	account, err := accounts.create(active)
+	if err != nil {
+		return err
+	}
+
+	for _, tool := range accountTools(account.Account) {
+		srv.AddTool(withAccountArgument(tool.Tool), accounts.dispatch(tool.Tool.Name))
+	}
 	srv.AddTools(
+		listAccountsTool(accounts),
+		switchAccountTool(accounts),
+		copyTaskTool(accounts),
+	)
+	return nil
+}
+
+// accountTools returns the tools that work with a single account.
+// Read tools go through the account's cache, which is kept up to date by
+// the writes made through its Graph client. Task writes go through its
+// queue, which holds them while Graph is unreachable.
+func accountTools(account *Account) []server.ServerTool {
+	graphClient, tokenManager, cache, queue := account.Graph, account.Tokens, account.Cache, account.Queue
+	return []server.ServerTool{
 		loginTool(tokenManager),
 		loginCompleteTool(tokenManager),
-		listTodoListsTool(graphClient),
-		listTasksTool(graphClient),
-		createTaskTool(graphClient),
-		completeTaskTool(graphClient),
-		deleteTaskTool(graphClient),
+		listTodoListsTool(graphClient, cache),
+		listTasksTool(cache),
+		listCategoriesTool(cache),
+		whatsChangedTool(graphClient),
+		createTaskTool(queue),
+		updateTaskTool(cache, queue),
+		completeTaskTool(queue),
+		deleteTaskTool(queue),
+		bulkUpdateTasksTool(graphClient, queue),
+		bulkCreateTasksTool(graphClient, queue),
+		pendingChangesTool(queue),
+		diagnosticsTool(graphClient),
+		moveTaskTool(graphClient),
 		createListTool(graphClient),
-	)
-}
\ No newline at end of file
+		renameListTool(graphClient),
+		deleteListTool(graphClient),
+		listChecklistItemsTool(graphClient),
+		addChecklistItemTool(graphClient),
+		updateChecklistItemTool(graphClient),
+		checkChecklistItemTool(graphClient),
+		deleteChecklistItemTool(graphClient),
+		addLinkTool(graphClient),
+		listLinksTool(graphClient),
+		removeLinkTool(graphClient),
+		attachFileTool(graphClient),
+		listAttachmentsTool(graphClient),
+		downloadAttachmentTool(graphClient),
+		deleteAttachmentTool(graphClient),
+	}
+}
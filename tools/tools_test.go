@@ -0,0 +1,565 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/auth"
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/graphtest"
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+// testEnv is an MCP server with every tool, signed in to a fake Graph.
+// Accounts other than the default one each have their own fake Graph and
+// start signed out.
+type testEnv struct {
+	srv *graphtest.Server
+	tm  *auth.TokenManager
+	mcp *server.MCPServer
+
+	servers map[string]*graphtest.Server
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	srv := graphtest.NewServer()
+	t.Cleanup(srv.Close)
+	env := &testEnv{srv: srv, servers: map[string]*graphtest.Server{auth.DefaultProfile: srv}}
+
+	dir := t.TempDir()
+	open := func(profile string) (*Account, error) {
+		srv := env.server(t, profile)
+		tm, err := auth.NewTokenManager("test-client", auth.WithAuthorityHost(srv.AuthorityHost()), auth.WithConfigDir(dir), auth.WithProfile(profile))
+		if err != nil {
+			return nil, err
+		}
+		if profile == auth.DefaultProfile {
+			if err := tm.SaveTokens(srv.Tokens()); err != nil {
+				return nil, err
+			}
+			env.tm = tm
+		}
+
+		graphClient := client.NewGraphClient(tm,
+			client.WithBaseURL(srv.GraphURL()),
+			client.WithRetryPolicy(client.RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}),
+		)
+		cache := store.NewCache(graphClient, tm.StatePath("cache.json"), time.Minute)
+		queue := store.NewQueue(graphClient, cache, tm.StatePath("queue.json"))
+		return &Account{Tokens: tm, Graph: graphClient, Cache: cache, Queue: queue}, nil
+	}
+
+	env.mcp = server.NewMCPServer("microsoft-todo-test", "0.0.0")
+	if err := Register(env.mcp, open, auth.DefaultProfile, nil); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return env
+}
+
+// server returns the fake Graph of an account, starting it on first use.
+func (e *testEnv) server(t *testing.T, account string) *graphtest.Server {
+	srv, ok := e.servers[account]
+	if !ok {
+		srv = graphtest.NewServer()
+		t.Cleanup(srv.Close)
+		e.servers[account] = srv
+	}
+	return srv
+}
+
+// call runs a tool and returns its text and whether it reported an error.
+func (e *testEnv) call(t *testing.T, name string, args map[string]any) (string, bool) {
+	t.Helper()
+	tool := e.mcp.GetTool(name)
+	if tool == nil {
+		t.Fatalf("tool %q is not registered", name)
+	}
+	var request mcp.CallToolRequest
+	request.Params.Name = name
+	request.Params.Arguments = args
+
+	result, err := tool.Handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	var sb strings.Builder
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			sb.WriteString(text.Text)
+		}
+	}
+	return sb.String(), result.IsError
+}
+
+// mustCall runs a tool that must succeed and returns its text.
+func (e *testEnv) mustCall(t *testing.T, name string, args map[string]any) string {
+	t.Helper()
+	text, isError := e.call(t, name, args)
+	if isError {
+		t.Fatalf("%s failed: %s", name, text)
+	}
+	return text
+}
+
+func assertContains(t *testing.T, text string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(text, w) {
+			t.Errorf("result does not contain %q:\n%s", w, text)
+		}
+	}
+}
+
+func TestLoginTools(t *testing.T) {
+	env := newTestEnv(t)
+	if err := env.tm.ClearTokens(); err != nil {
+		t.Fatal(err)
+	}
+
+	text, isError := env.call(t, "login_complete", nil)
+	if !isError {
+		t.Errorf("login_complete without login succeeded: %s", text)
+	}
+
+	text = env.mustCall(t, "login", nil)
+	code := regexp.MustCompile(`Enter code: (\S+)`).FindStringSubmatch(text)
+	if code == nil {
+		t.Fatalf("login did not return a code:\n%s", text)
+	}
+	env.srv.ApproveDeviceCode(code[1])
+
+	text = env.mustCall(t, "login_complete", nil)
+	assertContains(t, text, "Authentication successful", "Signed in as user@example.com (personal Microsoft account)")
+	if _, err := env.tm.GetValidToken(context.Background()); err != nil {
+		t.Errorf("no valid token after login: %v", err)
+	}
+}
+
+func TestBrowserLoginTool(t *testing.T) {
+	env := newTestEnv(t)
+	if err := env.tm.ClearTokens(); err != nil {
+		t.Fatal(err)
+	}
+
+	text := env.mustCall(t, "login", map[string]any{"flow": "browser"})
+	authURL := regexp.MustCompile(`http://\S+/authorize\?\S+`).FindString(text)
+	if authURL == "" {
+		t.Fatalf("login did not return a sign-in URL:\n%s", text)
+	}
+
+	// Sign in as the browser would; the fake redirects back to the server.
+	resp, err := http.Get(authURL)
+	if err != nil {
+		t.Fatalf("opening the sign-in URL: %v", err)
+	}
+	resp.Body.Close()
+
+	text = env.mustCall(t, "login_complete", nil)
+	assertContains(t, text, "Authentication successful", "personal Microsoft account")
+	if text, isError := env.call(t, "login_complete", nil); !isError {
+		t.Errorf("second login_complete succeeded: %s", text)
+	}
+}
+
+func TestListTools(t *testing.T) {
+	env := newTestEnv(t)
+	defaultList := env.srv.AddList(types.TodoTaskList{DisplayName: "Tasks", WellknownName: "defaultList"})
+
+	env.mustCall(t, "create_list", map[string]any{"display_name": "Groceries"})
+	lists := env.srv.Lists()
+	if len(lists) != 2 {
+		t.Fatalf("lists = %+v, want Tasks and Groceries", lists)
+	}
+	groceries := lists[1]
+
+	text := env.mustCall(t, "list_todo_lists", nil)
+	assertContains(t, text, "Tasks", "Groceries", groceries.ID)
+
+	env.mustCall(t, "rename_list", map[string]any{"list_id": groceries.ID, "display_name": "Shopping"})
+	text = env.mustCall(t, "list_todo_lists", map[string]any{"name": "Shopping"})
+	assertContains(t, text, "Shopping")
+
+	if text, isError := env.call(t, "delete_list", map[string]any{"list_id": defaultList.ID}); !isError {
+		t.Errorf("deleting the built-in list succeeded: %s", text)
+	}
+	env.mustCall(t, "delete_list", map[string]any{"list_id": groceries.ID})
+	if lists := env.srv.Lists(); len(lists) != 1 {
+		t.Errorf("lists after delete = %+v", lists)
+	}
+}
+
+func TestTaskTools(t *testing.T) {
+	env := newTestEnv(t)
+	list := env.srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+
+	text := env.mustCall(t, "create_task", map[string]any{
+		"list_id":    list.ID,
+		"title":      "Buy milk",
+		"importance": "high",
+		"due_date":   "2025-03-01",
+		"categories": []any{"Errands"},
+	})
+	assertContains(t, text, `"Buy milk" created`)
+	env.mustCall(t, "create_task", map[string]any{"list_id": list.ID, "title": "Call bank"})
+	tasks := env.srv.Tasks(list.ID)
+	if len(tasks) != 2 {
+		t.Fatalf("tasks = %+v", tasks)
+	}
+	milk := tasks[0]
+	env.mustCall(t, "add_checklist_item", map[string]any{"list_id": list.ID, "task_id": milk.ID, "display_name": "Check expiry date"})
+	env.mustCall(t, "add_link", map[string]any{"list_id": list.ID, "task_id": milk.ID, "web_url": "https://example.com/shop"})
+
+	text = env.mustCall(t, "list_tasks", map[string]any{"list_id": list.ID})
+	assertContains(t, text, "Buy milk", "Call bank", "Steps:", "Check expiry date", "https://example.com/shop")
+	text = env.mustCall(t, "list_tasks", map[string]any{"list_id": list.ID, "status": "open"})
+	assertContains(t, text, "Buy milk", "Steps:", "Check expiry date", "https://example.com/shop")
+
+	text = env.mustCall(t, "update_task", map[string]any{"list_id": list.ID, "task_id": milk.ID, "title": "Buy oat milk", "due_date": ""})
+	assertContains(t, text, `"Buy oat milk" updated`, `Title: "Buy milk" → "Buy oat milk"`, "Due: ")
+	if text, isError := env.call(t, "update_task", map[string]any{"list_id": list.ID, "task_id": milk.ID, "title": " "}); !isError {
+		t.Errorf("an empty title was accepted: %s", text)
+	}
+	if task, _ := env.srv.Task(list.ID, milk.ID); task.Title != "Buy oat milk" || task.DueDateTime != nil {
+		t.Errorf("updated task = %+v", task)
+	}
+
+	env.mustCall(t, "complete_task", map[string]any{"list_id": list.ID, "task_id": milk.ID})
+	text = env.mustCall(t, "list_tasks", map[string]any{"list_id": list.ID, "status": "completed"})
+	assertContains(t, text, "Buy oat milk")
+	if strings.Contains(text, "Call bank") {
+		t.Errorf("completed tasks include an open task:\n%s", text)
+	}
+
+	text = env.mustCall(t, "list_categories", nil)
+	assertContains(t, text, "Errands (1 tasks)")
+
+	env.mustCall(t, "delete_task", map[string]any{"list_id": list.ID, "task_id": milk.ID})
+	if _, ok := env.srv.Task(list.ID, milk.ID); ok {
+		t.Error("the task was not deleted")
+	}
+}
+
+func TestListTasksCategoryWithLimit(t *testing.T) {
+	env := newTestEnv(t)
+	list := env.srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	env.srv.AddTask(list.ID, types.TodoTask{Title: "Write docs"})
+	env.srv.AddTask(list.ID, types.TodoTask{Title: "Fix CSS", Categories: []string{"frontend"}})
+	env.mustCall(t, "create_task", map[string]any{"list_id": list.ID, "title": "Fix API", "categories": []any{"#Backend"}})
+	args := map[string]any{"list_id": list.ID, "category": "#Backend", "limit": 1}
+
+	// From Graph, then from the cache filled by an unfiltered listing.
+	text := env.mustCall(t, "list_tasks", args)
+	assertContains(t, text, "Fix API")
+	env.mustCall(t, "list_tasks", map[string]any{"list_id": list.ID})
+	text = env.mustCall(t, "list_tasks", args)
+	assertContains(t, text, "Fix API", "From local cache")
+	if strings.Contains(text, "Write docs") || strings.Contains(text, "Fix CSS") {
+		t.Errorf("tasks of other categories were listed:\n%s", text)
+	}
+}
+
+func TestMoveTaskTool(t *testing.T) {
+	env := newTestEnv(t)
+	inbox := env.srv.AddList(types.TodoTaskList{DisplayName: "Inbox"})
+	work := env.srv.AddList(types.TodoTaskList{DisplayName: "Work"})
+	task := env.srv.AddTask(inbox.ID, types.TodoTask{Title: "Review PR"})
+
+	text := env.mustCall(t, "move_task", map[string]any{"list_id": inbox.ID, "task_id": task.ID, "target_list_id": work.ID})
+	assertContains(t, text, `"Review PR" moved`)
+	if len(env.srv.Tasks(inbox.ID)) != 0 || len(env.srv.Tasks(work.ID)) != 1 {
+		t.Error("the task was not moved")
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	env := newTestEnv(t)
+	inbox := env.srv.AddList(types.TodoTaskList{DisplayName: "Inbox"})
+	task := env.srv.AddTask(inbox.ID, types.TodoTask{Title: "Review PR", Body: &types.ItemBody{Content: "See #42", ContentType: "text"}})
+	work := env.server(t, "work")
+	work.SetAccount(graphtest.Account{Username: "jane@outlook.com", Name: "Jane", TenantID: graphtest.PersonalTenantID})
+	projects := work.AddList(types.TodoTaskList{DisplayName: "Projects"})
+
+	text := env.mustCall(t, "list_accounts", nil)
+	assertContains(t, text, "default (active): user@example.com (personal Microsoft account)")
+
+	// Only login creates an account; a mistyped name is an error.
+	for _, tool := range []string{"list_todo_lists", "switch_account"} {
+		text, isError := env.call(t, tool, map[string]any{"account": "work"})
+		if !isError || !strings.Contains(text, `unknown account "work"`) {
+			t.Errorf("%s with an unknown account: %s", tool, text)
+		}
+	}
+	if text, isError := env.call(t, "list_todo_lists", map[string]any{"account": "../work"}); !isError {
+		t.Errorf("an invalid account name was accepted: %s", text)
+	}
+
+	text = env.mustCall(t, "login", map[string]any{"account": "work"})
+	code := regexp.MustCompile(`Enter code: (\S+)`).FindStringSubmatch(text)
+	if code == nil {
+		t.Fatalf("login did not return a code:\n%s", text)
+	}
+	text, isError := env.call(t, "list_todo_lists", map[string]any{"account": "work"})
+	if !isError || !strings.Contains(text, "Not signed in") {
+		t.Errorf("listing a signed-out account: %s", text)
+	}
+	work.ApproveDeviceCode(code[1])
+	text = env.mustCall(t, "login_complete", map[string]any{"account": "work"})
+	assertContains(t, text, "Signed in as jane@outlook.com")
+
+	text = env.mustCall(t, "list_accounts", nil)
+	assertContains(t, text, "default (active): user@example.com", "work: jane@outlook.com")
+
+	// The account argument picks the account; without it the active one is used.
+	text = env.mustCall(t, "list_todo_lists", map[string]any{"account": "work"})
+	assertContains(t, text, "Projects")
+	if strings.Contains(text, "Inbox") {
+		t.Errorf("the work account shows the default account's lists:\n%s", text)
+	}
+	text = env.mustCall(t, "switch_account", map[string]any{"account": "work"})
+	assertContains(t, text, "Switched to account work, signed in as jane@outlook.com")
+	text = env.mustCall(t, "list_todo_lists", nil)
+	assertContains(t, text, "Projects")
+
+	text = env.mustCall(t, "copy_task", map[string]any{
+		"account":        "default",
+		"list_id":        inbox.ID,
+		"task_id":        task.ID,
+		"target_account": "work",
+		"target_list_id": projects.ID,
+	})
+	assertContains(t, text, `"Review PR" copied to account work`)
+	copies := work.Tasks(projects.ID)
+	if len(copies) != 1 || copies[0].Title != "Review PR" || copies[0].Body == nil || copies[0].Body.Content != "See #42" {
+		t.Errorf("tasks in the work account = %+v", copies)
+	}
+	if _, ok := env.srv.Task(inbox.ID, task.ID); !ok {
+		t.Error("copying removed the original task")
+	}
+}
+
+func TestBulkTools(t *testing.T) {
+	env := newTestEnv(t)
+	list := env.srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+
+	text := env.mustCall(t, "bulk_create_tasks", map[string]any{
+		"list_id": list.ID,
+		"tasks": []any{
+			map[string]any{"title": "One"},
+			map[string]any{"title": "Two", "importance": "high"},
+			map[string]any{"title": "Three", "categories": []any{"Home"}},
+		},
+	})
+	assertContains(t, text, "3 created, 0 failed")
+
+	var ids []any
+	for _, task := range env.srv.Tasks(list.ID) {
+		ids = append(ids, task.ID)
+	}
+	ids = append(ids, "missing")
+	text, _ = env.call(t, "bulk_update_tasks", map[string]any{"list_id": list.ID, "task_ids": ids, "action": "complete"})
+	assertContains(t, text, "3 completed, 1 failed", "Task not found")
+	for _, task := range env.srv.Tasks(list.ID) {
+		if task.Status != "completed" {
+			t.Errorf("task %q is %s, want completed", task.Title, task.Status)
+		}
+	}
+}
+
+func TestChecklistTools(t *testing.T) {
+	env := newTestEnv(t)
+	list := env.srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	task := env.srv.AddTask(list.ID, types.TodoTask{Title: "Pack"})
+	args := func(extra map[string]any) map[string]any {
+		m := map[string]any{"list_id": list.ID, "task_id": task.ID}
+		for k, v := range extra {
+			m[k] = v
+		}
+		return m
+	}
+
+	env.mustCall(t, "add_checklist_item", args(map[string]any{"display_name": "Passport"}))
+	current, _ := env.srv.Task(list.ID, task.ID)
+	itemID := current.ChecklistItems[0].ID
+
+	env.mustCall(t, "update_checklist_item", args(map[string]any{"item_id": itemID, "display_name": "Passport and visa"}))
+	env.mustCall(t, "check_checklist_item", args(map[string]any{"item_id": itemID, "checked": true}))
+	text := env.mustCall(t, "list_checklist_items", args(nil))
+	assertContains(t, text, "Passport and visa")
+	if current, _ := env.srv.Task(list.ID, task.ID); !current.ChecklistItems[0].IsChecked {
+		t.Error("the item was not checked")
+	}
+
+	env.mustCall(t, "delete_checklist_item", args(map[string]any{"item_id": itemID}))
+	text = env.mustCall(t, "list_checklist_items", args(nil))
+	assertContains(t, text, "no checklist items")
+
+	text, isError := env.call(t, "delete_checklist_item", args(map[string]any{"item_id": itemID}))
+	if !isError {
+		t.Fatal("deleting a deleted item succeeded")
+	}
+	assertContains(t, text, "Checklist item not found", "list_checklist_items")
+}
+
+func TestLinkTools(t *testing.T) {
+	env := newTestEnv(t)
+	list := env.srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	task := env.srv.AddTask(list.ID, types.TodoTask{Title: "Review"})
+
+	env.mustCall(t, "add_link", map[string]any{
+		"list_id": list.ID, "task_id": task.ID,
+		"web_url": "https://example.com/doc", "display_name": "Design doc", "application_name": "Docs",
+	})
+	current, _ := env.srv.Task(list.ID, task.ID)
+	if len(current.LinkedResources) != 1 {
+		t.Fatalf("linked resources = %+v", current.LinkedResources)
+	}
+
+	text := env.mustCall(t, "list_links", map[string]any{"list_id": list.ID, "task_id": task.ID})
+	assertContains(t, text, "Design doc", "https://example.com/doc")
+
+	env.mustCall(t, "remove_link", map[string]any{"list_id": list.ID, "task_id": task.ID, "link_id": current.LinkedResources[0].ID})
+	text = env.mustCall(t, "list_links", map[string]any{"list_id": list.ID, "task_id": task.ID})
+	assertContains(t, text, "no linked resources")
+}
+
+func TestAttachmentTools(t *testing.T) {
+	env := newTestEnv(t)
+	list := env.srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	task := env.srv.AddTask(list.ID, types.TodoTask{Title: "Taxes"})
+	dir := t.TempDir()
+	path := filepath.Join(dir, "receipt.txt")
+	if err := os.WriteFile(path, []byte("total: 42"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	text := env.mustCall(t, "attach_file", map[string]any{"list_id": list.ID, "task_id": task.ID, "file_path": path})
+	id := regexp.MustCompile(`\(ID: (\S+)\)`).FindStringSubmatch(text)
+	if id == nil {
+		t.Fatalf("attach_file did not return an ID:\n%s", text)
+	}
+
+	text = env.mustCall(t, "list_attachments", map[string]any{"list_id": list.ID, "task_id": task.ID})
+	assertContains(t, text, "receipt.txt")
+
+	out := t.TempDir()
+	env.mustCall(t, "download_attachment", map[string]any{"list_id": list.ID, "task_id": task.ID, "attachment_id": id[1], "output_path": out})
+	data, err := os.ReadFile(filepath.Join(out, "receipt.txt"))
+	if err != nil || string(data) != "total: 42" {
+		t.Errorf("downloaded %q, %v", data, err)
+	}
+
+	// An existing file is only replaced when asked to.
+	if err := os.WriteFile(filepath.Join(out, "receipt.txt"), []byte("keep me"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	text, isError := env.call(t, "download_attachment", map[string]any{"list_id": list.ID, "task_id": task.ID, "attachment_id": id[1], "output_path": out})
+	if !isError || !strings.Contains(text, "already exists") {
+		t.Errorf("downloading over an existing file: %s", text)
+	}
+	if data, _ := os.ReadFile(filepath.Join(out, "receipt.txt")); string(data) != "keep me" {
+		t.Errorf("the existing file was changed to %q", data)
+	}
+	env.mustCall(t, "download_attachment", map[string]any{"list_id": list.ID, "task_id": task.ID, "attachment_id": id[1], "output_path": out, "overwrite": true})
+	if data, _ := os.ReadFile(filepath.Join(out, "receipt.txt")); string(data) != "total: 42" {
+		t.Errorf("overwritten file = %q", data)
+	}
+
+	env.mustCall(t, "delete_attachment", map[string]any{"list_id": list.ID, "task_id": task.ID, "attachment_id": id[1]})
+	if text, isError := env.call(t, "download_attachment", map[string]any{"list_id": list.ID, "task_id": task.ID, "attachment_id": id[1], "output_path": out}); !isError {
+		t.Errorf("downloading a deleted attachment succeeded: %s", text)
+	}
+}
+
+func TestOfflineChangesAreQueued(t *testing.T) {
+	env := newTestEnv(t)
+	list := env.srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+
+	env.srv.SetOffline(true)
+	text := env.mustCall(t, "create_task", map[string]any{"list_id": list.ID, "title": "Written offline"})
+	assertContains(t, text, "queued")
+	text = env.mustCall(t, "pending_changes", nil)
+	assertContains(t, text, "1 pending change(s)", "Written offline")
+
+	env.srv.SetOffline(false)
+	text = env.mustCall(t, "pending_changes", map[string]any{"action": "replay"})
+	assertContains(t, text, "Applied")
+	if tasks := env.srv.Tasks(list.ID); len(tasks) != 1 || tasks[0].Title != "Written offline" {
+		t.Errorf("tasks after replay = %+v", tasks)
+	}
+	text = env.mustCall(t, "pending_changes", nil)
+	assertContains(t, text, "No pending changes.")
+
+	env.srv.Fail(graphtest.Failure{Method: "POST", Disconnect: true, AfterProcessing: true, Times: 1})
+	text, isError := env.call(t, "create_task", map[string]any{"list_id": list.ID, "title": "Lost answer"})
+	if !isError {
+		t.Fatalf("a create cut off after it was sent succeeded: %s", text)
+	}
+	assertContains(t, text, "may have been created", "list_tasks")
+	text = env.mustCall(t, "pending_changes", nil)
+	assertContains(t, text, "No pending changes.")
+}
+
+func TestWhatsChanged(t *testing.T) {
+	env := newTestEnv(t)
+	list := env.srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+	env.srv.AddTask(list.ID, types.TodoTask{Title: "Existing"})
+
+	text := env.mustCall(t, "whats_changed", nil)
+	assertContains(t, text, "baseline recorded (1 tasks)")
+
+	env.srv.AddTask(list.ID, types.TodoTask{Title: "Added elsewhere"})
+	text = env.mustCall(t, "whats_changed", nil)
+	assertContains(t, text, "Added elsewhere", `List "Tasks"`)
+	if strings.Contains(text, "Existing") {
+		t.Errorf("unchanged task reported:\n%s", text)
+	}
+	if requests := env.srv.Requests(); slices.Contains(requests, "GET /me/todo/lists") {
+		t.Errorf("requests = %v, want the lists taken from the delta", requests)
+	}
+}
+
+func TestErrorMessages(t *testing.T) {
+	env := newTestEnv(t)
+	list := env.srv.AddList(types.TodoTaskList{DisplayName: "Tasks"})
+
+	text, isError := env.call(t, "update_task", map[string]any{"list_id": list.ID, "task_id": "missing", "title": "x"})
+	if !isError {
+		t.Fatal("updating a missing task succeeded")
+	}
+	assertContains(t, text, "Task not found", "list_tasks", "request-id")
+
+	env.srv.Fail(graphtest.Failure{Status: 429, RetryAfter: 30})
+	text, _ = env.call(t, "rename_list", map[string]any{"list_id": list.ID, "display_name": "Renamed"})
+	assertContains(t, text, "throttling", "Wait 30s")
+	env.srv.ClearFailures()
+
+	text = env.mustCall(t, "diagnostics", nil)
+	assertContains(t, text, "Throttled (429): 3", "asked to wait 30s")
+
+	expired := env.srv.Tokens()
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := env.tm.SaveTokens(expired); err != nil {
+		t.Fatal(err)
+	}
+	env.srv.FailTokens(graphtest.Failure{Status: 503, Code: "temporarily_unavailable", Times: 1})
+	text, _ = env.call(t, "rename_list", map[string]any{"list_id": list.ID, "display_name": "Renamed"})
+	assertContains(t, text, "sign-in service is unavailable", "still valid")
+
+	if err := env.tm.ClearTokens(); err != nil {
+		t.Fatal(err)
+	}
+	text, _ = env.call(t, "delete_list", map[string]any{"list_id": list.ID})
+	assertContains(t, text, "Not signed in", "login")
+}
@@ -0,0 +1,227 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/store"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+func updateTaskTool(cache *store.Cache, queue *store.Queue) server.ServerTool {
+	tool := mcp.NewTool(
+		"update_task",
+		mcp.WithDescription("Update fields of an existing Microsoft To-Do task. Only the fields you pass are changed."),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("The ID of the task list containing the task"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"task_id",
+			mcp.Description("The ID of the task to update"),
+			mcp.Required(),
+		),
+		mcp.WithString(
+			"title",
+			mcp.Description("New title for the task"),
+		),
+		mcp.WithString(
+			"body",
+			mcp.Description("New description/notes for the task. Pass an empty string to clear."),
+		),
+		mcp.WithString(
+			"importance",
+			mcp.Description("New importance level"),
+			mcp.Enum("low", "normal", "high"),
+		),
+		mcp.WithString(
+			"status",
+			mcp.Description("New task status"),
+			mcp.Enum("notStarted", "inProgress", "completed", "waitingOnOthers", "deferred"),
+		),
+		mcp.WithString(
+			"due_date",
+			mcp.Description("New due date in ISO 8601 format (e.g. 2025-12-31T17:00:00). Pass an empty string to clear."),
+		),
+		mcp.WithString(
+			"start_date",
+			mcp.Description("New start date in ISO 8601 format. Pass an empty string to clear."),
+		),
+		mcp.WithString(
+			"reminder_date",
+			mcp.Description("New reminder date and time in ISO 8601 format. Pass an empty string to turn the reminder off."),
+		),
+		mcp.WithString(
+			"time_zone",
+			mcp.Description("IANA time zone the new dates are in, e.g. Europe/Paris (default UTC)"),
+		),
+		mcp.WithArray(
+			"categories",
+			mcp.Description("New categories (tags), replacing the existing ones. Pass an empty array to remove all."),
+			mcp.WithStringItems(),
+		),
+		mcp.WithObject(
+			"recurrence",
+			mcp.Description(recurrenceDescription+" Pass an empty object to remove the recurrence."),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		listID := request.GetString("list_id", "")
+		taskID := request.GetString("task_id", "")
+		if listID == "" || taskID == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: list_id and task_id are required"}},
+				IsError: true,
+			}, nil
+		}
+
+		recurrence, err := recurrenceArg(request)
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+
+		update := client.TaskUpdate{
+			Title:        optionalString(request, "title"),
+			Body:         optionalString(request, "body"),
+			Importance:   optionalString(request, "importance"),
+			Status:       optionalString(request, "status"),
+			DueDate:      optionalString(request, "due_date"),
+			StartDate:    optionalString(request, "start_date"),
+			ReminderDate: optionalString(request, "reminder_date"),
+			Recurrence:   recurrence,
+			Categories:   optionalStringSlice(request, "categories"),
+			TimeZone:     request.GetString("time_zone", ""),
+		}
+		if update.Title != nil && strings.TrimSpace(*update.Title) == "" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: title cannot be empty"}},
+				IsError: true,
+			}, nil
+		}
+		if update.IsEmpty() {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: "Error: no fields to update were provided"}},
+				IsError: true,
+			}, nil
+		}
+
+		// The last known version is only needed for the summary, so it is
+		// taken from the cache rather than fetched, which would be slow
+		// while Graph is unreachable. The queue fills in the entry's title
+		// and base from the cache as well.
+		before, _ := cache.Task(listID, taskID)
+
+		entry := store.Entry{Op: store.OpUpdate, ListID: listID, TaskID: taskID, Update: &update}
+		result, err := queue.Submit(ctx, entry)
+		if err != nil {
+			return errorResult(err, taskResource), nil
+		}
+		if result.Queued != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: queuedMessage(result.Queued)}},
+			}, nil
+		}
+		after := result.Task
+		if before == nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf("Task \"%s\" updated.", after.Title)}},
+			}, nil
+		}
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("Task \"%s\" updated.\n", after.Title))
+		changes := diffTasks(*before, *after)
+		if len(changes) == 0 {
+			sb.WriteString("No fields changed.\n")
+		}
+		for _, change := range changes {
+			sb.WriteString(fmt.Sprintf("  %s\n", change))
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: sb.String()}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+// optionalString returns a pointer to the string argument, or nil if the
+// caller did not supply it. An explicit empty string is returned as "".
+func optionalString(request mcp.CallToolRequest, key string) *string {
+	value, ok := request.GetArguments()[key].(string)
+	if !ok {
+		return nil
+	}
+	return &value
+}
+
+// optionalStringSlice returns the string array argument, or nil if the
+// caller did not supply it. An explicit empty array is returned as non-nil.
+func optionalStringSlice(request mcp.CallToolRequest, key string) []string {
+	if _, ok := request.GetArguments()[key]; !ok {
+		return nil
+	}
+	return request.GetStringSlice(key, []string{})
+}
+
+// diffTasks describes the fields that differ between two versions of a task.
+func diffTasks(before, after types.TodoTask) []string {
+	var changes []string
+	add := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", field, quoteOrNone(old), quoteOrNone(new)))
+		}
+	}
+
+	add("Title", before.Title, after.Title)
+	add("Notes", bodyContent(before.Body), bodyContent(after.Body))
+	add("Importance", before.Importance, after.Importance)
+	add("Status", before.Status, after.Status)
+	add("Due", formatDateTime(before.DueDateTime), formatDateTime(after.DueDateTime))
+	add("Start", formatDateTime(before.StartDateTime), formatDateTime(after.StartDateTime))
+	add("Reminder", reminder(before), reminder(after))
+	add("Categories", strings.Join(before.Categories, ", "), strings.Join(after.Categories, ", "))
+	add("Recurrence", summarizeRecurrence(before.Recurrence), summarizeRecurrence(after.Recurrence))
+	return changes
+}
+
+func quoteOrNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return fmt.Sprintf("%q", s)
+}
+
+func bodyContent(body *types.ItemBody) string {
+	if body == nil {
+		return ""
+	}
+	return body.Content
+}
+
+func reminder(task types.TodoTask) string {
+	if !task.IsReminderOn {
+		return ""
+	}
+	return formatDateTime(task.ReminderDateTime)
+}
+
+// formatDateTime renders a Graph date/time without its trailing fractional seconds.
+func formatDateTime(dt *types.DateTimeZone) string {
+	if dt == nil || dt.DateTime == "" {
+		return ""
+	}
+	value, _, _ := strings.Cut(dt.DateTime, ".")
+	if dt.TimeZone == "" {
+		return value
+	}
+	return value + " " + dt.TimeZone
+}
@@ -0,0 +1,148 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/michMartineau/mcp-server-microsoft-todo/client"
+	"github.com/michMartineau/mcp-server-microsoft-todo/types"
+)
+
+func whatsChangedTool(graphClient *client.GraphClient) server.ServerTool {
+	tool := mcp.NewTool(
+		"whats_changed",
+		mcp.WithDescription("Show which Microsoft To-Do tasks were added, completed, edited or deleted since the last time this tool was called. The first call records a baseline."),
+		mcp.WithString(
+			"list_id",
+			mcp.Description("Optional ID of a single task list to check. Checks every list when omitted."),
+		),
+	)
+
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var sb strings.Builder
+		var lists []types.TodoTaskList
+
+		if listID := request.GetString("list_id", ""); listID != "" {
+			lists = []types.TodoTaskList{{ID: listID}}
+		} else {
+			changes, err := graphClient.SyncLists(ctx)
+			if err != nil {
+				return errorResult(err, anyResource), nil
+			}
+			// Lists that were not reported as changed still need their tasks checked.
+			lists = changes.All
+			if !changes.Since.IsZero() {
+				sb.WriteString(describeListChanges(changes.Lists))
+			}
+		}
+
+		listIDs := make([]string, len(lists))
+		for i, list := range lists {
+			listIDs[i] = list.ID
+		}
+		allChanges, failed, err := graphClient.SyncAllTasks(ctx, listIDs)
+		if err != nil {
+			return errorResult(err, anyResource), nil
+		}
+
+		for _, list := range lists {
+			if err := failed[list.ID]; err != nil {
+				sb.WriteString(fmt.Sprintf("List %s could not be checked: %s\n", listName(list), describeError(err, listResource)))
+				continue
+			}
+			changes := allChanges[list.ID]
+			if changes.Since.IsZero() {
+				sb.WriteString(fmt.Sprintf("List %s: baseline recorded (%d tasks). Changes will be reported from now on.\n", listName(list), len(changes.Tasks)))
+				continue
+			}
+			sb.WriteString(describeTaskChanges(list, changes))
+		}
+
+		if sb.Len() == 0 {
+			sb.WriteString("Nothing has changed since the last check.")
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.TextContent{Type: "text", Text: sb.String()}},
+		}, nil
+	}
+
+	return server.ServerTool{Tool: tool, Handler: handler}
+}
+
+func listName(list types.TodoTaskList) string {
+	if list.DisplayName == "" {
+		return fmt.Sprintf("`%s`", list.ID)
+	}
+	return fmt.Sprintf("\"%s\"", list.DisplayName)
+}
+
+func describeListChanges(lists []types.TodoTaskList) string {
+	var sb strings.Builder
+	for _, list := range lists {
+		if list.Removed != nil {
+			sb.WriteString(fmt.Sprintf("List `%s` was deleted.\n", list.ID))
+		} else {
+			sb.WriteString(fmt.Sprintf("List %s was added or renamed. (ID: `%s`)\n", listName(list), list.ID))
+		}
+	}
+	return sb.String()
+}
+
+// describeTaskChanges groups the changed tasks of a list into added,
+// completed, edited and deleted.
+func describeTaskChanges(list types.TodoTaskList, changes *client.TaskChanges) string {
+	var added, completed, edited, deleted []string
+	for _, task := range changes.Tasks {
+		line := fmt.Sprintf("    - %s (ID: `%s`)", task.Title, task.ID)
+		switch {
+		case task.Removed != nil:
+			deleted = append(deleted, fmt.Sprintf("    - ID: `%s`", task.ID))
+		case task.CreatedDateTime != nil && task.CreatedDateTime.After(changes.Since):
+			added = append(added, line)
+		case task.Status == "completed" && completedAfter(task, changes.Since):
+			completed = append(completed, line)
+		default:
+			edited = append(edited, line)
+		}
+	}
+	if len(added)+len(completed)+len(edited)+len(deleted) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("List %s, since %s:\n", listName(list), changes.Since.Format(time.RFC3339)))
+	for _, group := range []struct {
+		label string
+		lines []string
+	}{
+		{"Added", added},
+		{"Completed", completed},
+		{"Edited", edited},
+		{"Deleted", deleted},
+	} {
+		if len(group.lines) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  %s:\n%s\n", group.label, strings.Join(group.lines, "\n")))
+	}
+	return sb.String()
+}
+
+// completedAfter reports whether the task was completed after t. To-Do often
+// records only the completion day, so the comparison is made by UTC day.
+func completedAfter(task types.TodoTask, t time.Time) bool {
+	if task.CompletedDateTime == nil {
+		return false
+	}
+	value, _, _ := strings.Cut(task.CompletedDateTime.DateTime, ".")
+	completed, err := time.Parse("2006-01-02T15:04:05", value)
+	if err != nil {
+		return false
+	}
+	return !completed.Before(t.UTC().Truncate(24 * time.Hour))
+}
@@ -10,26 +10,108 @@ type TodoTaskList struct {
 	IsOwner       bool   `json:"isOwner"`
 	IsShared      bool   `json:"isShared"`
 	WellknownName string `json:"wellknownListName,omitempty"`
+
+	// Removed is set on delta query results for lists that were deleted.
+	Removed *Removed `json:"@removed,omitempty"`
 }
 
 // TodoTaskListsResponse is the API response for listing task lists.
 type TodoTaskListsResponse struct {
-	Value    []TodoTaskList `json:"value"`
-	NextLink string         `json:"@odata.nextLink,omitempty"`
+	Value     []TodoTaskList `json:"value"`
+	NextLink  string         `json:"@odata.nextLink,omitempty"`
+	DeltaLink string         `json:"@odata.deltaLink,omitempty"`
+}
+
+// Removed marks an item in a delta query result as deleted.
+type Removed struct {
+	Reason string `json:"reason"`
 }
 
 // TodoTask represents a single task in Microsoft To-Do.
 type TodoTask struct {
-	ID                   string          `json:"id,omitempty"`
-	Title                string          `json:"title"`
-	Body                 *ItemBody       `json:"body,omitempty"`
-	Importance           string          `json:"importance,omitempty"`
-	Status               string          `json:"status,omitempty"`
-	CreatedDateTime      *time.Time      `json:"createdDateTime,omitempty"`
-	LastModifiedDateTime *time.Time      `json:"lastModifiedDateTime,omitempty"`
-	DueDateTime          *DateTimeZone   `json:"dueDateTime,omitempty"`
-	CompletedDateTime    *DateTimeZone   `json:"completedDateTime,omitempty"`
+	ID                   string               `json:"id,omitempty"`
+	Title                string               `json:"title"`
+	Body                 *ItemBody            `json:"body,omitempty"`
+	Importance           string               `json:"importance,omitempty"`
+	Status               string               `json:"status,omitempty"`
+	CreatedDateTime      *time.Time           `json:"createdDateTime,omitempty"`
+	LastModifiedDateTime *time.Time           `json:"lastModifiedDateTime,omitempty"`
+	DueDateTime          *DateTimeZone        `json:"dueDateTime,omitempty"`
+	StartDateTime        *DateTimeZone        `json:"startDateTime,omitempty"`
+	ReminderDateTime     *DateTimeZone        `json:"reminderDateTime,omitempty"`
+	IsReminderOn         bool                 `json:"isReminderOn,omitempty"`
+	CompletedDateTime    *DateTimeZone        `json:"completedDateTime,omitempty"`
 	Recurrence           *PatternedRecurrence `json:"recurrence,omitempty"`
+	Categories           []string             `json:"categories,omitempty"`
+	ChecklistItems       []ChecklistItem      `json:"checklistItems,omitempty"`
+	LinkedResources      []LinkedResource     `json:"linkedResources,omitempty"`
+
+	// Removed is set on delta query results for tasks that were deleted.
+	Removed *Removed `json:"@removed,omitempty"`
+}
+
+// ChecklistItem represents a subtask (step) within a task.
+type ChecklistItem struct {
+	ID              string     `json:"id,omitempty"`
+	DisplayName     string     `json:"displayName"`
+	IsChecked       bool       `json:"isChecked"`
+	CreatedDateTime *time.Time `json:"createdDateTime,omitempty"`
+	CheckedDateTime *time.Time `json:"checkedDateTime,omitempty"`
+}
+
+// ChecklistItemsResponse is the API response for listing checklist items.
+type ChecklistItemsResponse struct {
+	Value    []ChecklistItem `json:"value"`
+	NextLink string          `json:"@odata.nextLink,omitempty"`
+}
+
+// LinkedResource links a task to an item in another application, such as
+// an issue tracker or wiki.
+type LinkedResource struct {
+	ID              string `json:"id,omitempty"`
+	WebURL          string `json:"webUrl,omitempty"`
+	ApplicationName string `json:"applicationName,omitempty"`
+	DisplayName     string `json:"displayName,omitempty"`
+	ExternalID      string `json:"externalId,omitempty"`
+}
+
+// LinkedResourcesResponse is the API response for listing linked resources.
+type LinkedResourcesResponse struct {
+	Value    []LinkedResource `json:"value"`
+	NextLink string           `json:"@odata.nextLink,omitempty"`
+}
+
+// TaskFileAttachment represents a file attached to a task. ContentBytes is
+// only populated when a single attachment is fetched.
+type TaskFileAttachment struct {
+	ODataType            string     `json:"@odata.type,omitempty"`
+	ID                   string     `json:"id,omitempty"`
+	Name                 string     `json:"name"`
+	ContentType          string     `json:"contentType,omitempty"`
+	Size                 int64      `json:"size,omitempty"`
+	LastModifiedDateTime *time.Time `json:"lastModifiedDateTime,omitempty"`
+	ContentBytes         []byte     `json:"contentBytes,omitempty"` // base64 encoded on the wire
+}
+
+// AttachmentsResponse is the API response for listing attachments.
+type AttachmentsResponse struct {
+	Value    []TaskFileAttachment `json:"value"`
+	NextLink string               `json:"@odata.nextLink,omitempty"`
+}
+
+// AttachmentInfo describes a file for which an upload session is created.
+type AttachmentInfo struct {
+	AttachmentType string `json:"attachmentType"` // always "file"
+	Name           string `json:"name"`
+	Size           int64  `json:"size"`
+	ContentType    string `json:"contentType,omitempty"`
+}
+
+// UploadSession is returned when creating an upload session for a large attachment.
+type UploadSession struct {
+	UploadURL          string     `json:"uploadUrl"`
+	ExpirationDateTime *time.Time `json:"expirationDateTime,omitempty"`
+	NextExpectedRanges []string   `json:"nextExpectedRanges,omitempty"`
 }
 
 // ItemBody represents the body content of a task.
@@ -56,6 +138,8 @@ type RecurrencePattern struct {
 	Interval       int      `json:"interval,omitempty"`
 	DaysOfWeek     []string `json:"daysOfWeek,omitempty"`
 	DayOfMonth     int      `json:"dayOfMonth,omitempty"`
+	Month          int      `json:"month,omitempty"`
+	Index          string   `json:"index,omitempty"` // "first" through "fourth", or "last"
 	FirstDayOfWeek string   `json:"firstDayOfWeek,omitempty"`
 }
 
@@ -69,8 +153,9 @@ type RecurrenceRange struct {
 
 // TodoTasksResponse is the API response for listing tasks.
 type TodoTasksResponse struct {
-	Value    []TodoTask `json:"value"`
-	NextLink string     `json:"@odata.nextLink,omitempty"`
+	Value     []TodoTask `json:"value"`
+	NextLink  string     `json:"@odata.nextLink,omitempty"`
+	DeltaLink string     `json:"@odata.deltaLink,omitempty"`
 }
 
 // GraphError represents an error from Microsoft Graph API.
@@ -80,8 +165,18 @@ type GraphError struct {
 
 // GraphErrorDetail contains error details.
 type GraphErrorDetail struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code       string           `json:"code"`
+	Message    string           `json:"message"`
+	InnerError *GraphInnerError `json:"innerError,omitempty"`
+}
+
+// GraphInnerError carries diagnostic details of a Graph error, including the
+// IDs Microsoft support asks for.
+type GraphInnerError struct {
+	Code            string `json:"code,omitempty"`
+	Date            string `json:"date,omitempty"`
+	RequestID       string `json:"request-id,omitempty"`
+	ClientRequestID string `json:"client-request-id,omitempty"`
 }
 
 // TokenResponse holds OAuth tokens from Azure AD.
@@ -91,6 +186,7 @@ type TokenResponse struct {
 	ExpiresIn    int    `json:"expires_in"`
 	TokenType    string `json:"token_type"`
 	Scope        string `json:"scope"`
+	IDToken      string `json:"id_token,omitempty"`
 }
 
 // DeviceCodeResponse is returned when initiating device code flow.
@@ -108,4 +204,13 @@ type StoredTokens struct {
 	AccessToken  string    `json:"access_token"`
 	RefreshToken string    `json:"refresh_token"`
 	ExpiresAt    time.Time `json:"expires_at"`
+	Account      *Account  `json:"account,omitempty"`
+}
+
+// Account identifies the signed-in user, from the claims of the ID token
+// returned at sign-in.
+type Account struct {
+	Username string `json:"username,omitempty"` // usually the email address
+	Name     string `json:"name,omitempty"`
+	TenantID string `json:"tenant_id,omitempty"`
 }